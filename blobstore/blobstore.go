@@ -0,0 +1,143 @@
+// Package blobstore keeps the original demo files behind parsed matches,
+// addressed by the hex sha256 of their content.
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore saves and retrieves blobs by the hex sha256 of their content.
+// Implementations must be safe for concurrent use.
+type BlobStore interface {
+	// Put stores the content read from r under hash. It fails with
+	// ErrHashMismatch if the content does not hash to hash. Storing a blob
+	// that already exists is a no-op.
+	Put(ctx context.Context, hash string, r io.Reader) error
+
+	// Get opens the blob stored under hash, or returns ErrNotFound. The
+	// caller must close the returned reader.
+	Get(ctx context.Context, hash string) (io.ReadCloser, int64, error)
+}
+
+var (
+	// ErrNotFound is returned when no blob is stored under a hash.
+	ErrNotFound = errors.New("blob not found")
+
+	// ErrHashMismatch is returned by Put when the content does not match
+	// the hash it is stored under.
+	ErrHashMismatch = errors.New("blob hash mismatch")
+
+	// ErrInvalidHash is returned for a key that is not a hex sha256.
+	ErrInvalidHash = errors.New("invalid blob hash")
+)
+
+// Local is a BlobStore on the local filesystem. Blobs are spread over
+// subdirectories named after the first two characters of their hash.
+type Local struct {
+	dir string
+}
+
+// NewLocal creates a Local store rooted at dir, creating it if needed.
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create blob dir: %w", err)
+	}
+	return &Local{dir: dir}, nil
+}
+
+// Put writes the blob to a temporary file and renames it into place once
+// its hash is verified, so a blob is never visible half written.
+func (l *Local) Put(ctx context.Context, hash string, r io.Reader) (err error) {
+	path, err := l.path(hash)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create blob dir: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return fmt.Errorf("create blob file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(f, h), &ctxReader{ctx: ctx, r: r}); err != nil {
+		return fmt.Errorf("write blob %s: %w", hash, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != hash {
+		return fmt.Errorf("write blob %s: content hashes to %s: %w", hash, got, ErrHashMismatch)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("close blob file: %w", err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("store blob %s: %w", hash, err)
+	}
+	return nil
+}
+
+// Get opens the blob file and returns it with its size.
+func (l *Local) Get(_ context.Context, hash string) (io.ReadCloser, int64, error) {
+	path, err := l.path(hash)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("open blob %s: %w", hash, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("stat blob %s: %w", hash, err)
+	}
+	return f, fi.Size(), nil
+}
+
+// path returns the file a blob is stored in, rejecting anything but a
+// lowercase hex sha256 so a hash can never escape the store directory.
+func (l *Local) path(hash string) (string, error) {
+	if len(hash) != sha256.Size*2 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidHash, hash)
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", fmt.Errorf("%w: %q", ErrInvalidHash, hash)
+		}
+	}
+	return filepath.Join(l.dir, hash[:2], hash), nil
+}
+
+// ctxReader stops reading once ctx is done, so a long copy can be
+// abandoned.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,85 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLocalPutGet(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	ctx := context.Background()
+
+	const demo = "raw demo bytes"
+	hash := hashOf(demo)
+	if err := store.Put(ctx, hash, strings.NewReader(demo)); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	// storing it again is a no-op
+	if err := store.Put(ctx, hash, strings.NewReader(demo)); err != nil {
+		t.Fatalf("put again: %v", err)
+	}
+
+	rc, size, err := store.Get(ctx, hash)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	if string(got) != demo || size != int64(len(demo)) {
+		t.Errorf("blob: got %q (%d bytes), want %q", got, size, demo)
+	}
+
+	// no temporary files are left next to the blob
+	entries, err := os.ReadDir(filepath.Join(dir, hash[:2]))
+	if err != nil {
+		t.Fatalf("read blob dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("blob dir: got %d entries, want 1", len(entries))
+	}
+}
+
+func TestLocalErrors(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, _, err := store.Get(ctx, hashOf("missing")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get missing: expected ErrNotFound, got %v", err)
+	}
+
+	hash := hashOf("expected")
+	if err := store.Put(ctx, hash, strings.NewReader("tampered")); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("put mismatch: expected ErrHashMismatch, got %v", err)
+	}
+	if _, _, err := store.Get(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get after mismatch: expected ErrNotFound, got %v", err)
+	}
+
+	for _, bad := range []string{"", "../../etc/passwd", strings.ToUpper(hash)} {
+		if _, _, err := store.Get(ctx, bad); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("get %q: expected ErrInvalidHash, got %v", bad, err)
+		}
+	}
+}
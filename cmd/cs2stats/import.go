@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/zarldev/cs2stats/blobstore"
+	"github.com/zarldev/cs2stats/parser"
+	"github.com/zarldev/cs2stats/repository"
+	"github.com/zarldev/cs2stats/service"
+)
+
+// importResult is the outcome of importing one file.
+type importResult struct {
+	path     string
+	matchIDs []string
+	err      error
+}
+
+// runImport walks a directory and ingests every demo file whose name
+// matches the glob into the database, parsing several at once. Demos
+// already stored are recognised by hash and skipped before parsing. A file
+// that fails is reported and the import carries on; a summary is printed
+// at the end.
+func runImport(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dbPath := flags.String("db", "cs2stats.db", "SQLite database path, or a postgres:// URL")
+	blobDir := flags.String("blob-dir", "demos", "directory original demos are kept in; empty keeps only parsed results")
+	pattern := flags.String("glob", "*.dem*", "only import files whose name matches this pattern")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of demos parsed concurrently")
+	replayInterval := flags.Duration("replay-interval", parser.DefaultSampleInterval, "in-game time between player samples for round replays; 0 disables them")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), `usage: cs2stats import [flags] dir
+
+Imports every demo under dir, including subdirectories, into the database.
+Demos may be compressed with gzip, bzip2 or zstd, or be zip archives; pass
+-glob '*.zip' to pick those up.
+
+flags:
+`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	if _, err := filepath.Match(*pattern, ""); err != nil {
+		fmt.Fprintf(flags.Output(), "invalid glob %q\n", *pattern)
+		return errUsage
+	}
+	dir := flags.Arg(0)
+	if fi, err := os.Stat(dir); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	repo, err := repository.Open(*dbPath)
+	if err != nil {
+		return fmt.Errorf("open database %s: %w", repository.RedactDSN(*dbPath), err)
+	}
+	defer repo.Close()
+	var blobs blobstore.BlobStore
+	if *blobDir != "" {
+		if blobs, err = blobstore.NewLocal(*blobDir); err != nil {
+			return fmt.Errorf("open blob store %s: %w", *blobDir, err)
+		}
+	}
+	svc := service.New(repo, demoParser(*replayInterval), blobs)
+
+	paths := make(chan string)
+	results := make(chan importResult)
+
+	// walk errors are reported as failed files rather than stopping the walk
+	go func() {
+		defer close(paths)
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				results <- importResult{path: path, err: err}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if ok, _ := filepath.Match(*pattern, d.Name()); !ok {
+				return nil
+			}
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for range max(*workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				ids, err := importFile(ctx, svc, path)
+				results <- importResult{path: path, matchIDs: ids, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var imported, duplicates, failed int
+	for res := range results {
+		switch {
+		case res.err == nil:
+			imported++
+			log.Printf("imported %s: %v", res.path, res.matchIDs)
+		case errors.Is(res.err, repository.ErrDuplicateDemo):
+			duplicates++
+			log.Printf("skipped %s: already stored as %v", res.path, res.matchIDs)
+		case ctx.Err() != nil:
+			// interrupted; the file is neither imported nor failed
+		default:
+			failed++
+			log.Printf("failed %s: %v", res.path, res.err)
+		}
+	}
+
+	fmt.Printf("imported %d, duplicates %d, failed %d\n", imported, duplicates, failed)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("import interrupted: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files failed to import", failed)
+	}
+	return nil
+}
+
+// importFile ingests the demos in the file at path, streaming it from
+// disk.
+func importFile(ctx context.Context, svc *service.Service, path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return svc.IngestDemoReader(ctx, f)
+}
+
+// demoParser parses demos sampling replays at the given interval, the same
+// way the server does, stopping early when the command is interrupted.
+func demoParser(replayInterval time.Duration) service.ParserFunc {
+	return func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		return parser.ParseContext(ctx, r, parser.Options{SampleInterval: replayInterval, Progress: progress})
+	}
+}
@@ -0,0 +1,74 @@
+// Command cs2stats works with CS2 demos from the command line, without
+// running the server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// errUsage is returned by a command whose arguments are invalid, after
+// its usage has been printed.
+var errUsage = errors.New("invalid usage")
+
+// commands maps each subcommand to the function that runs it with the
+// remaining arguments.
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"parse":     runParse,
+	"import":    runImport,
+	"reprocess": runReprocess,
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("cs2stats: ")
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cs2stats: unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := cmd(ctx, os.Args[2:])
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		stop()
+		os.Exit(2)
+	default:
+		stop()
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: cs2stats <command> [flags] [args]
+
+commands:
+  parse      parse demos and print the matches as JSON
+  import     import a directory of demos into the database
+  reprocess  parse stored demos again with the current parser
+
+Run "cs2stats <command> -h" for the flags of a command.
+`)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/zarldev/cs2stats/parser"
+)
+
+// matchJSON is the JSON form of a parsed match: the fields of
+// parser.Match under their Go names, plus File. Sections left out by the
+// parse flags are nil and omitted from the output.
+type matchJSON struct {
+	File string // demo path, with the file name appended for zip archives
+	*parser.Match
+	Rounds []roundJSON `json:",omitempty"`
+}
+
+// roundJSON shadows the optional sections of parser.Round so they can be
+// omitted.
+type roundJSON struct {
+	parser.Round
+	Kills     []parser.KillEvent      `json:",omitempty"`
+	FirstKill *parser.KillEvent       `json:",omitempty"`
+	CTEconomy *parser.EconomySnapshot `json:",omitempty"`
+	TEconomy  *parser.EconomySnapshot `json:",omitempty"`
+	Frames    []parser.ReplayFrame    `json:",omitempty"`
+}
+
+// sections selects the optional parts of a match written by parse.
+type sections struct {
+	rounds  bool
+	kills   bool
+	economy bool
+}
+
+// runParse parses each demo named in args and writes the matches to
+// stdout. With the json format a single demo is written as one object and
+// several as an array; with ndjson each match is written on its own line
+// as soon as it is parsed. A demo that fails to parse is reported and
+// skipped.
+func runParse(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	format := flags.String("format", "json", "output format: json or ndjson")
+	rounds := flags.Bool("rounds", true, "include per-round data")
+	kills := flags.Bool("kills", true, "include kill events in rounds")
+	economy := flags.Bool("economy", true, "include team economy in rounds")
+	replay := flags.Duration("replay", 0, "sample player positions at this interval for round replays; 0 omits them")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), `usage: cs2stats parse [flags] demo...
+
+Parses each demo and prints the match as JSON. Demos may be compressed with
+gzip, bzip2 or zstd, and every .dem file in a zip archive is parsed.
+
+Each match is an object with the fields of the parser package's Match type,
+named as in Go: File, Map, Date, Duration, Teams, Players and Rounds. Each
+round has the fields of Round; Kills and FirstKill are left out with
+-kills=false, CTEconomy and TEconomy with -economy=false, Frames when
+-replay is 0, and Rounds altogether with -rounds=false. Sides, win methods,
+buy types and grenade types are written by name, and durations in
+nanoseconds.
+
+flags:
+`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+	if *format != "json" && *format != "ndjson" {
+		fmt.Fprintf(flags.Output(), "unknown format %q\n", *format)
+		flags.Usage()
+		return errUsage
+	}
+
+	opts := parser.Options{SampleInterval: *replay}
+	want := sections{rounds: *rounds, kills: *kills, economy: *economy}
+	enc := json.NewEncoder(os.Stdout)
+
+	var (
+		matches []matchJSON
+		failed  int
+	)
+	for _, path := range flags.Args() {
+		err := parseFile(ctx, path, opts, func(m matchJSON) error {
+			m = want.apply(m)
+			if *format == "ndjson" {
+				return enc.Encode(m)
+			}
+			matches = append(matches, m)
+			return nil
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("%s: %v", path, err)
+			failed++
+		}
+	}
+
+	if *format == "json" && len(matches) > 0 {
+		enc.SetIndent("", "  ")
+		var err error
+		if len(matches) == 1 {
+			err = enc.Encode(matches[0])
+		} else {
+			err = enc.Encode(matches)
+		}
+		if err != nil {
+			return fmt.Errorf("write matches: %w", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d demo files failed", failed, flags.NArg())
+	}
+	return nil
+}
+
+// parseFile parses every demo in the file at path and passes each match
+// to fn.
+func parseFile(ctx context.Context, path string, opts parser.Options, fn func(matchJSON) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return parser.Demos(f, func(name string, demo io.Reader) error {
+		file := path
+		if name != "" {
+			file = filepath.Join(path, name)
+		}
+		m, err := parser.ParseContext(ctx, demo, opts)
+		if err != nil {
+			if name != "" {
+				return fmt.Errorf("parse %s: %w", name, err)
+			}
+			return fmt.Errorf("parse demo: %w", err)
+		}
+		return fn(matchJSON{File: file, Match: m})
+	})
+}
+
+// apply drops the sections that were not asked for from m.
+func (s sections) apply(m matchJSON) matchJSON {
+	if !s.rounds {
+		return m
+	}
+	m.Rounds = make([]roundJSON, len(m.Match.Rounds))
+	for i, r := range m.Match.Rounds {
+		out := roundJSON{Round: r, Frames: r.Frames}
+		if s.kills {
+			out.Kills = r.Kills
+			out.FirstKill = r.FirstKill
+		}
+		if s.economy {
+			out.CTEconomy = &r.CTEconomy
+			out.TEconomy = &r.TEconomy
+		}
+		m.Rounds[i] = out
+	}
+	return m
+}
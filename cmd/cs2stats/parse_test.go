@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zarldev/cs2stats/parser"
+)
+
+func TestSectionsApply(t *testing.T) {
+	kill := parser.KillEvent{Tick: 100, RoundNumber: 1}
+	match := &parser.Match{
+		Map: "de_mirage",
+		Rounds: []parser.Round{{
+			Number:    1,
+			Kills:     []parser.KillEvent{kill},
+			FirstKill: &kill,
+			CTEconomy: parser.EconomySnapshot{TeamSpend: 4000},
+			TEconomy:  parser.EconomySnapshot{TeamSpend: 3500},
+			Frames:    []parser.ReplayFrame{{Tick: 64}},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		sections sections
+		rounds   bool
+		present  []string
+		absent   []string
+	}{
+		{
+			name:     "all",
+			sections: sections{rounds: true, kills: true, economy: true},
+			rounds:   true,
+			present:  []string{"Kills", "FirstKill", "CTEconomy", "TEconomy", "Frames"},
+		},
+		{
+			name:     "no kills",
+			sections: sections{rounds: true, economy: true},
+			rounds:   true,
+			present:  []string{"CTEconomy", "TEconomy", "Frames"},
+			absent:   []string{"Kills", "FirstKill"},
+		},
+		{
+			name:     "no economy",
+			sections: sections{rounds: true, kills: true},
+			rounds:   true,
+			present:  []string{"Kills", "FirstKill", "Frames"},
+			absent:   []string{"CTEconomy", "TEconomy"},
+		},
+		{
+			name:     "no rounds",
+			sections: sections{kills: true, economy: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.sections.apply(matchJSON{File: "a.dem", Match: match}))
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var got struct {
+				File   string
+				Map    string
+				Rounds []map[string]json.RawMessage
+			}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got.File != "a.dem" || got.Map != "de_mirage" {
+				t.Errorf("match: got file %q map %q, want a.dem de_mirage", got.File, got.Map)
+			}
+
+			if !tt.rounds {
+				if got.Rounds != nil {
+					t.Errorf("rounds: got %d, want none", len(got.Rounds))
+				}
+				return
+			}
+			if len(got.Rounds) != 1 {
+				t.Fatalf("rounds: got %d, want 1", len(got.Rounds))
+			}
+			round := got.Rounds[0]
+			for _, key := range tt.present {
+				if _, ok := round[key]; !ok {
+					t.Errorf("round: missing %s", key)
+				}
+			}
+			for _, key := range tt.absent {
+				if _, ok := round[key]; ok {
+					t.Errorf("round: unexpected %s", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/zarldev/cs2stats/blobstore"
+	"github.com/zarldev/cs2stats/parser"
+	"github.com/zarldev/cs2stats/repository"
+	"github.com/zarldev/cs2stats/service"
+)
+
+// runReprocess parses the kept demos of stored matches again with the
+// current parser, replacing their rounds, kills, economy and players while
+// keeping their IDs. Without match IDs every match produced by an older
+// parser version is reprocessed.
+func runReprocess(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	dbPath := flags.String("db", "cs2stats.db", "SQLite database path, or a postgres:// URL")
+	blobDir := flags.String("blob-dir", "demos", "directory original demos are kept in")
+	all := flags.Bool("all", false, "reprocess every match, not only those from an older parser version")
+	replayInterval := flags.Duration("replay-interval", parser.DefaultSampleInterval, "in-game time between player samples for round replays; 0 disables them")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), `usage: cs2stats reprocess [flags] [match-id...]
+
+Parses the original demos of stored matches again with the current parser
+(version %d) and replaces their data, keeping the match IDs. Without match
+IDs every match from an older parser version is reprocessed. Matches whose
+demo was not kept are skipped.
+
+flags:
+`, parser.Version)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *all && flags.NArg() > 0 {
+		fmt.Fprintln(flags.Output(), "-all cannot be combined with match IDs")
+		return errUsage
+	}
+
+	repo, err := repository.Open(*dbPath)
+	if err != nil {
+		return fmt.Errorf("open database %s: %w", repository.RedactDSN(*dbPath), err)
+	}
+	defer repo.Close()
+	blobs, err := blobstore.NewLocal(*blobDir)
+	if err != nil {
+		return fmt.Errorf("open blob store %s: %w", *blobDir, err)
+	}
+	svc := service.New(repo, demoParser(*replayInterval), blobs)
+
+	var reprocessed, skipped, failed int
+	report := func(res service.ReprocessResult) {
+		switch {
+		case res.Err == nil:
+			reprocessed++
+			log.Printf("reprocessed %s", res.MatchID)
+		case errors.Is(res.Err, service.ErrDemoUnavailable):
+			skipped++
+			log.Printf("skipped %s: demo was not kept", res.MatchID)
+		default:
+			failed++
+			log.Printf("failed %s: %v", res.MatchID, res.Err)
+		}
+	}
+
+	if flags.NArg() == 0 {
+		err = svc.ReprocessAll(ctx, *all, report)
+	} else {
+		for _, id := range flags.Args() {
+			_, rerr := svc.ReprocessMatch(ctx, id)
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			report(service.ReprocessResult{MatchID: id, Err: rerr})
+		}
+	}
+
+	fmt.Printf("reprocessed %d, skipped %d, failed %d\n", reprocessed, skipped, failed)
+	if err != nil {
+		return fmt.Errorf("reprocess: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d matches failed to reprocess", failed)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zarldev/cs2stats/repository"
+	"github.com/zarldev/cs2stats/service"
+)
+
+// downloadHandler serves GET /api/matches/{id}/demo, the original demo a
+// match was parsed from, as a file download. Range requests are supported
+// so large downloads can be resumed.
+func downloadHandler(svc *service.Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		demo, err := svc.OpenDemo(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrNotFound):
+				http.Error(w, fmt.Sprintf("match %s not found", id), http.StatusNotFound)
+			case errors.Is(err, service.ErrDemoUnavailable):
+				http.Error(w, fmt.Sprintf("demo for match %s was not kept", id), http.StatusNotFound)
+			default:
+				log.Printf("download demo %s: %v", id, err)
+				http.Error(w, "open demo failed", http.StatusInternalServerError)
+			}
+			return
+		}
+		defer demo.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": demo.Name}))
+
+		if rs, ok := demo.ReadCloser.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, demo.Name, time.Time{}, rs)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(demo.Size, 10))
+		if _, err := io.Copy(w, demo); err != nil {
+			log.Printf("download demo %s: %v", id, err)
+		}
+	})
+}
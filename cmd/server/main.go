@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -14,51 +16,109 @@ import (
 
 	"connectrpc.com/connect"
 
+	"github.com/zarldev/cs2stats/blobstore"
 	"github.com/zarldev/cs2stats/parser"
 	"github.com/zarldev/cs2stats/repository"
 	"github.com/zarldev/cs2stats/service"
 	transportgrpc "github.com/zarldev/cs2stats/transport/grpc"
 	"github.com/zarldev/cs2stats/transport/grpc/gen/demo/v1/demov1connect"
+	"github.com/zarldev/cs2stats/transport/grpc/gen/player/v1/playerv1connect"
 	"github.com/zarldev/cs2stats/transport/grpc/gen/stats/v1/statsv1connect"
+	"github.com/zarldev/cs2stats/transport/grpc/gen/team/v1/teamv1connect"
 )
 
+// maxDemoBytes caps the size of a demo sent in a single request body or
+// streamed in chunks.
+const maxDemoBytes = 256 << 20
+
+// config holds the server's command-line settings.
+type config struct {
+	addr           string
+	dbPath         string
+	uploadDir      string
+	blobDir        string
+	workers        int
+	replayInterval time.Duration
+	watchDir       string
+	watchInterval  time.Duration
+	watchMove      bool
+}
+
 func main() {
-	addr := flag.String("addr", ":8080", "listen address")
-	dbPath := flag.String("db", "cs2stats.db", "SQLite database path")
+	var cfg config
+	flag.StringVar(&cfg.addr, "addr", ":8080", "listen address")
+	flag.StringVar(&cfg.dbPath, "db", "cs2stats.db", "SQLite database path, or a postgres:// URL")
+	flag.StringVar(&cfg.uploadDir, "upload-dir", "uploads", "directory uploaded demos are spooled to until parsed")
+	flag.StringVar(&cfg.blobDir, "blob-dir", "demos", "directory original demos are kept in after ingestion; empty keeps only parsed results")
+	flag.IntVar(&cfg.workers, "workers", 2, "number of demos parsed concurrently")
+	flag.DurationVar(&cfg.replayInterval, "replay-interval", parser.DefaultSampleInterval, "in-game time between player samples for round replays; 0 disables them")
+	flag.StringVar(&cfg.watchDir, "watch-dir", "", "directory polled for new demos to ingest; empty disables watching")
+	flag.DurationVar(&cfg.watchInterval, "watch-interval", 5*time.Second, "how often -watch-dir is polled; a file is ingested once unchanged for this long")
+	flag.BoolVar(&cfg.watchMove, "watch-move", false, "move demos ingested from -watch-dir to its done/ or failed/ subdirectory")
 	flag.Parse()
 
-	if err := run(*addr, *dbPath); err != nil {
+	if err := run(cfg); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run(addr, dbPath string) error {
+func run(cfg config) error {
 	// repository
-	repo, err := repository.New(dbPath)
+	repo, err := repository.Open(cfg.dbPath)
 	if err != nil {
-		return fmt.Errorf("open database %s: %w", dbPath, err)
+		return fmt.Errorf("open database %s: %w", repository.RedactDSN(cfg.dbPath), err)
 	}
 	defer repo.Close()
 
+	// original demos
+	var blobs blobstore.BlobStore
+	if cfg.blobDir != "" {
+		if blobs, err = blobstore.NewLocal(cfg.blobDir); err != nil {
+			return fmt.Errorf("open blob store %s: %w", cfg.blobDir, err)
+		}
+	}
+
 	// service
-	svc := service.New(repo, service.ParserFunc(parser.Parse))
+	svc := service.New(repo, demoParser(cfg.replayInterval), blobs)
+	jobs, err := service.NewJobs(svc, cfg.uploadDir, cfg.workers)
+	if err != nil {
+		return fmt.Errorf("set up ingest jobs: %w", err)
+	}
+	var watcher *service.Watcher
+	if cfg.watchDir != "" {
+		watcher, err = service.NewWatcher(svc, cfg.watchDir, service.WatchOptions{
+			Interval: cfg.watchInterval,
+			Move:     cfg.watchMove,
+			Report:   logWatchResult,
+		})
+		if err != nil {
+			return fmt.Errorf("set up watch dir: %w", err)
+		}
+	}
 
 	// transport handlers
-	demoHandler := transportgrpc.NewDemoHandler(svc)
+	demoHandler := transportgrpc.NewDemoHandler(svc, jobs, maxDemoBytes)
 	statsHandler := transportgrpc.NewStatsHandler(svc)
-
-	// max request size for large demo files (256 MB)
-	handlerOpts := []connect.HandlerOption{
-		connect.WithReadMaxBytes(256 << 20),
-	}
+	playerHandler := transportgrpc.NewPlayerHandler(svc)
+	teamHandler := transportgrpc.NewTeamHandler(svc)
 
 	mux := http.NewServeMux()
 
-	// mount ConnectRPC handlers
-	demoPath, demoHTTP := demov1connect.NewDemoServiceHandler(demoHandler, handlerOpts...)
-	statsPath, statsHTTP := statsv1connect.NewStatsServiceHandler(statsHandler, handlerOpts...)
+	// mount ConnectRPC handlers; demo files are sent whole to UploadDemo,
+	// so only the demo service accepts messages that large
+	demoPath, demoHTTP := demov1connect.NewDemoServiceHandler(demoHandler,
+		connect.WithReadMaxBytes(maxDemoBytes))
+	statsPath, statsHTTP := statsv1connect.NewStatsServiceHandler(statsHandler)
+	playerPath, playerHTTP := playerv1connect.NewPlayerServiceHandler(playerHandler)
+	teamPath, teamHTTP := teamv1connect.NewTeamServiceHandler(teamHandler)
 	mux.Handle(demoPath, demoHTTP)
 	mux.Handle(statsPath, statsHTTP)
+	mux.Handle(playerPath, playerHTTP)
+	mux.Handle(teamPath, teamHTTP)
+
+	// plain HTTP upload for scripts and curl
+	mux.Handle("POST /api/demos", uploadHandler(jobs, maxDemoBytes))
+	mux.Handle("GET /api/matches/{id}/demo", downloadHandler(svc))
 
 	// mount frontend (embedded or dev stub)
 	mux.Handle("/", frontendHandler())
@@ -67,7 +127,7 @@ func run(addr, dbPath string) error {
 	handler := corsMiddleware(mux)
 
 	srv := &http.Server{
-		Addr:              addr,
+		Addr:              cfg.addr,
 		Handler:           handler,
 		ReadHeaderTimeout: 10 * time.Second,
 	}
@@ -76,9 +136,36 @@ func run(addr, dbPath string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// background ingestion; jobs still running at shutdown resume on the
+	// next start
+	jobsDone := make(chan error, 1)
+	go func() {
+		jobsDone <- jobs.Run(ctx)
+	}()
+	defer func() {
+		stop()
+		if err := <-jobsDone; err != nil {
+			log.Printf("ingest jobs: %v", err)
+		}
+	}()
+
+	// demos dropped into the watch dir
+	if watcher != nil {
+		watchDone := make(chan struct{})
+		go func() {
+			defer close(watchDone)
+			log.Printf("watching %s for demos", cfg.watchDir)
+			watcher.Run(ctx)
+		}()
+		defer func() {
+			stop()
+			<-watchDone
+		}()
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
-		log.Printf("listening on %s (db: %s)", addr, dbPath)
+		log.Printf("listening on %s (db: %s)", cfg.addr, repository.RedactDSN(cfg.dbPath))
 		errCh <- srv.ListenAndServe()
 	}()
 
@@ -120,3 +207,23 @@ func corsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// demoParser parses demos sampling replays at the given interval, stopping
+// early when the server shuts down.
+func demoParser(replayInterval time.Duration) service.ParserFunc {
+	return func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		return parser.ParseContext(ctx, r, parser.Options{SampleInterval: replayInterval, Progress: progress})
+	}
+}
+
+// logWatchResult logs the outcome of a demo ingested from the watch dir.
+func logWatchResult(res service.WatchResult) {
+	switch {
+	case res.Err == nil:
+		log.Printf("watch: ingested %s as %s", res.Path, strings.Join(res.MatchIDs, ", "))
+	case errors.Is(res.Err, repository.ErrDuplicateDemo):
+		log.Printf("watch: %s already stored as %s", res.Path, strings.Join(res.MatchIDs, ", "))
+	default:
+		log.Printf("watch: %s: %v", res.Path, res.Err)
+	}
+}
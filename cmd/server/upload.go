@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/zarldev/cs2stats/parser"
+	"github.com/zarldev/cs2stats/repository"
+	"github.com/zarldev/cs2stats/service"
+)
+
+// uploadResponse is the JSON body returned by POST /api/demos. It
+// describes the first demo uploaded; when a zip archive holds several,
+// Demos describes each of them.
+type uploadResponse struct {
+	JobID     string           `json:"job_id,omitempty"`
+	FileName  string           `json:"file_name,omitempty"`
+	MatchID   string           `json:"match_id,omitempty"`
+	Status    string           `json:"status,omitempty"`
+	Duplicate bool             `json:"duplicate,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Demos     []uploadResponse `json:"demos,omitempty"`
+}
+
+// uploadHandler serves POST /api/demos for scripts that cannot speak
+// ConnectRPC. The demo is sent either as the "file" field of a
+// multipart/form-data body or as the raw request body, with an optional
+// file_name query parameter. It may be compressed or a zip archive of
+// demos, and is streamed into the same ingestion queue as UploadDemo.
+//
+// By default the response is sent once every demo is ingested and carries
+// the match IDs. A demo that is already stored gets the existing match ID
+// with duplicate set. With wait=false the request returns as soon as the
+// demos are queued, with the job IDs to poll. A body larger than maxBytes
+// is rejected.
+func uploadHandler(jobs *service.Jobs, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+		body, fileName, err := uploadBody(r)
+		if err != nil {
+			writeUploadError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		submitted, err := jobs.Submit(r.Context(), fileName, body)
+		if err != nil && !errors.Is(err, repository.ErrDuplicateDemo) {
+			var maxErr *http.MaxBytesError
+			switch {
+			case errors.Is(err, service.ErrEmptyDemo), errors.Is(err, parser.ErrNoDemos):
+				writeUploadError(w, http.StatusBadRequest, err)
+			case errors.As(err, &maxErr):
+				writeUploadError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("demo exceeds %d bytes", maxErr.Limit))
+			case errors.Is(err, parser.ErrDemoTooLarge):
+				writeUploadError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("demo exceeds %d bytes decompressed", parser.MaxDemoSize))
+			default:
+				log.Printf("upload %s: %v", fileName, err)
+				writeUploadError(w, http.StatusInternalServerError, fmt.Errorf("queue demo: %w", err))
+			}
+			return
+		}
+
+		wait := r.URL.Query().Get("wait") != "false"
+		demos := make([]uploadResponse, 0, len(submitted))
+		status := http.StatusOK
+		for _, job := range submitted {
+			if job.ID == "" {
+				demos = append(demos, uploadResponse{
+					FileName: job.FileName, MatchID: job.MatchID, Status: string(service.JobDone), Duplicate: true,
+				})
+				continue
+			}
+			if !wait {
+				demos = append(demos, uploadResponse{JobID: job.ID, FileName: job.FileName, Status: string(job.Status)})
+				status = http.StatusAccepted
+				continue
+			}
+
+			job, err := waitForJob(r.Context(), jobs, job.ID)
+			if err != nil {
+				writeUploadError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if !job.Finished() {
+				// the client went away; the jobs carry on without it
+				return
+			}
+
+			demo := uploadResponse{
+				JobID: job.ID, FileName: job.FileName, MatchID: job.MatchID, Status: string(job.Status), Error: job.Error,
+			}
+			switch {
+			case job.Status == service.JobDone:
+				if status == http.StatusOK {
+					status = http.StatusCreated
+				}
+			case job.MatchID != "":
+				// failed jobs only carry a match ID when the demo was
+				// stored by a concurrent upload
+				demo.Duplicate = true
+				demo.Error = ""
+			default:
+				status = http.StatusUnprocessableEntity
+			}
+			demos = append(demos, demo)
+		}
+
+		resp := demos[0]
+		if len(demos) > 1 {
+			resp.Demos = demos
+		}
+		writeUploadJSON(w, status, resp)
+	})
+}
+
+// waitForJob returns the job's final state, or its last state seen if ctx
+// ends first.
+func waitForJob(ctx context.Context, jobs *service.Jobs, id string) (service.IngestJob, error) {
+	updates, err := jobs.WatchIngestJob(ctx, id)
+	if err != nil {
+		return service.IngestJob{}, fmt.Errorf("watch ingest job: %w", err)
+	}
+	var job service.IngestJob
+	for job = range updates {
+	}
+	return job, nil
+}
+
+// uploadBody returns a reader over the uploaded demo and its file name.
+func uploadBody(r *http.Request) (io.Reader, string, error) {
+	fileName := r.URL.Query().Get("file_name")
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		// anything that is not a form is taken as the raw demo
+		return r.Body, fileName, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", fmt.Errorf("read multipart body: %w", err)
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", fmt.Errorf("multipart body has no file field")
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("read multipart body: %w", err)
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		if fileName == "" {
+			fileName = part.FileName()
+		}
+		return part, fileName, nil
+	}
+}
+
+func writeUploadError(w http.ResponseWriter, status int, err error) {
+	writeUploadJSON(w, status, uploadResponse{Error: err.Error()})
+}
+
+func writeUploadJSON(w http.ResponseWriter, status int, resp uploadResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("write upload response: %v", err)
+	}
+}
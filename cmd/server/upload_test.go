@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zarldev/cs2stats/parser"
+	"github.com/zarldev/cs2stats/repository"
+	"github.com/zarldev/cs2stats/service"
+)
+
+// maxTestBytes caps upload bodies in these tests.
+const maxTestBytes = 1 << 10
+
+// newTestUploadHandler returns an upload handler backed by an in-memory
+// database and a running job queue whose parser returns a fixed match.
+func newTestUploadHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	repo, err := repository.New(":memory:")
+	if err != nil {
+		t.Fatalf("open repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	svc := service.New(repo, service.ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		return &parser.Match{
+			Map:  "de_mirage",
+			Date: time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC),
+			Teams: [2]parser.Team{
+				{Name: "Team Alpha", Score: 13},
+				{Name: "Team Beta", Score: 9},
+			},
+		}, nil
+	}), nil)
+	jobs, err := service.NewJobs(svc, t.TempDir(), 1)
+	if err != nil {
+		t.Fatalf("create jobs: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		jobs.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return uploadHandler(jobs, maxTestBytes)
+}
+
+// postUpload sends an upload request and decodes the response.
+func postUpload(t *testing.T, h http.Handler, query, contentType string, body io.Reader) (int, uploadResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/demos"+query, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp uploadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestUploadRawBody(t *testing.T) {
+	h := newTestUploadHandler(t)
+
+	code, resp := postUpload(t, h, "?file_name=raw.dem", "application/octet-stream", strings.NewReader("raw demo"))
+	if code != http.StatusCreated {
+		t.Fatalf("status: got %d, want %d (%s)", code, http.StatusCreated, resp.Error)
+	}
+	if resp.FileName != "raw.dem" || resp.MatchID == "" || resp.Status != string(service.JobDone) || resp.Duplicate {
+		t.Errorf("response: got %+v, want done raw.dem with a match ID", resp)
+	}
+}
+
+func TestUploadMultipart(t *testing.T) {
+	h := newTestUploadHandler(t)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("note", "ignored")
+	fw, err := mw.CreateFormFile("file", "form.dem")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	fw.Write([]byte("form demo"))
+	mw.Close()
+
+	code, resp := postUpload(t, h, "", mw.FormDataContentType(), &body)
+	if code != http.StatusCreated {
+		t.Fatalf("status: got %d, want %d (%s)", code, http.StatusCreated, resp.Error)
+	}
+	if resp.FileName != "form.dem" || resp.MatchID == "" {
+		t.Errorf("response: got %+v, want form.dem with a match ID", resp)
+	}
+}
+
+func TestUploadDuplicate(t *testing.T) {
+	h := newTestUploadHandler(t)
+
+	_, first := postUpload(t, h, "?file_name=a.dem", "", strings.NewReader("same demo"))
+	code, resp := postUpload(t, h, "?file_name=b.dem", "", strings.NewReader("same demo"))
+	if code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d (%s)", code, http.StatusOK, resp.Error)
+	}
+	if !resp.Duplicate || resp.MatchID != first.MatchID || resp.JobID != "" {
+		t.Errorf("response: got %+v, want duplicate of match %s", resp, first.MatchID)
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	h := newTestUploadHandler(t)
+
+	code, resp := postUpload(t, h, "?file_name=big.dem", "", bytes.NewReader(make([]byte, maxTestBytes+1)))
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status: got %d, want %d", code, http.StatusRequestEntityTooLarge)
+	}
+	if resp.Error == "" {
+		t.Error("expected an error message")
+	}
+}
+
+func TestUploadNoWait(t *testing.T) {
+	h := newTestUploadHandler(t)
+
+	code, resp := postUpload(t, h, "?file_name=queued.dem&wait=false", "", strings.NewReader("queued demo"))
+	if code != http.StatusAccepted {
+		t.Fatalf("status: got %d, want %d (%s)", code, http.StatusAccepted, resp.Error)
+	}
+	if resp.JobID == "" || resp.FileName != "queued.dem" || resp.MatchID != "" {
+		t.Errorf("response: got %+v, want a queued job without a match ID", resp)
+	}
+}
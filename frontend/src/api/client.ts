@@ -4,11 +4,35 @@ import type {
   ListMatchesRequest,
   ListMatchesResponse,
   GetMatchResponse,
+  DeleteMatchResponse,
   UploadDemoResponse,
+  GetIngestJobResponse,
+  ListIngestJobsRequest,
+  ListIngestJobsResponse,
+  WatchIngestJobResponse,
   GetPlayerStatsResponse,
   GetEconomyStatsResponse,
   GetRoundTimelineResponse,
   GetPositionalDataResponse,
+  GetDamageEventsResponse,
+  GetPlayerRoundStatsResponse,
+  MapStatsFilters,
+  GetMapStatsResponse,
+  GetPlayerProfileRequest,
+  GetPlayerProfileResponse,
+  GetPlayerHistoryRequest,
+  GetPlayerHistoryResponse,
+  GetLeaderboardRequest,
+  GetLeaderboardResponse,
+  ComparePlayersRequest,
+  ComparePlayersResponse,
+  GetTeamResponse,
+  ListTeamsRequest,
+  ListTeamsResponse,
+  GetTeamMatchesRequest,
+  GetTeamMatchesResponse,
+  CompareTeamsRequest,
+  CompareTeamsResponse,
 } from "./types";
 
 async function rpc<TReq, TRes>(
@@ -31,6 +55,67 @@ async function rpc<TReq, TRes>(
   return res.json() as Promise<TRes>;
 }
 
+// serverStream calls a server-streaming method using the Connect
+// protocol's enveloped JSON: each message is prefixed with a flags byte and
+// a big-endian uint32 length, and the end-of-stream envelope (flag 0x02)
+// carries any error.
+async function* serverStream<TReq, TRes>(
+  service: string,
+  method: string,
+  request: TReq,
+  signal?: AbortSignal,
+): AsyncGenerator<TRes> {
+  const url = `/${service}/${method}`;
+  const payload = new TextEncoder().encode(JSON.stringify(request));
+  const body = new Uint8Array(5 + payload.length);
+  new DataView(body.buffer).setUint32(1, payload.length);
+  body.set(payload, 5);
+
+  const res = await fetch(url, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/connect+json",
+      "Connect-Protocol-Version": "1",
+    },
+    body,
+    signal,
+  });
+  if (!res.ok || !res.body) {
+    const text = await res.text();
+    throw new Error(`${method}: ${res.status} ${text}`);
+  }
+
+  const reader = res.body.getReader();
+  const decoder = new TextDecoder();
+  let buf = new Uint8Array(0);
+  for (;;) {
+    while (buf.length >= 5) {
+      const flags = buf[0];
+      const len = new DataView(buf.buffer, buf.byteOffset).getUint32(1);
+      if (buf.length < 5 + len) break;
+      const msg: unknown = JSON.parse(decoder.decode(buf.subarray(5, 5 + len)));
+      buf = buf.slice(5 + len);
+      if (flags & 0x02) {
+        const end = msg as { error?: { code: string; message?: string } };
+        if (end.error) {
+          throw new Error(
+            `${method}: ${end.error.code} ${end.error.message ?? ""}`,
+          );
+        }
+        return;
+      }
+      yield msg as TRes;
+    }
+
+    const { done, value } = await reader.read();
+    if (done) return;
+    const next = new Uint8Array(buf.length + value.length);
+    next.set(buf);
+    next.set(value, buf.length);
+    buf = next;
+  }
+}
+
 // demo.v1.DemoService
 
 export function listMatches(
@@ -43,6 +128,10 @@ export function getMatch(matchId: string): Promise<GetMatchResponse> {
   return rpc("demo.v1.DemoService", "GetMatch", { matchId });
 }
 
+export function deleteMatch(matchId: string): Promise<DeleteMatchResponse> {
+  return rpc("demo.v1.DemoService", "DeleteMatch", { matchId });
+}
+
 export async function uploadDemo(file: File): Promise<UploadDemoResponse> {
   const buf = await file.arrayBuffer();
   const bytes = new Uint8Array(buf);
@@ -60,6 +149,25 @@ export async function uploadDemo(file: File): Promise<UploadDemoResponse> {
   });
 }
 
+export function getIngestJob(jobId: string): Promise<GetIngestJobResponse> {
+  return rpc("demo.v1.DemoService", "GetIngestJob", { jobId });
+}
+
+export function listIngestJobs(
+  req: ListIngestJobsRequest,
+): Promise<ListIngestJobsResponse> {
+  return rpc("demo.v1.DemoService", "ListIngestJobs", req);
+}
+
+// watchIngestJob yields the job's state on every change until it is done
+// or failed.
+export function watchIngestJob(
+  jobId: string,
+  signal?: AbortSignal,
+): AsyncGenerator<WatchIngestJobResponse> {
+  return serverStream("demo.v1.DemoService", "WatchIngestJob", { jobId }, signal);
+}
+
 // stats.v1.StatsService
 
 export function getPlayerStats(
@@ -72,6 +180,18 @@ export function getPlayerStats(
   });
 }
 
+export function getPlayerRoundStats(
+  matchId: string,
+  steamId?: string,
+  buyType?: string,
+): Promise<GetPlayerRoundStatsResponse> {
+  return rpc("stats.v1.StatsService", "GetPlayerRoundStats", {
+    matchId,
+    steamId: steamId ?? "",
+    buyType: buyType ?? "",
+  });
+}
+
 export function getEconomyStats(
   matchId: string,
 ): Promise<GetEconomyStatsResponse> {
@@ -93,3 +213,73 @@ export function getPositionalData(
     roundNumber: roundNumber ?? 0,
   });
 }
+
+export function getDamageEvents(
+  matchId: string,
+  roundNumber?: number,
+  steamId?: string,
+): Promise<GetDamageEventsResponse> {
+  return rpc("stats.v1.StatsService", "GetDamageEvents", {
+    matchId,
+    roundNumber: roundNumber ?? 0,
+    steamId: steamId ?? "",
+  });
+}
+
+export function getMapStats(
+  filters?: MapStatsFilters,
+): Promise<GetMapStatsResponse> {
+  return rpc("stats.v1.StatsService", "GetMapStats", {
+    mapName: filters?.mapName ?? "",
+    steamId: filters?.steamId ?? "",
+    teamName: filters?.teamName ?? "",
+  });
+}
+
+// player.v1.PlayerService
+
+export function getPlayerProfile(
+  req: GetPlayerProfileRequest,
+): Promise<GetPlayerProfileResponse> {
+  return rpc("player.v1.PlayerService", "GetPlayerProfile", req);
+}
+
+export function getPlayerHistory(
+  req: GetPlayerHistoryRequest,
+): Promise<GetPlayerHistoryResponse> {
+  return rpc("player.v1.PlayerService", "GetPlayerHistory", req);
+}
+
+export function getLeaderboard(
+  req: GetLeaderboardRequest,
+): Promise<GetLeaderboardResponse> {
+  return rpc("player.v1.PlayerService", "GetLeaderboard", req);
+}
+
+export function comparePlayers(
+  req: ComparePlayersRequest,
+): Promise<ComparePlayersResponse> {
+  return rpc("player.v1.PlayerService", "ComparePlayers", req);
+}
+
+// team.v1.TeamService
+
+export function getTeam(teamId: string): Promise<GetTeamResponse> {
+  return rpc("team.v1.TeamService", "GetTeam", { teamId });
+}
+
+export function listTeams(req: ListTeamsRequest): Promise<ListTeamsResponse> {
+  return rpc("team.v1.TeamService", "ListTeams", req);
+}
+
+export function getTeamMatches(
+  req: GetTeamMatchesRequest,
+): Promise<GetTeamMatchesResponse> {
+  return rpc("team.v1.TeamService", "GetTeamMatches", req);
+}
+
+export function compareTeams(
+  req: CompareTeamsRequest,
+): Promise<CompareTeamsResponse> {
+  return rpc("team.v1.TeamService", "CompareTeams", req);
+}
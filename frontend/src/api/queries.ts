@@ -7,12 +7,30 @@ import {
 import {
   listMatches,
   getMatch,
+  deleteMatch,
   uploadDemo,
+  watchIngestJob,
   getPlayerStats,
   getEconomyStats,
   getRoundTimeline,
   getPositionalData,
+  getMapStats,
+  getPlayerProfile,
+  getPlayerHistory,
+  getLeaderboard,
+  comparePlayers,
+  getTeam,
+  listTeams,
+  getTeamMatches,
+  compareTeams,
 } from "./client";
+import type {
+  IngestJob,
+  IngestJobStatus,
+  LeaderboardFilters,
+  MapStatsFilters,
+  PlayerFilters,
+} from "./types";
 
 const PAGE_SIZE = 20;
 
@@ -46,12 +64,70 @@ export function useGetMatch(matchId: string) {
   });
 }
 
+// uploadAndWatch uploads a demo and follows its ingest jobs, reporting each
+// status change and the parse progress, until the matches are stored. A
+// zip archive queues one job per demo; they are followed in turn and the
+// first is returned. It rejects if any job fails.
+async function uploadAndWatch({
+  file,
+  onStatus,
+}: {
+  file: File;
+  onStatus?: (status: IngestJobStatus, progress?: number) => void;
+}): Promise<IngestJob> {
+  const { jobId, jobIds } = await uploadDemo(file);
+  onStatus?.("INGEST_JOB_STATUS_QUEUED");
+
+  const finished: IngestJob[] = [];
+  for (const id of jobIds ?? [jobId]) {
+    let last: IngestJob | undefined;
+    for await (const { job } of watchIngestJob(id)) {
+      last = job;
+      onStatus?.(job.status, job.progress);
+    }
+    if (!last || last.status !== "INGEST_JOB_STATUS_DONE") {
+      throw new Error(last?.error || "ingest did not finish");
+    }
+    finished.push(last);
+  }
+  return finished[0];
+}
+
 export function useUploadDemo() {
   const qc = useQueryClient();
   return useMutation({
-    mutationFn: uploadDemo,
+    mutationFn: uploadAndWatch,
     onSuccess: () => {
       void qc.invalidateQueries({ queryKey: ["matches"] });
+      void qc.invalidateQueries({ queryKey: ["playerProfile"] });
+      void qc.invalidateQueries({ queryKey: ["playerHistory"] });
+      void qc.invalidateQueries({ queryKey: ["leaderboard"] });
+      void qc.invalidateQueries({ queryKey: ["mapStats"] });
+      void qc.invalidateQueries({ queryKey: ["teams"] });
+      void qc.invalidateQueries({ queryKey: ["team"] });
+      void qc.invalidateQueries({ queryKey: ["teamMatches"] });
+      void qc.invalidateQueries({ queryKey: ["compareTeams"] });
+      void qc.invalidateQueries({ queryKey: ["comparePlayers"] });
+    },
+  });
+}
+
+export function useDeleteMatch() {
+  const qc = useQueryClient();
+  return useMutation({
+    mutationFn: deleteMatch,
+    onSuccess: (_, matchId) => {
+      qc.removeQueries({ queryKey: ["match", matchId] });
+      void qc.invalidateQueries({ queryKey: ["matches"] });
+      void qc.invalidateQueries({ queryKey: ["playerProfile"] });
+      void qc.invalidateQueries({ queryKey: ["playerHistory"] });
+      void qc.invalidateQueries({ queryKey: ["leaderboard"] });
+      void qc.invalidateQueries({ queryKey: ["mapStats"] });
+      void qc.invalidateQueries({ queryKey: ["teams"] });
+      void qc.invalidateQueries({ queryKey: ["team"] });
+      void qc.invalidateQueries({ queryKey: ["teamMatches"] });
+      void qc.invalidateQueries({ queryKey: ["compareTeams"] });
+      void qc.invalidateQueries({ queryKey: ["comparePlayers"] });
     },
   });
 }
@@ -87,3 +163,93 @@ export function usePositionalData(matchId: string, roundNumber?: number) {
     enabled: !!matchId,
   });
 }
+
+export function useMapStats(filters?: MapStatsFilters) {
+  return useQuery({
+    queryKey: ["mapStats", filters],
+    queryFn: () => getMapStats(filters),
+  });
+}
+
+export function usePlayerProfile(steamId: string, filters?: PlayerFilters) {
+  return useQuery({
+    queryKey: ["playerProfile", steamId, filters],
+    queryFn: () => getPlayerProfile({ steamId, ...filters }),
+    enabled: !!steamId,
+  });
+}
+
+export function usePlayerHistory(steamId: string, filters?: PlayerFilters) {
+  return useInfiniteQuery({
+    queryKey: ["playerHistory", steamId, filters],
+    queryFn: ({ pageParam }) =>
+      getPlayerHistory({
+        steamId,
+        pageSize: PAGE_SIZE,
+        pageToken: pageParam,
+        ...filters,
+      }),
+    initialPageParam: "",
+    getNextPageParam: (lastPage) => lastPage.nextPageToken || undefined,
+    enabled: !!steamId,
+  });
+}
+
+export function useLeaderboard(filters?: LeaderboardFilters) {
+  return useInfiniteQuery({
+    queryKey: ["leaderboard", filters],
+    queryFn: ({ pageParam }) =>
+      getLeaderboard({
+        pageSize: PAGE_SIZE,
+        pageToken: pageParam,
+        ...filters,
+      }),
+    initialPageParam: "",
+    getNextPageParam: (lastPage) => lastPage.nextPageToken || undefined,
+  });
+}
+
+export function useComparePlayers(steamIdA: string, steamIdB: string) {
+  return useQuery({
+    queryKey: ["comparePlayers", steamIdA, steamIdB],
+    queryFn: () => comparePlayers({ steamIdA, steamIdB }),
+    enabled: !!steamIdA && !!steamIdB && steamIdA !== steamIdB,
+  });
+}
+
+export function useTeam(teamId: string) {
+  return useQuery({
+    queryKey: ["team", teamId],
+    queryFn: () => getTeam(teamId),
+    enabled: !!teamId,
+  });
+}
+
+export function useTeams(name?: string) {
+  return useInfiniteQuery({
+    queryKey: ["teams", name],
+    queryFn: ({ pageParam }) =>
+      listTeams({ pageSize: PAGE_SIZE, pageToken: pageParam, name }),
+    initialPageParam: "",
+    getNextPageParam: (lastPage) => lastPage.nextPageToken || undefined,
+  });
+}
+
+export function useTeamMatches(teamId: string) {
+  return useInfiniteQuery({
+    queryKey: ["teamMatches", teamId],
+    queryFn: ({ pageParam }) =>
+      getTeamMatches({ teamId, pageSize: PAGE_SIZE, pageToken: pageParam }),
+    initialPageParam: "",
+    getNextPageParam: (lastPage) => lastPage.nextPageToken || undefined,
+    enabled: !!teamId,
+  });
+}
+
+export function useCompareTeams(teamAId: string, teamBId: string) {
+  return useQuery({
+    queryKey: ["compareTeams", teamAId, teamBId],
+    queryFn: () => compareTeams({ teamAId, teamBId }),
+    enabled: !!teamAId && !!teamBId && teamAId !== teamBId,
+  });
+}
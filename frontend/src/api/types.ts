@@ -1,5 +1,5 @@
-// types matching the proto contract in proto/demo/v1/demo.proto
-// and proto/stats/v1/stats.proto
+// types matching the proto contract in proto/demo/v1/demo.proto,
+// proto/stats/v1/stats.proto and proto/player/v1/player.proto
 
 // demo.v1
 
@@ -14,6 +14,9 @@ export interface Match {
   teamBScore: number;
   demoFileHash: string;
   teamAStartedAs: string;
+  parserVersion?: number; // omitted when zero, or in match listings
+  teamAId?: string; // omitted for a side not linked to a team
+  teamBId?: string;
 }
 
 export interface Player {
@@ -28,7 +31,49 @@ export interface UploadDemoRequest {
 }
 
 export interface UploadDemoResponse {
-  matchId: string;
+  jobId: string;
+  // one job per demo queued from a compressed upload or zip archive
+  jobIds?: string[];
+}
+
+// proto JSON serializes enums as strings
+export type IngestJobStatus =
+  | "INGEST_JOB_STATUS_UNSPECIFIED"
+  | "INGEST_JOB_STATUS_QUEUED"
+  | "INGEST_JOB_STATUS_PARSING"
+  | "INGEST_JOB_STATUS_STORING"
+  | "INGEST_JOB_STATUS_DONE"
+  | "INGEST_JOB_STATUS_FAILED";
+
+export interface IngestJob {
+  id: string;
+  fileName: string;
+  demoFileHash: string;
+  status: IngestJobStatus;
+  error?: string; // set when the job failed
+  matchId?: string; // set when done, or when the demo was already stored
+  createdAt: string; // ISO timestamp
+  updatedAt: string; // ISO timestamp
+  progress?: number; // percent of the demo parsed, while parsing
+}
+
+export interface GetIngestJobResponse {
+  job: IngestJob;
+}
+
+export interface ListIngestJobsRequest {
+  pageSize: number;
+  pageToken: string;
+  status?: IngestJobStatus;
+}
+
+export interface ListIngestJobsResponse {
+  jobs: IngestJob[];
+  nextPageToken: string;
+}
+
+export interface WatchIngestJobResponse {
+  job: IngestJob;
 }
 
 export interface ListMatchesRequest {
@@ -54,6 +99,12 @@ export interface GetMatchResponse {
   players: Player[];
 }
 
+export interface DeleteMatchRequest {
+  matchId: string;
+}
+
+export type DeleteMatchResponse = Record<string, never>;
+
 // stats.v1
 
 export interface PlayerStats {
@@ -67,8 +118,26 @@ export interface PlayerStats {
   kast: number;
   hsPct: number;
   rating: number;
+  kpr: number;
+  dpr: number;
+  impact: number;
+  headshots: number;
+  totalDamage: number;
+  survived: number;
+  firstKills: number;
+  firstDeaths: number;
+  tradeKills: number;
+  twoK: number;
+  threeK: number;
+  fourK: number;
+  fiveK: number;
   flashAssists: number;
   utilityDamage: number;
+  enemiesFlashed: number;
+  teammatesFlashed: number;
+  enemyBlindDuration: number;
+  avgEnemyBlindDuration: number;
+  flashesLeadingToKill: number;
 }
 
 export interface GetPlayerStatsResponse {
@@ -137,6 +206,8 @@ export interface RoundEvent {
   clutch?: ClutchInfo;
   plant?: PlantEvent;
   defuse?: DefuseEvent;
+  teamASide: string;
+  teamAWon: boolean;
 }
 
 export interface GetRoundTimelineResponse {
@@ -163,3 +234,334 @@ export interface GetPositionalDataResponse {
   mapName: string;
   kills: KillPosition[];
 }
+
+export interface DamageEvent {
+  roundNumber: number;
+  tick: number;
+  attackerSteamId: string;
+  victimSteamId: string;
+  weapon: string;
+  hitGroup: string;
+  healthDamage: number;
+  armorDamage: number;
+  attackerPos: Position;
+  victimPos: Position;
+  aggregate: boolean; // a round damage total from CS2 counters, not a single hit
+}
+
+export interface GetDamageEventsResponse {
+  events: DamageEvent[];
+}
+
+export interface PlayerRoundStats {
+  roundNumber: number;
+  steamId: string;
+  name: string;
+  side: string;
+  kills: number;
+  deaths: number;
+  assists: number;
+  damage: number;
+  survived: boolean;
+  traded: boolean;
+  kast: boolean;
+  equipmentValue: number;
+  moneySpent: number;
+  buyType: string;
+}
+
+export interface GetPlayerRoundStatsResponse {
+  rounds: PlayerRoundStats[];
+}
+
+export interface MapStatsFilters {
+  mapName?: string;
+  steamId?: string; // stats from this player's team's view
+  teamName?: string; // stats from this team's view
+}
+
+// the pistol rounds are the first round of each half
+export interface MapSideStats {
+  rounds: number;
+  roundsWon: number;
+  winPct: number;
+  pistolRounds: number;
+  pistolRoundsWon: number;
+  pistolWinPct: number;
+}
+
+// side, win method and plant stats cover the rounds of the filtered
+// player's or team's side, or of both teams without a filter
+export interface MapStats {
+  mapName: string;
+  matches: number;
+  wins: number; // zero without a player or team filter
+  losses: number;
+  rounds: number;
+  ct: MapSideStats;
+  t: MapSideStats;
+  eliminations: number; // rounds won by each method
+  bombsExploded: number;
+  bombsDefused: number;
+  timeExpired: number;
+  avgRoundDuration: number; // seconds
+  bombPlants: number;
+  plantsA: number;
+  plantsB: number;
+}
+
+export interface GetMapStatsResponse {
+  maps: MapStats[]; // most played first
+}
+
+// player.v1
+
+export interface SideStats {
+  side: string; // CT or T
+  rounds: number;
+  roundsWon: number;
+  kills: number;
+  deaths: number;
+  assists: number;
+  damage: number;
+  survived: number;
+  adr: number;
+  kast: number; // percentage of rounds
+  kpr: number;
+  dpr: number;
+}
+
+// rate stats are averaged weighted by rounds played, hsPct by kills
+export interface CareerStats {
+  mapName?: string; // omitted for stats over all maps
+  matches: number;
+  wins: number;
+  losses: number;
+  roundsPlayed: number;
+  kills: number;
+  deaths: number;
+  assists: number;
+  adr: number;
+  kast: number;
+  hsPct: number;
+  rating: number;
+  kpr: number;
+  dpr: number;
+  impact: number;
+  headshots: number;
+  totalDamage: number;
+  firstKills: number;
+  firstDeaths: number;
+  tradeKills: number;
+  flashAssists: number;
+  utilityDamage: number;
+  twoK: number;
+  threeK: number;
+  fourK: number;
+  fiveK: number;
+  sides?: SideStats[]; // CT then T; omitted for matches without round stats
+}
+
+export interface PlayerFilters {
+  mapName?: string;
+  dateFrom?: string; // ISO timestamp
+  dateTo?: string; // ISO timestamp
+}
+
+export interface GetPlayerProfileRequest extends PlayerFilters {
+  steamId: string;
+}
+
+export interface GetPlayerProfileResponse {
+  steamId: string;
+  name: string;
+  overall: CareerStats;
+  maps: CareerStats[]; // most played first
+}
+
+export interface GetPlayerHistoryRequest extends PlayerFilters {
+  steamId: string;
+  pageSize: number;
+  pageToken: string;
+}
+
+export interface PlayerMatch {
+  match: Match;
+  stats: PlayerStats;
+  roundsPlayed: number;
+  roundsWon: number; // by the player's team
+  roundsLost: number;
+}
+
+export interface GetPlayerHistoryResponse {
+  matches: PlayerMatch[];
+  nextPageToken: string;
+}
+
+// proto JSON serializes enums as strings
+export type LeaderboardMetric =
+  | "LEADERBOARD_METRIC_UNSPECIFIED"
+  | "LEADERBOARD_METRIC_RATING"
+  | "LEADERBOARD_METRIC_ADR"
+  | "LEADERBOARD_METRIC_KAST"
+  | "LEADERBOARD_METRIC_HS_PCT"
+  | "LEADERBOARD_METRIC_OPENING_KILL_SUCCESS"
+  | "LEADERBOARD_METRIC_CLUTCH_WIN_RATE";
+
+export interface LeaderboardFilters extends PlayerFilters {
+  metric?: LeaderboardMetric; // rating when unset
+  minRounds?: number;
+  minMatches?: number;
+}
+
+export interface GetLeaderboardRequest extends LeaderboardFilters {
+  pageSize: number;
+  pageToken: string;
+}
+
+// players with equal values share a rank
+export interface LeaderboardEntry {
+  rank: number;
+  steamId: string;
+  name: string;
+  value: number; // of the metric ranked by
+  matches: number;
+  roundsPlayed: number;
+  kills: number;
+  deaths: number;
+  firstKills: number;
+  firstDeaths: number;
+  clutches: number;
+  clutchesWon: number;
+  rating: number;
+  adr: number;
+  kast: number;
+  hsPct: number;
+  openingKillSuccess: number; // % of opening duels won
+  clutchWinRate: number; // % of clutches won
+}
+
+export interface GetLeaderboardResponse {
+  entries: LeaderboardEntry[];
+  nextPageToken: string;
+}
+
+export interface ComparePlayersRequest {
+  steamIdA: string;
+  steamIdB: string;
+}
+
+export interface ComparePlayersResponse {
+  playerA: ComparedPlayer;
+  playerB: ComparedPlayer;
+  matches: number; // both played
+  opposed: number; // of matches, on opposing teams
+  winsA: number; // of opposed matches
+  winsB: number;
+  deltas: StatDelta[];
+  duels: DuelRecord;
+}
+
+// stats over the matches both compared players played
+export interface ComparedPlayer {
+  steamId: string;
+  name: string;
+  stats: CareerStats;
+}
+
+export interface StatDelta {
+  stat: string; // e.g. rating, adr, kast or hs_pct
+  a: number;
+  b: number;
+  delta: number; // a - b
+}
+
+// kills of one player by the other, team kills aside
+export interface DuelRecord {
+  killsA: number; // of player B by player A
+  killsB: number;
+  headshotKillsA: number;
+  headshotKillsB: number;
+}
+
+// team.v1.TeamService
+
+export interface Team {
+  id: string;
+  name: string; // the clan name, or the players' names without one
+  clanName: string; // empty for a team identified by its roster
+  matches: number;
+  wins: number;
+  losses: number;
+  firstPlayed: string; // ISO timestamp
+  lastPlayed: string;
+}
+
+export interface TeamMember {
+  steamId: string;
+  name: string;
+  matches: number;
+  firstPlayed: string; // ISO timestamp
+  lastPlayed: string;
+}
+
+export interface GetTeamResponse {
+  team: Team;
+  roster: TeamMember[]; // most recently played first
+}
+
+export interface ListTeamsRequest {
+  pageSize: number;
+  pageToken: string;
+  name?: string; // part of the team name, ignoring case
+}
+
+export interface ListTeamsResponse {
+  teams: Team[];
+  nextPageToken: string;
+}
+
+export interface GetTeamMatchesRequest {
+  teamId: string;
+  pageSize: number;
+  pageToken: string;
+}
+
+export interface GetTeamMatchesResponse {
+  matches: Match[];
+  nextPageToken: string;
+}
+
+export interface CompareTeamsRequest {
+  teamAId: string;
+  teamBId: string;
+}
+
+// scores and rounds are from team A's point of view
+export interface CompareTeamsResponse {
+  teamA: Team;
+  teamB: Team;
+  winsA: number;
+  winsB: number;
+  draws: number;
+  roundsA: number;
+  roundsB: number;
+  roundDiff: number; // roundsA - roundsB
+  maps: HeadToHeadMap[]; // most played first
+  matches: HeadToHeadMatch[]; // newest first
+}
+
+export interface HeadToHeadMap {
+  mapName: string;
+  matches: number;
+  winsA: number;
+  winsB: number;
+  roundDiff: number;
+}
+
+export interface HeadToHeadMatch {
+  match: Match;
+  scoreA: number; // rounds won by team A
+  scoreB: number;
+  roundDiff: number;
+}
@@ -3,6 +3,7 @@ import { useNavigate } from "@tanstack/react-router";
 import { Upload, FileUp, Loader2 } from "lucide-react";
 import { toast } from "sonner";
 import { useUploadDemo } from "../api/queries";
+import type { IngestJobStatus } from "../api/types";
 import {
   Dialog,
   DialogContent,
@@ -12,6 +13,25 @@ import {
 } from "@/components/ui/dialog";
 import { cn } from "@/lib/utils";
 
+// progress shown for each ingest job state
+const statusProgress: Record<IngestJobStatus, number> = {
+  INGEST_JOB_STATUS_UNSPECIFIED: 0,
+  INGEST_JOB_STATUS_QUEUED: 20,
+  INGEST_JOB_STATUS_PARSING: 50,
+  INGEST_JOB_STATUS_STORING: 85,
+  INGEST_JOB_STATUS_DONE: 100,
+  INGEST_JOB_STATUS_FAILED: 0,
+};
+
+const statusLabel: Record<IngestJobStatus, string> = {
+  INGEST_JOB_STATUS_UNSPECIFIED: "Uploading...",
+  INGEST_JOB_STATUS_QUEUED: "Queued for parsing...",
+  INGEST_JOB_STATUS_PARSING: "Parsing demo...",
+  INGEST_JOB_STATUS_STORING: "Saving match...",
+  INGEST_JOB_STATUS_DONE: "Done",
+  INGEST_JOB_STATUS_FAILED: "Failed",
+};
+
 interface DemoUploadProps {
   open: boolean;
   onOpenChange: (open: boolean) => void;
@@ -22,48 +42,60 @@ export function DemoUpload({ open, onOpenChange }: DemoUploadProps) {
   const fileRef = useRef<HTMLInputElement>(null);
   const navigate = useNavigate();
   const upload = useUploadDemo();
-  const [progress, setProgress] = useState(0);
+  const [status, setStatus] = useState<IngestJobStatus>(
+    "INGEST_JOB_STATUS_UNSPECIFIED",
+  );
+  const [parsed, setParsed] = useState(0);
+  // while parsing, the bar fills from the queued mark towards the storing
+  // mark as the demo is read
+  const queued = statusProgress.INGEST_JOB_STATUS_QUEUED;
+  const storing = statusProgress.INGEST_JOB_STATUS_STORING;
+  const progress =
+    status === "INGEST_JOB_STATUS_PARSING"
+      ? queued + ((storing - queued) * parsed) / 100
+      : statusProgress[status];
+
+  const onStatus = useCallback((next: IngestJobStatus, percent?: number) => {
+    setStatus(next);
+    setParsed(percent ?? 0);
+  }, []);
 
   const handleFile = useCallback(
     (file: File) => {
-      setProgress(0);
-      // simulate progress while uploading
-      const interval = setInterval(() => {
-        setProgress((p) => Math.min(p + Math.random() * 15, 90));
-      }, 300);
+      setStatus("INGEST_JOB_STATUS_UNSPECIFIED");
 
-      upload.mutate(file, {
-        onSuccess: (data) => {
-          clearInterval(interval);
-          setProgress(100);
-          toast.success("Demo uploaded", {
-            description: `${file.name} parsed successfully`,
-            action: {
-              label: "View Match",
-              onClick: () => {
-                void navigate({
-                  to: "/matches/$matchId",
-                  params: { matchId: data.matchId },
-                });
+      upload.mutate(
+        { file, onStatus },
+        {
+          onSuccess: (job) => {
+            toast.success("Demo uploaded", {
+              description: `${file.name} parsed successfully`,
+              action: {
+                label: "View Match",
+                onClick: () => {
+                  void navigate({
+                    to: "/matches/$matchId",
+                    params: { matchId: job.matchId ?? "" },
+                  });
+                },
               },
-            },
-          });
-          setTimeout(() => {
-            onOpenChange(false);
-            setProgress(0);
-            upload.reset();
-          }, 800);
-        },
-        onError: (err) => {
-          clearInterval(interval);
-          setProgress(0);
-          toast.error("Upload error", {
-            description: err.message,
-          });
+            });
+            setTimeout(() => {
+              onOpenChange(false);
+              setStatus("INGEST_JOB_STATUS_UNSPECIFIED");
+              upload.reset();
+            }, 800);
+          },
+          onError: (err) => {
+            setStatus("INGEST_JOB_STATUS_UNSPECIFIED");
+            toast.error("Upload error", {
+              description: err.message,
+            });
+          },
         },
-      });
+      );
     },
-    [upload, onOpenChange, navigate],
+    [upload, onOpenChange, navigate, onStatus],
   );
 
   const onDrop = useCallback(
@@ -91,7 +123,7 @@ export function DemoUpload({ open, onOpenChange }: DemoUploadProps) {
         <DialogHeader>
           <DialogTitle>Upload Demo</DialogTitle>
           <DialogDescription>
-            Upload a CS2 .dem file to parse match data
+            Upload a CS2 .dem file, compressed or zipped, to parse match data
           </DialogDescription>
         </DialogHeader>
 
@@ -110,7 +142,7 @@ export function DemoUpload({ open, onOpenChange }: DemoUploadProps) {
           <input
             ref={fileRef}
             type="file"
-            accept=".dem"
+            accept=".dem,.gz,.bz2,.zst,.zip"
             className="hidden"
             onChange={(e) => {
               const file = e.target.files?.[0];
@@ -121,7 +153,7 @@ export function DemoUpload({ open, onOpenChange }: DemoUploadProps) {
             <div className="flex flex-col items-center gap-3">
               <Loader2 className="h-8 w-8 animate-spin text-team-ct" />
               <p className="text-sm text-muted-foreground">
-                Uploading and parsing...
+                {statusLabel[status]}
               </p>
               {/* progress bar */}
               <div className="h-2 w-full overflow-hidden rounded-full bg-muted">
@@ -140,7 +172,7 @@ export function DemoUpload({ open, onOpenChange }: DemoUploadProps) {
               )}
               <div>
                 <p className="text-sm font-medium text-foreground">
-                  Drag & drop a .dem file here
+                  Drag & drop a .dem, .gz, .bz2, .zst or .zip file here
                 </p>
                 <p className="mt-1 text-xs text-muted-foreground">
                   or click to browse
@@ -27,8 +27,7 @@ interface EconomyChartProps {
   rounds: EconomyRound[];
   teamAName: string;
   teamBName: string;
-  teamAStartedAs: string;
-  roundWinners: string[];
+  roundTeamAWon: boolean[];
 }
 
 function buyTypeLabel(bt: BuyType): string {
@@ -64,11 +63,6 @@ function buyTypeBadgeClass(bt: BuyType): string {
 const CT_COLOR = "#5B9BD5";
 const T_COLOR = "#EAC843";
 
-function teamAWonRound(roundNumber: number, winner: string, teamAStartedAs: string): boolean {
-  if (roundNumber <= 12) return winner === teamAStartedAs;
-  return winner !== teamAStartedAs;
-}
-
 function buyOutcomeBadge(buyType: BuyType, won: boolean): { label: string; cls: string } | null {
   const label = buyTypeLabel(buyType);
   if (!label) return null;
@@ -85,8 +79,7 @@ export function EconomyChart({
   rounds,
   teamAName,
   teamBName,
-  teamAStartedAs,
-  roundWinners,
+  roundTeamAWon,
 }: EconomyChartProps) {
   const data = rounds.map((r) => ({
     round: r.roundNumber,
@@ -106,9 +99,8 @@ export function EconomyChart({
     let teamBForceWins = 0;
 
     for (const r of rounds) {
-      const winner = roundWinners[r.roundNumber - 1];
-      if (!winner) continue;
-      const aWon = teamAWonRound(r.roundNumber, winner, teamAStartedAs);
+      const aWon = roundTeamAWon[r.roundNumber - 1];
+      if (aWon === undefined) continue;
 
       if (aWon) {
         if (r.teamABuyType === "BUY_TYPE_ECO") teamAEcoWins++;
@@ -119,7 +111,7 @@ export function EconomyChart({
       }
     }
     return { teamAEcoWins, teamBEcoWins, teamAForceWins, teamBForceWins };
-  }, [rounds, roundWinners, teamAStartedAs]);
+  }, [rounds, roundTeamAWon]);
 
   const hasNarrative =
     ecoStats.teamAEcoWins + ecoStats.teamBEcoWins + ecoStats.teamAForceWins + ecoStats.teamBForceWins > 0;
@@ -273,8 +265,7 @@ export function EconomyChart({
                   {teamAName}
                 </TableCell>
                 {rounds.map((r) => {
-                  const winner = roundWinners[r.roundNumber - 1];
-                  const aWon = winner ? teamAWonRound(r.roundNumber, winner, teamAStartedAs) : null;
+                  const aWon = roundTeamAWon[r.roundNumber - 1] ?? null;
                   const outcome = aWon !== null ? buyOutcomeBadge(r.teamABuyType, aWon) : null;
                   return (
                     <TableCell key={r.roundNumber} className="text-center">
@@ -300,8 +291,8 @@ export function EconomyChart({
                   {teamBName}
                 </TableCell>
                 {rounds.map((r) => {
-                  const winner = roundWinners[r.roundNumber - 1];
-                  const bWon = winner ? !teamAWonRound(r.roundNumber, winner, teamAStartedAs) : null;
+                  const won = roundTeamAWon[r.roundNumber - 1];
+                  const bWon = won === undefined ? null : !won;
                   const outcome = bWon !== null ? buyOutcomeBadge(r.teamBBuyType, bWon) : null;
                   return (
                     <TableCell key={r.roundNumber} className="text-center">
@@ -21,7 +21,6 @@ interface RoundTimelineProps {
   rounds: RoundEvent[];
   teamAName: string;
   teamBName: string;
-  teamAStartedAs: string;
   players: Player[];
 }
 
@@ -64,16 +63,12 @@ export function RoundTimeline({
   rounds,
   teamAName,
   teamBName,
-  teamAStartedAs,
   players,
 }: RoundTimelineProps) {
   const [selectedRound, setSelectedRound] = useState<number | null>(null);
 
-  // determine if teamA won each round (accounts for side swap at half)
-  const teamAWonRound = (r: RoundEvent): boolean => {
-    if (r.roundNumber <= 12) return r.winner === teamAStartedAs;
-    return r.winner !== teamAStartedAs;
-  };
+  // the server resolves which team won each round across side swaps
+  const teamAWonRound = (r: RoundEvent): boolean => r.teamAWon;
 
   // running score
   let scoreA = 0;
@@ -92,7 +87,7 @@ export function RoundTimeline({
       return { round: r.roundNumber, diff };
     });
   // eslint-disable-next-line react-hooks/exhaustive-deps
-  }, [rounds, teamAStartedAs]);
+  }, [rounds]);
 
   // compute streaks (4+ rounds)
   const streaks = useMemo(() => {
@@ -120,7 +115,7 @@ export function RoundTimeline({
     }
     return result;
   // eslint-disable-next-line react-hooks/exhaustive-deps
-  }, [rounds, teamAStartedAs]);
+  }, [rounds]);
 
   // half-time scores
   const htA = scores[11]?.a ?? 0;
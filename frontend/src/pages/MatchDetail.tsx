@@ -1,11 +1,12 @@
 import { lazy, Suspense } from "react";
-import { useParams } from "@tanstack/react-router";
+import { useNavigate, useParams } from "@tanstack/react-router";
 import {
   useGetMatch,
   usePlayerStats,
   useEconomyStats,
   useRoundTimeline,
   usePositionalData,
+  useDeleteMatch,
 } from "../api/queries";
 import { Scoreboard } from "../components/Scoreboard";
 import { RoundTimeline } from "../components/RoundTimeline";
@@ -15,7 +16,7 @@ import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs";
 import { MatchDetailSkeleton, ScoreboardSkeleton } from "@/components/skeletons";
 import { Skeleton } from "@/components/ui/skeleton";
 import { Badge } from "@/components/ui/badge";
-import { Clock, Calendar, Copy, Check, Map as MapIcon, Flame, Timer } from "lucide-react";
+import { Clock, Calendar, Copy, Check, Download, Trash2, Map as MapIcon, Flame, Timer } from "lucide-react";
 import { useState, useMemo } from "react";
 import type { RoundEvent } from "../api/types";
 import { toast } from "sonner";
@@ -87,8 +88,9 @@ function computeHalftimeScore(
   let teamAFirstHalf = 0;
   let teamBFirstHalf = 0;
   for (const r of rounds) {
-    if (r.roundNumber > 12) break;
-    if (r.winner === teamAStartedAs) {
+    // the first half ends at the first side swap
+    if (r.teamASide !== teamAStartedAs) break;
+    if (r.teamAWon) {
       teamAFirstHalf++;
     } else {
       teamBFirstHalf++;
@@ -97,19 +99,14 @@ function computeHalftimeScore(
   return { teamAFirstHalf, teamBFirstHalf };
 }
 
-function computeLongestStreak(rounds: RoundEvent[], teamAStartedAs: string): { team: "A" | "B"; length: number } {
+function computeLongestStreak(rounds: RoundEvent[]): { team: "A" | "B"; length: number } {
   let bestTeam: "A" | "B" = "A";
   let bestLen = 0;
   let curTeam: "A" | "B" | null = null;
   let curLen = 0;
 
   for (const r of rounds) {
-    // in first half, teamA plays as teamAStartedAs. In second half, sides swap.
-    const teamAWon =
-      r.roundNumber <= 12
-        ? r.winner === teamAStartedAs
-        : r.winner !== teamAStartedAs;
-    const team = teamAWon ? "A" as const : "B" as const;
+    const team = r.teamAWon ? "A" as const : "B" as const;
 
     if (team === curTeam) {
       curLen++;
@@ -133,6 +130,8 @@ export function MatchDetail() {
   const economyQ = useEconomyStats(matchId);
   const roundsQ = useRoundTimeline(matchId);
   const posQ = usePositionalData(matchId);
+  const deleteM = useDeleteMatch();
+  const navigate = useNavigate();
 
   if (matchQ.isLoading) {
     return <MatchDetailSkeleton />;
@@ -173,9 +172,9 @@ export function MatchDetail() {
   }, [roundData, match.teamAStartedAs]);
 
   const longestStreak = useMemo(() => {
-    if (roundData.length === 0 || !match.teamAStartedAs) return null;
-    return computeLongestStreak(roundData, match.teamAStartedAs);
-  }, [roundData, match.teamAStartedAs]);
+    if (roundData.length === 0) return null;
+    return computeLongestStreak(roundData);
+  }, [roundData]);
 
   return (
     <div className="space-y-6">
@@ -249,6 +248,38 @@ export function MatchDetail() {
               {formatDuration(match.durationSeconds)}
             </span>
             {match.demoFileHash && <CopyHash hash={match.demoFileHash} />}
+            {match.demoFileHash && (
+              <a
+                href={`/api/matches/${encodeURIComponent(matchId)}/demo`}
+                download
+                className="inline-flex items-center gap-1.5 rounded-md bg-muted px-2 py-1 text-xs text-muted-foreground transition-colors hover:bg-muted/80 hover:text-foreground"
+                title="Download the original demo"
+              >
+                <Download className="h-3 w-3" />
+                Demo
+              </a>
+            )}
+            <button
+              type="button"
+              disabled={deleteM.isPending}
+              onClick={() => {
+                if (!window.confirm("Delete this match and all of its stats?")) return;
+                deleteM.mutate(matchId, {
+                  onSuccess: () => {
+                    toast.success("Match deleted");
+                    void navigate({ to: "/matches" });
+                  },
+                  onError: (err) => {
+                    toast.error("Delete failed", { description: err.message });
+                  },
+                });
+              }}
+              className="inline-flex items-center gap-1.5 rounded-md bg-muted px-2 py-1 text-xs text-muted-foreground transition-colors hover:bg-destructive/20 hover:text-destructive disabled:opacity-50"
+              title="Delete this match"
+            >
+              <Trash2 className="h-3 w-3" />
+              Delete
+            </button>
           </div>
         </CardContent>
       </Card>
@@ -298,7 +329,6 @@ export function MatchDetail() {
               rounds={roundsQ.data.rounds}
               teamAName={match.teamAName}
               teamBName={match.teamBName}
-              teamAStartedAs={match.teamAStartedAs}
               players={players}
             />
           )}
@@ -320,8 +350,7 @@ export function MatchDetail() {
               rounds={economyQ.data.rounds}
               teamAName={match.teamAName}
               teamBName={match.teamBName}
-              teamAStartedAs={match.teamAStartedAs}
-              roundWinners={roundData.map((r) => r.winner)}
+              roundTeamAWon={roundData.map((r) => r.teamAWon)}
             />
           )}
         </TabsContent>
@@ -14,6 +14,9 @@ export default defineConfig({
     proxy: {
       "/demo.v1": "http://localhost:8080",
       "/stats.v1": "http://localhost:8080",
+      "/player.v1": "http://localhost:8080",
+      "/team.v1": "http://localhost:8080",
+      "/api": "http://localhost:8080",
     },
   },
 });
@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is the container a demo is delivered in, detected from its
+// leading magic bytes.
+type Format string
+
+const (
+	FormatDemo  Format = "dem" // an uncompressed demo, or anything unrecognised
+	FormatGzip  Format = "gzip"
+	FormatBzip2 Format = "bzip2"
+	FormatZstd  Format = "zstd"
+	FormatZip   Format = "zip"
+)
+
+// MaxDemoSize is the largest demo, after decompression, that Demos passes
+// on. It is well above any real match demo and stops decompression bombs.
+const MaxDemoSize = 2 << 30
+
+var (
+	// ErrNoDemos is returned by Demos for a zip archive without any .dem
+	// file.
+	ErrNoDemos = errors.New("no demos in archive")
+	// ErrDemoTooLarge is returned by Demos, or by reading one of the demos
+	// it passes on, for a demo larger than MaxDemoSize.
+	ErrDemoTooLarge = errors.New("demo too large")
+)
+
+var magics = []struct {
+	prefix []byte
+	format Format
+}{
+	{[]byte{0x1f, 0x8b}, FormatGzip},
+	{[]byte("BZh"), FormatBzip2},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, FormatZstd},
+	{[]byte("PK\x03\x04"), FormatZip},
+	{[]byte("PK\x05\x06"), FormatZip}, // empty archive
+}
+
+// DetectFormat returns the format whose magic bytes head b. It needs at
+// most the first four bytes of the input.
+func DetectFormat(b []byte) Format {
+	for _, m := range magics {
+		if bytes.HasPrefix(b, m.prefix) {
+			return m.format
+		}
+	}
+	return FormatDemo
+}
+
+// Demos calls fn with a reader over each demo in r, sniffing its format
+// first: a plain demo is passed through, a gzip, bzip2 or zstd stream is
+// decompressed, and every .dem file in a zip archive is passed in turn.
+// name is the file name inside the archive, or empty for a single demo.
+// Zip archives are read from r directly if it is an *os.File or
+// *bytes.Reader and spooled to a temporary file otherwise. Reading more
+// than MaxDemoSize bytes of a demo fails with ErrDemoTooLarge. Iteration
+// stops at the first error returned by fn.
+func Demos(r io.Reader, fn func(name string, demo io.Reader) error) error {
+	return demos(r, MaxDemoSize, fn)
+}
+
+// demos is Demos with the size limit as a parameter.
+func demos(r io.Reader, limit int64, fn func(name string, demo io.Reader) error) error {
+	limited := func(name string, demo io.Reader) error {
+		return fn(name, &sizeLimitReader{r: demo, n: limit})
+	}
+
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("sniff demo format: %w", err)
+	}
+
+	switch DetectFormat(magic) {
+	case FormatGzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("open gzip demo: %w", err)
+		}
+		defer zr.Close()
+		return limited("", zr)
+	case FormatBzip2:
+		return limited("", bzip2.NewReader(br))
+	case FormatZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("open zstd demo: %w", err)
+		}
+		defer zr.Close()
+		return limited("", zr)
+	case FormatZip:
+		return zipDemos(r, br, limit, limited)
+	default:
+		return limited("", br)
+	}
+}
+
+// zipDemos calls fn for every .dem file in the archive read from r. br is
+// the buffered reader already wrapping r, used when r must be spooled. A
+// file whose recorded size is over limit fails before it is opened.
+func zipDemos(r io.Reader, br *bufio.Reader, limit int64, fn func(name string, demo io.Reader) error) error {
+	var (
+		ra   io.ReaderAt
+		size int64
+	)
+	switch v := r.(type) {
+	case *os.File:
+		if _, err := v.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("rewind zip archive: %w", err)
+		}
+		fi, err := v.Stat()
+		if err != nil {
+			return fmt.Errorf("stat zip archive: %w", err)
+		}
+		ra, size = v, fi.Size()
+	case *bytes.Reader:
+		if _, err := v.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("rewind zip archive: %w", err)
+		}
+		ra, size = v, v.Size()
+	default:
+		// archives keep their directory at the end, so the whole
+		// stream has to land somewhere seekable first
+		f, err := os.CreateTemp("", "cs2stats-*.zip")
+		if err != nil {
+			return fmt.Errorf("spool zip archive: %w", err)
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		size, err = io.Copy(f, br)
+		if err != nil {
+			return fmt.Errorf("spool zip archive: %w", err)
+		}
+		ra = f
+	}
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return fmt.Errorf("open zip archive: %w", err)
+	}
+
+	found := false
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() || !strings.EqualFold(path.Ext(zf.Name), ".dem") {
+			continue
+		}
+		found = true
+		if zf.UncompressedSize64 > uint64(limit) {
+			return fmt.Errorf("%s in zip archive: %w", zf.Name, ErrDemoTooLarge)
+		}
+		if err := zipDemo(zf, fn); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return ErrNoDemos
+	}
+	return nil
+}
+
+func zipDemo(zf *zip.File, fn func(name string, demo io.Reader) error) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("open %s in zip archive: %w", zf.Name, err)
+	}
+	defer rc.Close()
+	return fn(zf.Name, rc)
+}
+
+// sizeLimitReader reads from r until more than n bytes have been read,
+// then fails with ErrDemoTooLarge.
+type sizeLimitReader struct {
+	r io.Reader
+	n int64 // bytes left before the limit
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrDemoTooLarge
+	}
+	// read one byte past the limit to tell an exact fit from an overrun
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n - 1, ErrDemoTooLarge
+	}
+	return n, err
+}
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -186,3 +189,49 @@ func TestBuildMatchDateSet(t *testing.T) {
 		t.Fatal("time went backwards")
 	}
 }
+
+// TestParseContextCS2Demo checks progress reporting and mid-parse
+// cancellation against the real CS2 demo, if available.
+func TestParseContextCS2Demo(t *testing.T) {
+	const demoPath = "/tmp/cs2-test-demos/s2/s2.dem"
+
+	data, err := os.ReadFile(demoPath)
+	if err != nil {
+		t.Skipf("test demo not available: %v", err)
+	}
+
+	var last Progress
+	reports := 0
+	opts := DefaultOptions()
+	opts.Progress = func(p Progress) {
+		if p.Percent < last.Percent || p.Ticks < last.Ticks {
+			t.Errorf("progress went backwards: %+v after %+v", p, last)
+		}
+		last = p
+		reports++
+	}
+	if _, err := ParseContext(context.Background(), bytes.NewReader(data), opts); err != nil {
+		t.Fatalf("parse CS2 demo: %v", err)
+	}
+	if reports < 2 {
+		t.Errorf("got %d progress reports, want at least 2", reports)
+	}
+	if last.Percent < 99 {
+		t.Errorf("final progress %.1f%%, want ~100%%", last.Percent)
+	}
+	if last.Round == 0 {
+		t.Error("final progress has no round")
+	}
+
+	// cancel once the match is under way
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts.Progress = func(p Progress) {
+		if p.Round > 0 {
+			cancel()
+		}
+	}
+	if _, err := ParseContext(ctx, bytes.NewReader(data), opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled parse error = %v, want context.Canceled", err)
+	}
+}
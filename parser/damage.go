@@ -0,0 +1,60 @@
+package parser
+
+import (
+	events "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// mapHitGroup converts a demoinfocs HitGroup to a readable label.
+func mapHitGroup(hg events.HitGroup) string {
+	switch hg {
+	case events.HitGroupHead:
+		return "Head"
+	case events.HitGroupNeck:
+		return "Neck"
+	case events.HitGroupChest:
+		return "Chest"
+	case events.HitGroupStomach:
+		return "Stomach"
+	case events.HitGroupLeftArm:
+		return "LeftArm"
+	case events.HitGroupRightArm:
+		return "RightArm"
+	case events.HitGroupLeftLeg:
+		return "LeftLeg"
+	case events.HitGroupRightLeg:
+		return "RightLeg"
+	case events.HitGroupGear:
+		return "Gear"
+	default:
+		return "Generic"
+	}
+}
+
+// damageEventFromHurt builds a DamageEvent from a PlayerHurt event.
+// healthDamage is the overkill-adjusted damage computed by onPlayerHurt.
+func (s *parseState) damageEventFromHurt(e events.PlayerHurt, healthDamage int) DamageEvent {
+	armorDmg := e.ArmorDamageTaken
+	if armorDmg <= 0 {
+		armorDmg = e.ArmorDamage
+	}
+
+	de := DamageEvent{
+		Tick:           s.p.GameState().IngameTick(),
+		RoundNumber:    s.roundNum,
+		VictimSteamID:  e.Player.SteamID64,
+		VictimName:     e.Player.Name,
+		VictimPosition: vecToPosition(e.Player.Position()),
+		HitGroup:       mapHitGroup(e.HitGroup),
+		HealthDamage:   healthDamage,
+		ArmorDamage:    armorDmg,
+	}
+	if e.Attacker != nil {
+		de.AttackerSteamID = e.Attacker.SteamID64
+		de.AttackerName = e.Attacker.Name
+		de.AttackerPosition = vecToPosition(e.Attacker.Position())
+	}
+	if e.Weapon != nil {
+		de.Weapon = e.Weapon.String()
+	}
+	return de
+}
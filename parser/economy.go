@@ -37,6 +37,29 @@ func snapshotTeamEconomy(team *common.TeamState, roundNum int) EconomySnapshot {
 	}
 }
 
+// playerEconomy is one player's spending for a round.
+type playerEconomy struct {
+	equipmentValue int
+	moneySpent     int
+}
+
+// snapshotPlayerEconomy captures per-player equipment value and spend,
+// keyed by steam ID. It reads the same entity properties as the team
+// fallbacks below.
+func snapshotPlayerEconomy(players []*common.Player) map[uint64]playerEconomy {
+	out := make(map[uint64]playerEconomy, len(players))
+	for _, pl := range players {
+		if pl == nil || pl.SteamID64 == 0 {
+			continue
+		}
+		out[pl.SteamID64] = playerEconomy{
+			equipmentValue: pl.EquipmentValueCurrent(),
+			moneySpent:     pl.MoneySpentThisRound(),
+		}
+	}
+	return out
+}
+
 // sumEquipmentValue sums EquipmentValueCurrent across all team members.
 // This reads m_unCurrentEquipmentValue from each player's pawn entity,
 // which is reliably populated in CS2 demos at freeze time end.
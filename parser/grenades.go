@@ -0,0 +1,135 @@
+package parser
+
+import (
+	common "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// mapGrenadeType converts a demoinfocs EquipmentType to our GrenadeType.
+func mapGrenadeType(eq common.EquipmentType) GrenadeType {
+	switch eq {
+	case common.EqSmoke:
+		return GrenadeTypeSmoke
+	case common.EqFlash:
+		return GrenadeTypeFlash
+	case common.EqHE:
+		return GrenadeTypeHE
+	case common.EqMolotov:
+		return GrenadeTypeMolotov
+	case common.EqIncendiary:
+		return GrenadeTypeIncendiary
+	case common.EqDecoy:
+		return GrenadeTypeDecoy
+	default:
+		return GrenadeTypeUnknown
+	}
+}
+
+func (s *parseState) onGrenadeThrow(e events.GrenadeProjectileThrow) {
+	if s.roundNum == 0 || e.Projectile == nil {
+		return
+	}
+
+	g := GrenadeEvent{
+		Tick:        s.p.GameState().IngameTick(),
+		RoundNumber: s.roundNum,
+	}
+	if e.Projectile.WeaponInstance != nil {
+		g.Type = mapGrenadeType(e.Projectile.WeaponInstance.Type)
+	}
+	if thrower := e.Projectile.Thrower; thrower != nil {
+		g.ThrowerSteamID = thrower.SteamID64
+		g.ThrowerName = thrower.Name
+		g.ThrowPosition = vecToPosition(thrower.Position())
+	}
+	if e.Projectile.Entity != nil {
+		g.ThrowPosition = vecToPosition(e.Projectile.Position())
+		s.grenadeIndex[e.Projectile.Entity.ID()] = len(s.roundGrenades)
+	}
+
+	s.roundGrenades = append(s.roundGrenades, g)
+}
+
+// onGrenadeDetonate handles the smoke, flash, HE and decoy detonation
+// events, which all carry the projectile entity ID of the thrown grenade.
+func (s *parseState) onGrenadeDetonate(e events.GrenadeEvent) {
+	if s.roundNum == 0 {
+		return
+	}
+
+	tick := s.p.GameState().IngameTick()
+	if i, ok := s.grenadeIndex[e.GrenadeEntityID]; ok {
+		s.roundGrenades[i].DetonationPosition = vecToPosition(e.Position)
+		s.roundGrenades[i].DetonationTick = tick
+		return
+	}
+
+	// no throw was observed (e.g. thrown before the round started);
+	// record the detonation on its own
+	g := GrenadeEvent{
+		Tick:               tick,
+		RoundNumber:        s.roundNum,
+		Type:               mapGrenadeType(e.GrenadeType),
+		DetonationPosition: vecToPosition(e.Position),
+		DetonationTick:     tick,
+	}
+	if e.Thrower != nil {
+		g.ThrowerSteamID = e.Thrower.SteamID64
+		g.ThrowerName = e.Thrower.Name
+	}
+	s.roundGrenades = append(s.roundGrenades, g)
+}
+
+func (s *parseState) onSmokeStart(e events.SmokeStart)     { s.onGrenadeDetonate(e.GrenadeEvent) }
+func (s *parseState) onFlashExplode(e events.FlashExplode) { s.onGrenadeDetonate(e.GrenadeEvent) }
+func (s *parseState) onHeExplode(e events.HeExplode)       { s.onGrenadeDetonate(e.GrenadeEvent) }
+func (s *parseState) onDecoyStart(e events.DecoyStart)     { s.onGrenadeDetonate(e.GrenadeEvent) }
+
+// onInfernoStart sets the detonation point of molotovs and incendiaries.
+// Infernos are separate entities from the projectile, so we attach the fire
+// to the thrower's most recent fire grenade in the round.
+func (s *parseState) onInfernoStart(e events.InfernoStart) {
+	if s.roundNum == 0 || e.Inferno == nil || e.Inferno.Entity == nil {
+		return
+	}
+	thrower := e.Inferno.Thrower()
+	if thrower == nil {
+		return
+	}
+
+	for i := len(s.roundGrenades) - 1; i >= 0; i-- {
+		g := &s.roundGrenades[i]
+		if g.ThrowerSteamID != thrower.SteamID64 {
+			continue
+		}
+		if g.Type != GrenadeTypeMolotov && g.Type != GrenadeTypeIncendiary {
+			continue
+		}
+		if g.DetonationTick != 0 {
+			continue
+		}
+		g.DetonationPosition = vecToPosition(e.Inferno.Entity.Position())
+		g.DetonationTick = s.p.GameState().IngameTick()
+		return
+	}
+}
+
+// onGrenadeDestroy finalizes grenades whose detonation was not reported by
+// a dedicated event, using the projectile's last known position.
+func (s *parseState) onGrenadeDestroy(e events.GrenadeProjectileDestroy) {
+	if e.Projectile == nil || e.Projectile.Entity == nil {
+		return
+	}
+	id := e.Projectile.Entity.ID()
+	i, ok := s.grenadeIndex[id]
+	if !ok {
+		return
+	}
+	// entity IDs are reused, so forget the mapping once the projectile is gone
+	delete(s.grenadeIndex, id)
+
+	if s.roundGrenades[i].DetonationTick == 0 {
+		s.roundGrenades[i].DetonationPosition = vecToPosition(e.Projectile.Position())
+		s.roundGrenades[i].DetonationTick = s.p.GameState().IngameTick()
+	}
+}
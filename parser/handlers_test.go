@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
+	common "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+	st "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/sendtables"
+)
+
+// fakeParser serves the game state parseState handlers read, so synthetic
+// events can be fed through them. Other Parser methods are not
+// implemented.
+type fakeParser struct {
+	demoinfocs.Parser
+	gs *fakeGameState
+}
+
+func (p *fakeParser) GameState() demoinfocs.GameState { return p.gs }
+func (p *fakeParser) CurrentTime() time.Duration {
+	return time.Duration(p.gs.tick) * time.Second / 64
+}
+
+type fakeGameState struct {
+	demoinfocs.GameState
+	tick    int
+	playing []*common.Player
+}
+
+func (gs *fakeGameState) IngameTick() int                          { return gs.tick }
+func (gs *fakeGameState) TeamCounterTerrorists() *common.TeamState { return nil }
+func (gs *fakeGameState) TeamTerrorists() *common.TeamState        { return nil }
+func (gs *fakeGameState) Participants() demoinfocs.Participants {
+	return fakeParticipants{playing: gs.playing}
+}
+
+type fakeParticipants struct {
+	demoinfocs.Participants
+	playing []*common.Player
+}
+
+func (p fakeParticipants) Playing() []*common.Player { return p.playing }
+
+// fakeEntity is an entity with an ID, a position and integer properties.
+type fakeEntity struct {
+	st.Entity
+	id    int
+	pos   r3.Vector
+	props map[string]int
+}
+
+func (e *fakeEntity) ID() int             { return e.id }
+func (e *fakeEntity) Position() r3.Vector { return e.pos }
+func (e *fakeEntity) PropertyValueMust(name string) st.PropertyValue {
+	return st.PropertyValue{IntVal: e.props[name]}
+}
+
+// fakeDemoInfo is the demo information players and infernos look up,
+// describing a Source 1 demo with no entities.
+type fakeDemoInfo struct{}
+
+func (fakeDemoInfo) IngameTick() int                              { return 0 }
+func (fakeDemoInfo) TickRate() float64                            { return 64 }
+func (fakeDemoInfo) FindPlayerByHandle(uint64) *common.Player     { return nil }
+func (fakeDemoInfo) FindPlayerByPawnHandle(uint64) *common.Player { return nil }
+func (fakeDemoInfo) PlayerResourceEntity() st.Entity              { return nil }
+func (fakeDemoInfo) FindWeaponByEntityID(int) *common.Equipment   { return nil }
+func (fakeDemoInfo) FindEntityByHandle(uint64) st.Entity          { return nil }
+func (fakeDemoInfo) IsSource2() bool                              { return false }
+
+// newTestState returns a parseState on a fake parser, with the handlers
+// not registered so tests call them directly.
+func newTestState() (*parseState, *fakeGameState) {
+	gs := &fakeGameState{}
+	return newParseState(&fakeParser{gs: gs}, DefaultOptions()), gs
+}
+
+func newTestPlayer(steamID uint64, name string, team common.Team) *common.Player {
+	pl := common.NewPlayer(fakeDemoInfo{})
+	pl.SteamID64 = steamID
+	pl.Name = name
+	pl.Team = team
+	return pl
+}
+
+func newTestProjectile(entityID int, eq common.EquipmentType, thrower *common.Player, pos r3.Vector) *common.GrenadeProjectile {
+	return &common.GrenadeProjectile{
+		Entity:         &fakeEntity{id: entityID, pos: pos},
+		WeaponInstance: &common.Equipment{Type: eq},
+		Thrower:        thrower,
+		Owner:          thrower,
+	}
+}
+
+func TestGrenadeHandlers(t *testing.T) {
+	s, gs := newTestState()
+	ct := newTestPlayer(1, "ct", common.TeamCounterTerrorists)
+	tt := newTestPlayer(2, "t", common.TeamTerrorists)
+
+	s.onMatchStart(events.MatchStart{})
+	s.onRoundStart(events.RoundStart{})
+
+	// a smoke detonates through its own event
+	gs.tick = 100
+	smoke := newTestProjectile(10, common.EqSmoke, ct, r3.Vector{X: 1, Y: 2, Z: 3})
+	s.onGrenadeThrow(events.GrenadeProjectileThrow{Projectile: smoke})
+	gs.tick = 150
+	s.onSmokeStart(events.SmokeStart{GrenadeEvent: events.GrenadeEvent{
+		GrenadeType: common.EqSmoke, GrenadeEntityID: 10, Position: r3.Vector{X: 10, Y: 20, Z: 3}, Thrower: ct,
+	}})
+	s.onGrenadeDestroy(events.GrenadeProjectileDestroy{Projectile: smoke})
+
+	// a molotov is placed by the inferno it starts, not by its projectile
+	gs.tick = 200
+	molotov := newTestProjectile(11, common.EqMolotov, tt, r3.Vector{X: 4, Y: 5, Z: 6})
+	s.onGrenadeThrow(events.GrenadeProjectileThrow{Projectile: molotov})
+	gs.tick = 240
+	fire := &fakeEntity{id: 90, pos: r3.Vector{X: 40, Y: 50, Z: 6}}
+	s.onInfernoStart(events.InfernoStart{Inferno: common.NewInferno(fakeDemoInfo{}, fire, tt)})
+	molotov.Entity.(*fakeEntity).pos = r3.Vector{X: 41, Y: 51, Z: 6}
+	s.onGrenadeDestroy(events.GrenadeProjectileDestroy{Projectile: molotov})
+
+	// a decoy without a detonation event lands where it was destroyed
+	gs.tick = 300
+	decoy := newTestProjectile(12, common.EqDecoy, ct, r3.Vector{X: 7, Y: 8, Z: 9})
+	s.onGrenadeThrow(events.GrenadeProjectileThrow{Projectile: decoy})
+	gs.tick = 320
+	decoy.Entity.(*fakeEntity).pos = r3.Vector{X: 70, Y: 80, Z: 9}
+	s.onGrenadeDestroy(events.GrenadeProjectileDestroy{Projectile: decoy})
+
+	// the entity ID is reused for a later flash, which must not update the
+	// finished smoke
+	gs.tick = 400
+	flash := newTestProjectile(10, common.EqFlash, tt, r3.Vector{X: 5, Y: 5, Z: 5})
+	s.onGrenadeThrow(events.GrenadeProjectileThrow{Projectile: flash})
+	gs.tick = 420
+	s.onFlashExplode(events.FlashExplode{GrenadeEvent: events.GrenadeEvent{
+		GrenadeType: common.EqFlash, GrenadeEntityID: 10, Position: r3.Vector{X: 50, Y: 50, Z: 5}, Thrower: tt,
+	}})
+
+	// a HE thrown before the round started only detonates
+	gs.tick = 500
+	s.onHeExplode(events.HeExplode{GrenadeEvent: events.GrenadeEvent{
+		GrenadeType: common.EqHE, GrenadeEntityID: 13, Position: r3.Vector{X: 60, Y: 60, Z: 6}, Thrower: ct,
+	}})
+
+	s.onRoundEnd(events.RoundEnd{Winner: common.TeamCounterTerrorists})
+
+	if len(s.rounds) != 1 {
+		t.Fatalf("rounds: got %d, want 1", len(s.rounds))
+	}
+	want := []GrenadeEvent{
+		{Tick: 100, RoundNumber: 1, Type: GrenadeTypeSmoke, ThrowerSteamID: 1, ThrowerName: "ct",
+			ThrowPosition: Position{X: 1, Y: 2, Z: 3}, DetonationPosition: Position{X: 10, Y: 20, Z: 3}, DetonationTick: 150},
+		{Tick: 200, RoundNumber: 1, Type: GrenadeTypeMolotov, ThrowerSteamID: 2, ThrowerName: "t",
+			ThrowPosition: Position{X: 4, Y: 5, Z: 6}, DetonationPosition: Position{X: 40, Y: 50, Z: 6}, DetonationTick: 240},
+		{Tick: 300, RoundNumber: 1, Type: GrenadeTypeDecoy, ThrowerSteamID: 1, ThrowerName: "ct",
+			ThrowPosition: Position{X: 7, Y: 8, Z: 9}, DetonationPosition: Position{X: 70, Y: 80, Z: 9}, DetonationTick: 320},
+		{Tick: 400, RoundNumber: 1, Type: GrenadeTypeFlash, ThrowerSteamID: 2, ThrowerName: "t",
+			ThrowPosition: Position{X: 5, Y: 5, Z: 5}, DetonationPosition: Position{X: 50, Y: 50, Z: 5}, DetonationTick: 420},
+		{Tick: 500, RoundNumber: 1, Type: GrenadeTypeHE, ThrowerSteamID: 1, ThrowerName: "ct",
+			DetonationPosition: Position{X: 60, Y: 60, Z: 6}, DetonationTick: 500},
+	}
+	got := s.rounds[0].Grenades
+	if len(got) != len(want) {
+		t.Fatalf("grenades: got %d, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("grenade %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGrenadeHandlersWarmup(t *testing.T) {
+	s, gs := newTestState()
+	ct := newTestPlayer(1, "ct", common.TeamCounterTerrorists)
+
+	// a smoke thrown in a warmup round is still in flight at match start
+	s.onRoundStart(events.RoundStart{})
+	gs.tick = 100
+	warmup := newTestProjectile(10, common.EqSmoke, ct, r3.Vector{X: 1, Y: 1, Z: 1})
+	s.onGrenadeThrow(events.GrenadeProjectileThrow{Projectile: warmup})
+	s.onMatchStart(events.MatchStart{})
+
+	// before round 1 starts nothing is recorded
+	gs.tick = 150
+	s.onSmokeStart(events.SmokeStart{GrenadeEvent: events.GrenadeEvent{
+		GrenadeType: common.EqSmoke, GrenadeEntityID: 10, Position: r3.Vector{X: 2, Y: 2, Z: 2}, Thrower: ct,
+	}})
+	s.onGrenadeDestroy(events.GrenadeProjectileDestroy{Projectile: warmup})
+	if len(s.roundGrenades) != 0 || len(s.grenadeIndex) != 0 {
+		t.Fatalf("warmup grenades kept after match start: %+v", s.roundGrenades)
+	}
+
+	s.onRoundStart(events.RoundStart{})
+	gs.tick = 200
+	s.onRoundEnd(events.RoundEnd{Winner: common.TeamCounterTerrorists})
+	if len(s.rounds) != 1 || len(s.rounds[0].Grenades) != 0 {
+		t.Errorf("round 1 grenades: got %+v, want none", s.rounds)
+	}
+}
+
+func TestInfernoStartSkipsDetonated(t *testing.T) {
+	s, gs := newTestState()
+	tt := newTestPlayer(2, "t", common.TeamTerrorists)
+
+	s.onMatchStart(events.MatchStart{})
+	s.onRoundStart(events.RoundStart{})
+
+	// two molotovs in flight at once: the second inferno belongs to the
+	// molotov that has not burned yet
+	gs.tick = 200
+	s.onGrenadeThrow(events.GrenadeProjectileThrow{Projectile: newTestProjectile(11, common.EqMolotov, tt, r3.Vector{X: 1})})
+	gs.tick = 210
+	s.onGrenadeThrow(events.GrenadeProjectileThrow{Projectile: newTestProjectile(12, common.EqIncendiary, tt, r3.Vector{X: 2})})
+	gs.tick = 240
+	first := &fakeEntity{id: 90, pos: r3.Vector{X: 20}}
+	s.onInfernoStart(events.InfernoStart{Inferno: common.NewInferno(fakeDemoInfo{}, first, tt)})
+	gs.tick = 250
+	second := &fakeEntity{id: 91, pos: r3.Vector{X: 10}}
+	s.onInfernoStart(events.InfernoStart{Inferno: common.NewInferno(fakeDemoInfo{}, second, tt)})
+
+	s.onRoundEnd(events.RoundEnd{Winner: common.TeamTerrorists})
+
+	got := s.rounds[0].Grenades
+	if len(got) != 2 {
+		t.Fatalf("grenades: got %d, want 2: %+v", len(got), got)
+	}
+	if got[0].DetonationTick != 250 || got[0].DetonationPosition.X != 10 {
+		t.Errorf("molotov: detonated at tick %d x %v, want 250 x 10", got[0].DetonationTick, got[0].DetonationPosition.X)
+	}
+	if got[1].DetonationTick != 240 || got[1].DetonationPosition.X != 20 {
+		t.Errorf("incendiary: detonated at tick %d x %v, want 240 x 20", got[1].DetonationTick, got[1].DetonationPosition.X)
+	}
+}
+
+func TestPlayerFlashedDead(t *testing.T) {
+	s, _ := newTestState()
+	ct := newTestPlayer(1, "ct", common.TeamCounterTerrorists)
+	tt := newTestPlayer(2, "t", common.TeamTerrorists)
+	mate := newTestPlayer(3, "mate", common.TeamTerrorists)
+	for _, pl := range []*common.Player{ct, mate} {
+		pl.Entity = &fakeEntity{props: map[string]int{"m_lifeState": 2}} // dead
+	}
+
+	s.onMatchStart(events.MatchStart{})
+	s.onRoundStart(events.RoundStart{})
+	s.onPlayerFlashed(events.PlayerFlashed{Player: ct, Attacker: tt})
+	s.onPlayerFlashed(events.PlayerFlashed{Player: mate, Attacker: tt})
+
+	if pt := s.players[tt.SteamID64]; pt != nil && (pt.enemiesFlashed != 0 || pt.teammatesFlashed != 0) {
+		t.Errorf("flashes: got %d enemies %d teammates, want none", pt.enemiesFlashed, pt.teammatesFlashed)
+	}
+	if _, ok := s.blinded[ct.SteamID64]; ok {
+		t.Error("dead player recorded as blinded")
+	}
+
+	// the same flash on a living enemy counts
+	ct.Entity = nil
+	s.onPlayerFlashed(events.PlayerFlashed{Player: ct, Attacker: tt})
+	if pt := s.players[tt.SteamID64]; pt == nil || pt.enemiesFlashed != 1 {
+		t.Errorf("enemies flashed: got %+v, want 1", pt)
+	}
+}
+
+func TestPlayerRoundsAfterDisconnect(t *testing.T) {
+	s, gs := newTestState()
+	ct := newTestPlayer(1, "ct", common.TeamCounterTerrorists)
+	t1 := newTestPlayer(2, "killed then left", common.TeamTerrorists)
+	t2 := newTestPlayer(3, "left early", common.TeamTerrorists)
+	gs.playing = []*common.Player{ct, t1, t2}
+
+	s.onMatchStart(events.MatchStart{})
+	s.onRoundStart(events.RoundStart{})
+
+	// one T leaves without doing anything, the other after being killed
+	gs.playing = []*common.Player{ct, t1}
+	gs.tick = 100
+	s.onKill(events.Kill{Killer: ct, Victim: t1, Weapon: &common.Equipment{Type: common.EqAK47}})
+	gs.playing = []*common.Player{ct}
+
+	gs.tick = 200
+	s.onRoundEnd(events.RoundEnd{Winner: common.TeamCounterTerrorists})
+
+	got := s.rounds[0].Players
+	want := []struct {
+		steamID uint64
+		side    Side
+		kills   int
+		deaths  int
+	}{
+		{1, SideCT, 1, 0},
+		{2, SideT, 0, 1},
+		{3, SideT, 0, 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("player rounds: got %+v, want %d rows", got, len(want))
+	}
+	for i, w := range want {
+		pr := got[i]
+		if pr.SteamID != w.steamID || pr.Side != w.side || pr.Kills != w.kills || pr.Deaths != w.deaths {
+			t.Errorf("row %d: got %d %s %d/%d, want %d %s %d/%d",
+				i, pr.SteamID, pr.Side, pr.Kills, pr.Deaths, w.steamID, w.side, w.kills, w.deaths)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"time"
 
 	demoinfocs "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs"
@@ -11,12 +14,48 @@ import (
 	msgs2 "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/msgs2"
 )
 
-// Parse reads a CS2 demo from r and returns a complete match analysis.
+// Version identifies the output of this parser. Bump it with every change
+// that alters the results parsed from an existing demo, so matches parsed
+// by an older version can be found and reprocessed.
+const Version = 2
+
+// Parse reads a CS2 demo from r and returns a complete match analysis
+// using DefaultOptions.
 func Parse(r io.Reader) (*Match, error) {
-	p := demoinfocs.NewParser(r)
+	return ParseWithOptions(r, DefaultOptions())
+}
+
+// ParseWithOptions is like Parse but allows configuring optional behaviour
+// such as the replay sample interval.
+func ParseWithOptions(r io.Reader, opts Options) (*Match, error) {
+	return ParseContext(context.Background(), r, opts)
+}
+
+// ParseContext is like ParseWithOptions but stops between demo frames once
+// ctx is done, returning ctx.Err(). Progress is reported through
+// opts.Progress if set. A panic inside the demo library, which some
+// corrupt demos trigger, is returned as an error.
+func ParseContext(ctx context.Context, r io.Reader, opts Options) (m *Match, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			m, err = nil, fmt.Errorf("parser panic: %v", rec)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("parse demo: %w", err)
+	}
+
+	size := opts.Size
+	if size <= 0 {
+		size = inputSize(r)
+	}
+	cr := &countingReader{r: r}
+
+	p := demoinfocs.NewParser(cr)
 	defer p.Close()
 
-	s := newParseState(p)
+	s := newParseState(p, opts)
 	s.registerHandlers()
 
 	// CS2 demos (Source 2) do not populate header fields like MapName or
@@ -39,13 +78,26 @@ func Parse(r io.Reader) (*Match, error) {
 	}
 	s.match.Duration = header.PlaybackTime
 
-	err = p.ParseToEnd()
-	if err != nil {
-		return nil, fmt.Errorf("parse demo: %w", err)
+	for frames := 1; ; frames++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("parse demo: %w", err)
+		}
+
+		more, err := p.ParseNextFrame()
+		if err != nil {
+			return nil, fmt.Errorf("parse demo: %w", err)
+		}
+		if !more {
+			break
+		}
+		if frames%progressFrames == 0 {
+			s.reportProgress(cr, size)
+		}
 	}
+	s.reportProgress(cr, size)
 
 	// CS2 headers always report zero PlaybackTime. Fall back to the parser's
-	// current game time which, after ParseToEnd, equals the demo length.
+	// current game time which, after the last frame, equals the demo length.
 	if s.match.Duration == 0 {
 		s.match.Duration = p.CurrentTime()
 	}
@@ -56,6 +108,7 @@ func Parse(r io.Reader) (*Match, error) {
 // parseState holds mutable state accumulated during parsing.
 type parseState struct {
 	p     demoinfocs.Parser
+	opts  Options
 	match matchState
 
 	players     map[uint64]*playerTracker
@@ -65,6 +118,20 @@ type parseState struct {
 	roundBomb   *BombEvent
 	roundDefuse *BombEvent
 
+	// utility thrown this round; grenadeIndex maps a live projectile
+	// entity ID to its position in roundGrenades
+	roundGrenades []GrenadeEvent
+	grenadeIndex  map[int]int
+
+	// every hit this round, from PlayerHurt or the CS2 entity fallback
+	roundDamage []DamageEvent
+
+	// replay sampling; roundLive is true between round start and round end
+	roundFrames []ReplayFrame
+	roundLive   bool
+	lastSample  time.Duration
+	hasSampled  bool
+
 	// alive tracking per round for clutch detection
 	// initial* maps are snapshots at freeze time end (not modified by kills)
 	// alive* maps are modified during the round as kills happen
@@ -76,16 +143,28 @@ type parseState struct {
 	// trade detection: recent deaths in current round
 	recentDeaths []recentDeath
 
+	// flash-kill attribution: victim steam ID -> most recent enemy flash,
+	// and a fallback ID source for flashes without a projectile
+	blinded     map[uint64]blindInfo
+	nextFlashID int64
+
 	// first kill tracking per round
 	roundHasFirstKill bool
 
-	// team name tracking
-	ctName string
-	tName  string
+	// the side each player took part in the current round on, kept for
+	// players who disconnect before it ends
+	roundSides map[uint64]Side
+
+	// stable team identity: steam ID -> index into Match.Teams, the side
+	// each team is currently playing, and their clan names
+	teamOf    map[uint64]int
+	teamSides [2]Side
+	teamNames [2]string
 
 	// economy snapshots taken at freeze time end
-	ctEconomy EconomySnapshot
-	tEconomy  EconomySnapshot
+	ctEconomy  EconomySnapshot
+	tEconomy   EconomySnapshot
+	playerEcon map[uint64]playerEconomy
 
 	// entity-based damage tracking for CS2 demos (no PlayerHurt events)
 	prevDamage     map[uint64]int
@@ -103,15 +182,21 @@ type matchState struct {
 	Duration time.Duration
 }
 
-func newParseState(p demoinfocs.Parser) *parseState {
+func newParseState(p demoinfocs.Parser, opts Options) *parseState {
 	return &parseState{
 		p:              p,
+		opts:           opts,
 		players:        make(map[uint64]*playerTracker),
 		initialAliveCT: make(map[uint64]bool),
 		initialAliveT:  make(map[uint64]bool),
 		aliveCT:        make(map[uint64]bool),
 		aliveT:         make(map[uint64]bool),
 		prevDamage:     make(map[uint64]int),
+		grenadeIndex:   make(map[int]int),
+		blinded:        make(map[uint64]blindInfo),
+		roundSides:     make(map[uint64]Side),
+		teamOf:         make(map[uint64]int),
+		teamSides:      [2]Side{SideCT, SideT},
 	}
 }
 
@@ -121,9 +206,18 @@ func (s *parseState) registerHandlers() {
 	s.p.RegisterEventHandler(s.onRoundFreezetimeEnd)
 	s.p.RegisterEventHandler(s.onKill)
 	s.p.RegisterEventHandler(s.onPlayerHurt)
+	s.p.RegisterEventHandler(s.onPlayerFlashed)
 	s.p.RegisterEventHandler(s.onBombPlanted)
 	s.p.RegisterEventHandler(s.onBombDefused)
 	s.p.RegisterEventHandler(s.onRoundEnd)
+	s.p.RegisterEventHandler(s.onGrenadeThrow)
+	s.p.RegisterEventHandler(s.onGrenadeDestroy)
+	s.p.RegisterEventHandler(s.onSmokeStart)
+	s.p.RegisterEventHandler(s.onFlashExplode)
+	s.p.RegisterEventHandler(s.onHeExplode)
+	s.p.RegisterEventHandler(s.onDecoyStart)
+	s.p.RegisterEventHandler(s.onInfernoStart)
+	s.p.RegisterEventHandler(s.onFrameDone)
 }
 
 func (s *parseState) onMatchStart(_ events.MatchStart) {
@@ -133,20 +227,39 @@ func (s *parseState) onMatchStart(_ events.MatchStart) {
 	for _, pt := range s.players {
 		*pt = *newPlayerTracker(pt.steamID, pt.name, pt.team)
 	}
+	s.teamOf = make(map[uint64]int)
+	s.teamSides = [2]Side{SideCT, SideT}
+	s.teamNames = [2]string{}
+	// drop warmup throws so their detonations are not matched in round 1
+	s.roundGrenades = nil
+	s.grenadeIndex = make(map[int]int)
+	s.roundSides = make(map[uint64]Side)
 }
 
 func (s *parseState) onRoundStart(_ events.RoundStart) {
 	s.roundNum++
 	s.roundKills = nil
 	s.recentDeaths = nil
+	s.blinded = make(map[uint64]blindInfo)
 	s.roundBomb = nil
 	s.roundDefuse = nil
+	s.roundGrenades = nil
+	s.grenadeIndex = make(map[int]int)
+	s.roundDamage = nil
+	s.roundFrames = nil
+	s.roundLive = true
+	s.hasSampled = false
 	s.roundHasFirstKill = false
 
 	s.initialAliveCT = make(map[uint64]bool)
 	s.initialAliveT = make(map[uint64]bool)
 	s.aliveCT = make(map[uint64]bool)
 	s.aliveT = make(map[uint64]bool)
+
+	s.roundSides = make(map[uint64]Side)
+	for _, pl := range s.p.GameState().Participants().Playing() {
+		s.markInRound(pl)
+	}
 }
 
 func (s *parseState) onRoundFreezetimeEnd(_ events.RoundFreezetimeEnd) {
@@ -164,7 +277,7 @@ func (s *parseState) onRoundFreezetimeEnd(_ events.RoundFreezetimeEnd) {
 		if pl == nil || pl.SteamID64 == 0 {
 			continue
 		}
-		s.ensurePlayer(pl)
+		s.markInRound(pl)
 		if pl.Team == common.TeamCounterTerrorists && pl.IsAlive() {
 			s.initialAliveCT[pl.SteamID64] = true
 			s.aliveCT[pl.SteamID64] = true
@@ -174,20 +287,13 @@ func (s *parseState) onRoundFreezetimeEnd(_ events.RoundFreezetimeEnd) {
 		}
 	}
 
-	// snapshot team names
-	ct := gs.TeamCounterTerrorists()
-	t := gs.TeamTerrorists()
-	if ct != nil {
-		s.ctName = ct.ClanName()
-	}
-	if t != nil {
-		s.tName = t.ClanName()
-	}
+	s.updateTeams()
 
 	// snapshot economy at freeze time end for CS:GO demos.
 	// CS2 demos do not fire this event, so economy is captured at round end.
-	s.ctEconomy = snapshotTeamEconomy(ct, s.roundNum)
-	s.tEconomy = snapshotTeamEconomy(t, s.roundNum)
+	s.ctEconomy = snapshotTeamEconomy(gs.TeamCounterTerrorists(), s.roundNum)
+	s.tEconomy = snapshotTeamEconomy(gs.TeamTerrorists(), s.roundNum)
+	s.playerEcon = snapshotPlayerEconomy(gs.Participants().Playing())
 }
 
 func (s *parseState) onKill(e events.Kill) {
@@ -213,18 +319,18 @@ func (s *parseState) onKill(e events.Kill) {
 		attackerID = e.Killer.SteamID64
 		attackerName = e.Killer.Name
 		attackerPos = vecToPosition(e.Killer.Position())
-		s.ensurePlayer(e.Killer)
+		s.markInRound(e.Killer)
 	}
 	if e.Victim != nil {
 		victimID = e.Victim.SteamID64
 		victimName = e.Victim.Name
 		victimPos = vecToPosition(e.Victim.Position())
-		s.ensurePlayer(e.Victim)
+		s.markInRound(e.Victim)
 	}
 	if e.Assister != nil {
 		assisterID = e.Assister.SteamID64
 		assisterName = e.Assister.Name
-		s.ensurePlayer(e.Assister)
+		s.markInRound(e.Assister)
 	}
 
 	if e.Weapon != nil {
@@ -271,6 +377,18 @@ func (s *parseState) onKill(e events.Kill) {
 		}
 	}
 
+	// flash-kill attribution: the victim was recently blinded by a
+	// teammate of the killer (or the killer themselves)
+	if e.Killer != nil && e.Victim != nil {
+		if bi, ok := s.blinded[victimID]; ok &&
+			bi.round == s.roundNum &&
+			killTime-bi.time <= flashKillWindow {
+			if flasher := s.players[bi.flasherSteamID]; flasher != nil && bi.flasherTeam == e.Killer.Team {
+				flasher.recordFlashKill(bi.flashID)
+			}
+		}
+	}
+
 	s.roundKills = append(s.roundKills, kill)
 
 	// update player stats
@@ -329,21 +447,10 @@ func (s *parseState) onPlayerHurt(e events.PlayerHurt) {
 	if s.roundNum == 0 {
 		return
 	}
-	if e.Attacker == nil || e.Player == nil {
-		return
-	}
-	if e.Attacker.SteamID64 == 0 {
+	if e.Player == nil {
 		return
 	}
 
-	// only count damage between enemies
-	if e.Attacker.Team == e.Player.Team {
-		return
-	}
-
-	s.ensurePlayer(e.Attacker)
-	s.hasHurtEvents = true
-
 	// prefer HealthDamageTaken (excludes overkill), fall back to HealthDamage
 	dmg := e.HealthDamageTaken
 	if dmg <= 0 {
@@ -356,8 +463,28 @@ func (s *parseState) onPlayerHurt(e events.PlayerHurt) {
 		}
 	}
 
+	// the damage log keeps every hit, including team and world damage
+	s.roundDamage = append(s.roundDamage, s.damageEventFromHurt(e, dmg))
+	s.markInRound(e.Player)
+	s.markInRound(e.Attacker)
+
+	if e.Attacker == nil {
+		return
+	}
+	if e.Attacker.SteamID64 == 0 {
+		return
+	}
+
+	// only count damage between enemies
+	if e.Attacker.Team == e.Player.Team {
+		return
+	}
+
+	s.ensurePlayer(e.Attacker)
+	s.hasHurtEvents = true
+
 	if pt := s.players[e.Attacker.SteamID64]; pt != nil {
-		pt.recordDamage(dmg)
+		pt.recordDamage(s.roundNum, dmg)
 
 		// track utility damage (grenades)
 		if e.Weapon != nil && e.Weapon.Class() == common.EqClassGrenade {
@@ -366,6 +493,49 @@ func (s *parseState) onPlayerHurt(e events.PlayerHurt) {
 	}
 }
 
+func (s *parseState) onPlayerFlashed(e events.PlayerFlashed) {
+	if s.roundNum == 0 {
+		return
+	}
+	if e.Attacker == nil || e.Player == nil {
+		return
+	}
+	if !e.Player.IsAlive() {
+		return // dead players still get flashed while spectating
+	}
+	if e.Attacker.SteamID64 == 0 || e.Attacker.SteamID64 == e.Player.SteamID64 {
+		return // self-flashes are neither team flashes nor enemy flashes
+	}
+
+	s.ensurePlayer(e.Attacker)
+	pt := s.players[e.Attacker.SteamID64]
+	if pt == nil {
+		return
+	}
+
+	if e.Attacker.Team == e.Player.Team {
+		pt.recordTeammateFlashed()
+		return
+	}
+
+	pt.recordEnemyFlashed(e.FlashDuration())
+
+	var flashID int64
+	if e.Projectile != nil {
+		flashID = e.Projectile.UniqueID()
+	} else {
+		s.nextFlashID--
+		flashID = s.nextFlashID
+	}
+	s.blinded[e.Player.SteamID64] = blindInfo{
+		flasherSteamID: e.Attacker.SteamID64,
+		flasherTeam:    e.Attacker.Team,
+		flashID:        flashID,
+		time:           s.p.CurrentTime(),
+		round:          s.roundNum,
+	}
+}
+
 func (s *parseState) onBombPlanted(e events.BombPlanted) {
 	if s.roundNum == 0 {
 		return
@@ -401,6 +571,7 @@ func (s *parseState) onRoundEnd(e events.RoundEnd) {
 		return
 	}
 
+	s.roundLive = false
 	duration := s.p.CurrentTime() - s.roundStart
 
 	// mark surviving players for KAST
@@ -422,8 +593,13 @@ func (s *parseState) onRoundEnd(e events.RoundEnd) {
 		t := gs.TeamTerrorists()
 		s.ctEconomy = snapshotTeamEconomy(ct, s.roundNum)
 		s.tEconomy = snapshotTeamEconomy(t, s.roundNum)
+		s.playerEcon = snapshotPlayerEconomy(gs.Participants().Playing())
 	}
 
+	// sides are still those played this round; any halftime swap happens
+	// after RoundEnd
+	s.updateTeams()
+
 	var firstKill *KillEvent
 	if len(s.roundKills) > 0 {
 		fk := s.roundKills[0]
@@ -431,6 +607,17 @@ func (s *parseState) onRoundEnd(e events.RoundEnd) {
 	}
 
 	clutch := detectClutch(s.roundKills, s.initialAliveCT, s.initialAliveT)
+	if clutch != nil && clutch.Success {
+		if pt := s.players[clutch.PlayerSteamID]; pt != nil {
+			pt.recordClutchWin(clutch.Opponents)
+		}
+	}
+
+	// CS2 demos don't fire PlayerHurt events. Read cumulative damage
+	// from entity properties and compute the per-round delta.
+	if !s.hasHurtEvents {
+		s.collectEntityDamage()
+	}
 
 	round := Round{
 		Number:     s.roundNum,
@@ -445,20 +632,21 @@ func (s *parseState) onRoundEnd(e events.RoundEnd) {
 		Duration:   duration,
 		BombPlant:  s.roundBomb,
 		BombDefuse: s.roundDefuse,
+		Grenades:   s.roundGrenades,
+		Damage:     s.roundDamage,
+		Frames:     s.roundFrames,
+		Players:    s.playerRounds(),
+		TeamSides:  s.teamSides,
 	}
 
 	s.rounds = append(s.rounds, round)
-
-	// CS2 demos don't fire PlayerHurt events. Read cumulative damage
-	// from entity properties and compute the per-round delta.
-	if !s.hasHurtEvents {
-		s.collectEntityDamage()
-	}
 }
 
 // collectEntityDamage reads m_pActionTrackingServices.m_iDamage from
 // player entities. The value is cumulative, so we track the previous
-// reading and record the delta as the round damage.
+// reading and record the delta as the round damage. Each delta is also
+// logged as an attacker-only DamageEvent marked Aggregate, since neither
+// the victims nor the individual hits are known.
 func (s *parseState) collectEntityDamage() {
 	gs := s.p.GameState()
 	for _, pl := range gs.Participants().Playing() {
@@ -480,12 +668,48 @@ func (s *parseState) collectEntityDamage() {
 		if delta > 0 {
 			s.ensurePlayer(pl)
 			if pt := s.players[pl.SteamID64]; pt != nil {
-				pt.recordDamage(delta)
+				pt.recordDamage(s.roundNum, delta)
 			}
+			s.roundDamage = append(s.roundDamage, DamageEvent{
+				Tick:             gs.IngameTick(),
+				RoundNumber:      s.roundNum,
+				AttackerSteamID:  pl.SteamID64,
+				AttackerName:     pl.Name,
+				AttackerPosition: vecToPosition(pl.Position()),
+				HealthDamage:     delta,
+				Aggregate:        true,
+			})
 		}
 	}
 }
 
+// playerRounds collects the contribution of every player who took part in
+// the current round, including those who left before it ended, ordered by
+// steam ID. Call it after survivors and damage have been recorded.
+func (s *parseState) playerRounds() []PlayerRound {
+	for _, pl := range s.p.GameState().Participants().Playing() {
+		s.markInRound(pl)
+	}
+
+	var out []PlayerRound
+	for _, id := range slices.Sorted(maps.Keys(s.roundSides)) {
+		out = append(out, s.players[id].roundStats(s.roundNum, s.roundSides[id], s.playerEcon[id]))
+	}
+	return out
+}
+
+// markInRound records that a player on a team took part in the current
+// round, on the side they are playing now.
+func (s *parseState) markInRound(pl *common.Player) {
+	if pl == nil || pl.SteamID64 == 0 {
+		return
+	}
+	s.ensurePlayer(pl)
+	if side, ok := teamSide(pl.Team); ok {
+		s.roundSides[pl.SteamID64] = side
+	}
+}
+
 func (s *parseState) buildMatch() *Match {
 	totalRounds := len(s.rounds)
 
@@ -493,33 +717,41 @@ func (s *parseState) buildMatch() *Match {
 	ct := gs.TeamCounterTerrorists()
 	t := gs.TeamTerrorists()
 
-	ctScore := 0
-	tScore := 0
+	// the game's team scores follow sides, so credit them to whichever
+	// team is on that side at the end of the demo
+	var scores [2]int
 	if ct != nil {
-		ctScore = ct.Score()
+		scores[teamOnSide(s.teamSides, SideCT)] = ct.Score()
 	}
 	if t != nil {
-		tScore = t.Score()
+		scores[teamOnSide(s.teamSides, SideT)] = t.Score()
 	}
 
-	// CS2 matchmaking demos leave ClanName empty. Fall back to side labels.
-	ctName := s.ctName
-	tName := s.tName
-	if ctName == "" {
-		ctName = "Counter-Terrorists"
-	}
-	if tName == "" {
-		tName = "Terrorists"
+	startSides := s.teamSides
+	if len(s.rounds) > 0 {
+		startSides = s.rounds[0].TeamSides
 	}
 
-	// collect steam IDs per team
-	var ctPlayers, tPlayers []uint64
-	for _, pt := range s.players {
-		switch pt.team {
-		case "CT":
-			ctPlayers = append(ctPlayers, pt.steamID)
-		case "T":
-			tPlayers = append(tPlayers, pt.steamID)
+	// CS2 matchmaking demos leave ClanName empty. Fall back to the label
+	// of the side each team started on.
+	var teams [2]Team
+	for i := range teams {
+		name := s.teamNames[i]
+		if name == "" {
+			name = sideLabel(startSides[i])
+		}
+		teams[i] = Team{
+			Name:       name,
+			ClanName:   s.teamNames[i],
+			Score:      scores[i],
+			StartedAs:  startSides[i],
+			RoundsWon:  scores[i],
+			RoundsLost: scores[1-i],
+		}
+	}
+	for steamID, idx := range s.teamOf {
+		if _, ok := s.players[steamID]; ok {
+			teams[idx].Players = append(teams[idx].Players, steamID)
 		}
 	}
 
@@ -527,29 +759,18 @@ func (s *parseState) buildMatch() *Match {
 		Map:      s.match.Map,
 		Date:     time.Now(),
 		Duration: s.match.Duration,
-		Teams: [2]Team{
-			{
-				Name:       ctName,
-				Score:      ctScore,
-				StartedAs:  SideCT,
-				Players:    ctPlayers,
-				RoundsWon:  ctScore,
-				RoundsLost: tScore,
-			},
-			{
-				Name:       tName,
-				Score:      tScore,
-				StartedAs:  SideT,
-				Players:    tPlayers,
-				RoundsWon:  tScore,
-				RoundsLost: ctScore,
-			},
-		},
-		Rounds: s.rounds,
+		Teams:    teams,
+		Rounds:   s.rounds,
 	}
 
 	for _, pt := range s.players {
-		match.Players = append(match.Players, pt.finalize(totalRounds))
+		p := pt.finalize(totalRounds)
+		// report the starting side of the player's team, so players who
+		// joined after halftime are grouped with their teammates
+		if idx, ok := s.teamOf[pt.steamID]; ok {
+			p.Team = startSides[idx].String()
+		}
+		match.Players = append(match.Players, p)
 	}
 
 	return match
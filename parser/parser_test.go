@@ -1,8 +1,19 @@
 package parser
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
 	"math"
 	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	common "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
 )
 
 func TestCalculateADR(t *testing.T) {
@@ -112,71 +123,68 @@ func TestClassifyBuyType(t *testing.T) {
 
 func TestCalculateRating(t *testing.T) {
 	tests := []struct {
-		name         string
-		kills        int
-		deaths       int
-		assists      int
-		roundsPlayed int
-		survived     int
-		kastPct      float64
-		adr          float64
-		wantMin      float64 // rating should be within this range
-		wantMax      float64
+		name    string
+		in      RatingInput
+		wantMin float64 // rating should be within this range
+		wantMax float64
 	}{
 		{
-			name:         "zero rounds",
-			kills:        0,
-			deaths:       0,
-			assists:      0,
-			roundsPlayed: 0,
-			survived:     0,
-			kastPct:      0,
-			adr:          0,
-			wantMin:      0,
-			wantMax:      0,
+			name:    "zero rounds",
+			in:      RatingInput{},
+			wantMin: 0,
+			wantMax: 0,
 		},
 		{
-			name:         "average player",
-			kills:        20,
-			deaths:       18,
-			assists:      5,
-			roundsPlayed: 30,
-			survived:     12,
-			kastPct:      70,
-			adr:          75,
-			wantMin:      0.8,
-			wantMax:      1.3,
+			name: "average player",
+			in: RatingInput{
+				Kills:        20,
+				Deaths:       18,
+				RoundsPlayed: 30,
+				KAST:         70,
+				ADR:          75,
+				KillRounds:   map[int]int{0: 14, 1: 12, 2: 4},
+				FirstKills:   3,
+				FirstDeaths:  3,
+			},
+			wantMin: 0.8,
+			wantMax: 1.3,
 		},
 		{
-			name:         "star player",
-			kills:        30,
-			deaths:       10,
-			assists:      8,
-			roundsPlayed: 25,
-			survived:     15,
-			kastPct:      90,
-			adr:          110,
-			wantMin:      1.3,
-			wantMax:      2.5,
+			name: "star player",
+			in: RatingInput{
+				Kills:                 30,
+				Deaths:                10,
+				RoundsPlayed:          25,
+				KAST:                  90,
+				ADR:                   110,
+				KillRounds:            map[int]int{0: 8, 1: 8, 2: 5, 3: 3, 4: 1},
+				FirstKills:            6,
+				FirstDeaths:           2,
+				ClutchOpponentsBeaten: 2,
+			},
+			wantMin: 1.3,
+			wantMax: 2.5,
 		},
 		{
-			name:         "struggling player",
-			kills:        8,
-			deaths:       22,
-			assists:      3,
-			roundsPlayed: 30,
-			survived:     8,
-			kastPct:      40,
-			adr:          40,
-			wantMin:      0.0,
-			wantMax:      0.8,
+			name: "struggling player",
+			in: RatingInput{
+				Kills:        8,
+				Deaths:       22,
+				RoundsPlayed: 30,
+				KAST:         40,
+				ADR:          40,
+				KillRounds:   map[int]int{0: 23, 1: 6, 2: 1},
+				FirstKills:   1,
+				FirstDeaths:  5,
+			},
+			wantMin: 0.0,
+			wantMax: 0.8,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := CalculateRating(tt.kills, tt.deaths, tt.assists,
-				tt.roundsPlayed, tt.survived, tt.kastPct, tt.adr)
+			got := CalculateRating(tt.in)
 
 			if got < tt.wantMin || got > tt.wantMax {
 				t.Errorf("CalculateRating() = %f, want between %f and %f",
@@ -186,34 +194,145 @@ func TestCalculateRating(t *testing.T) {
 	}
 }
 
+// TestCalculateRatingWorked pins the rating regression on a hand-worked
+// line. No published HLTV player line could be checked while writing it,
+// and Impact is only an estimate, so it guards the arithmetic rather than
+// agreement with HLTV.
+func TestCalculateRatingWorked(t *testing.T) {
+	in := RatingInput{
+		Kills:                 24,
+		Deaths:                18,
+		RoundsPlayed:          30,
+		KAST:                  75,
+		ADR:                   85,
+		KillRounds:            map[int]int{0: 12, 1: 12, 2: 6},
+		FirstKills:            4,
+		FirstDeaths:           2,
+		ClutchOpponentsBeaten: 1,
+	}
+
+	// multi-kill 36/30/1.277, opening 3/30/0.05 and clutch 1/30/0.011
+	// give Impact 0.6*0.9397 + 0.25*2 + 0.15*3.0303 = 1.5184
+	if got := EstimateImpact(in); math.Abs(got-1.5184) > 0.0001 {
+		t.Errorf("EstimateImpact() = %f, want 1.5184", got)
+	}
+	// 0.0073*75 + 0.3591*0.8 - 0.5329*0.6 + 0.2372*1.5184 + 0.0032*85 + 0.1587
+	if got := CalculateRating(in); math.Abs(got-1.3059) > 0.0001 {
+		t.Errorf("CalculateRating() = %f, want 1.3059", got)
+	}
+}
+
+func TestEstimateImpact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   RatingInput
+		want float64
+	}{
+		{
+			name: "zero rounds",
+			in:   RatingInput{},
+			want: 0,
+		},
+		{
+			name: "no impact",
+			in: RatingInput{
+				RoundsPlayed: 10,
+				KillRounds:   map[int]int{0: 10},
+			},
+			want: 0,
+		},
+		{
+			// 10 single kills over 10 rounds: 0.6 * 1/1.277
+			name: "single kills only",
+			in: RatingInput{
+				RoundsPlayed: 10,
+				KillRounds:   map[int]int{1: 10},
+			},
+			want: 0.6 / 1.277,
+		},
+		{
+			// one 3k in 10 rounds: 0.6 * (9/10)/1.277
+			name: "multi-kill weighted by square",
+			in: RatingInput{
+				RoundsPlayed: 10,
+				KillRounds:   map[int]int{0: 9, 3: 1},
+			},
+			want: 0.6 * 0.9 / 1.277,
+		},
+		{
+			// (2 - 0.5*2)/20/0.05 = 1.0 opening, weighted 0.25
+			name: "opening duels",
+			in: RatingInput{
+				RoundsPlayed: 20,
+				FirstKills:   2,
+				FirstDeaths:  2,
+			},
+			want: 0.25,
+		},
+		{
+			// 1v2 won in 20 rounds: 2/20/0.011, weighted 0.15
+			name: "clutch",
+			in: RatingInput{
+				RoundsPlayed:          20,
+				ClutchOpponentsBeaten: 2,
+			},
+			want: 0.15 * 0.1 / 0.011,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateImpact(tt.in)
+			if math.Abs(got-tt.want) > 0.0001 {
+				t.Errorf("EstimateImpact() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateKPRDPR(t *testing.T) {
+	if got := CalculateKPR(15, 20); got != 0.75 {
+		t.Errorf("CalculateKPR(15, 20) = %f, want 0.75", got)
+	}
+	if got := CalculateDPR(10, 20); got != 0.5 {
+		t.Errorf("CalculateDPR(10, 20) = %f, want 0.5", got)
+	}
+	if got := CalculateKPR(5, 0); got != 0 {
+		t.Errorf("CalculateKPR(5, 0) = %f, want 0", got)
+	}
+	if got := CalculateDPR(5, 0); got != 0 {
+		t.Errorf("CalculateDPR(5, 0) = %f, want 0", got)
+	}
+}
+
 func TestPlayerTrackerFinalize(t *testing.T) {
 	pt := newPlayerTracker(12345, "TestPlayer", "CT")
 
 	// simulate a 5-round match
 	// round 1: kill + survived
 	pt.recordKill(1, true)
-	pt.recordDamage(100)
+	pt.recordDamage(1, 100)
 	pt.markSurvived(1)
 
 	// round 2: death, no contribution
 	pt.recordDeath(2)
-	pt.recordDamage(30)
+	pt.recordDamage(2, 30)
 
 	// round 3: assist + survived
 	pt.recordAssist(3)
-	pt.recordDamage(50)
+	pt.recordDamage(3, 50)
 	pt.markSurvived(3)
 
 	// round 4: kill + death (traded)
 	pt.recordKill(4, false)
 	pt.recordDeath(4)
-	pt.recordDamage(100)
+	pt.recordDamage(4, 100)
 	pt.markTraded(4)
 
 	// round 5: kill + headshot + survived
 	pt.recordKill(5, true)
 	pt.recordKill(5, false)
-	pt.recordDamage(200)
+	pt.recordDamage(5, 200)
 	pt.markSurvived(5)
 
 	player := pt.finalize(5)
@@ -257,32 +376,82 @@ func TestPlayerTrackerFinalize(t *testing.T) {
 		t.Errorf("multi-kills[2] = %d, want 1", player.Stats.MultiKills[2])
 	}
 
+	// KPR: 4/5, DPR: 2/5
+	if math.Abs(player.Stats.KPR-0.8) > 0.001 {
+		t.Errorf("KPR = %f, want 0.8", player.Stats.KPR)
+	}
+	if math.Abs(player.Stats.DPR-0.4) > 0.001 {
+		t.Errorf("DPR = %f, want 0.4", player.Stats.DPR)
+	}
+
+	// impact from kill rounds 1, 1, 2: (1+1+4)/5/1.277 weighted 0.6
+	wantImpact := 0.6 * 6.0 / 5.0 / 1.277
+	if math.Abs(player.Stats.Impact-wantImpact) > 0.001 {
+		t.Errorf("Impact = %f, want %f", player.Stats.Impact, wantImpact)
+	}
+
 	// rating should be positive for a decent performance
 	if player.Stats.Rating <= 0 {
 		t.Errorf("rating = %f, want > 0", player.Stats.Rating)
 	}
 }
 
+func TestPlayerTrackerRoundStats(t *testing.T) {
+	pt := newPlayerTracker(12345, "TestPlayer", "CT")
+
+	// round 1: two kills, an assist and survived
+	pt.recordKill(1, true)
+	pt.recordKill(1, false)
+	pt.recordAssist(1)
+	pt.recordDamage(1, 150)
+	pt.recordDamage(1, 40)
+	pt.markSurvived(1)
+
+	// round 2: died and was traded
+	pt.recordDeath(2)
+	pt.recordDamage(2, 20)
+	pt.markTraded(2)
+
+	// round 3: died without contributing
+	pt.recordDeath(3)
+
+	econ := playerEconomy{equipmentValue: 4700, moneySpent: 3900}
+	tests := []struct {
+		round int
+		want  PlayerRound
+	}{
+		{1, PlayerRound{SteamID: 12345, Side: SideCT, Kills: 2, Assists: 1, Damage: 190, Survived: true, KAST: true, EquipmentValue: 4700, MoneySpent: 3900}},
+		{2, PlayerRound{SteamID: 12345, Side: SideCT, Deaths: 1, Damage: 20, Traded: true, KAST: true, EquipmentValue: 4700, MoneySpent: 3900}},
+		{3, PlayerRound{SteamID: 12345, Side: SideCT, Deaths: 1, EquipmentValue: 4700, MoneySpent: 3900}},
+	}
+	for _, tt := range tests {
+		got := pt.roundStats(tt.round, SideCT, econ)
+		if got != tt.want {
+			t.Errorf("round %d: got %+v, want %+v", tt.round, got, tt.want)
+		}
+	}
+}
+
 func TestPlayerTrackerMultiKills(t *testing.T) {
 	pt := newPlayerTracker(99999, "AcePlayer", "T")
 
 	// round with 5 kills (ace)
 	for i := 0; i < 5; i++ {
 		pt.recordKill(1, i%2 == 0) // alternate headshots
-		pt.recordDamage(100)
+		pt.recordDamage(1, 100)
 	}
 	pt.markSurvived(1)
 
 	// round with 3 kills
 	for i := 0; i < 3; i++ {
 		pt.recordKill(2, false)
-		pt.recordDamage(80)
+		pt.recordDamage(2, 80)
 	}
 	pt.markSurvived(2)
 
 	// round with 1 kill (not a multi-kill)
 	pt.recordKill(3, true)
-	pt.recordDamage(100)
+	pt.recordDamage(3, 100)
 	pt.markSurvived(3)
 
 	player := pt.finalize(3)
@@ -441,10 +610,82 @@ func TestSideString(t *testing.T) {
 	}
 }
 
+func TestGrenadeTypeString(t *testing.T) {
+	tests := []struct {
+		gt   GrenadeType
+		want string
+	}{
+		{GrenadeTypeSmoke, "Smoke"},
+		{GrenadeTypeFlash, "Flash"},
+		{GrenadeTypeHE, "HE"},
+		{GrenadeTypeMolotov, "Molotov"},
+		{GrenadeTypeIncendiary, "Incendiary"},
+		{GrenadeTypeDecoy, "Decoy"},
+		{GrenadeTypeUnknown, "Unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.gt.String(); got != tt.want {
+				t.Errorf("GrenadeType.String() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapGrenadeType(t *testing.T) {
+	tests := []struct {
+		eq   common.EquipmentType
+		want GrenadeType
+	}{
+		{common.EqSmoke, GrenadeTypeSmoke},
+		{common.EqFlash, GrenadeTypeFlash},
+		{common.EqHE, GrenadeTypeHE},
+		{common.EqMolotov, GrenadeTypeMolotov},
+		{common.EqIncendiary, GrenadeTypeIncendiary},
+		{common.EqDecoy, GrenadeTypeDecoy},
+		{common.EqAK47, GrenadeTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eq.String(), func(t *testing.T) {
+			if got := mapGrenadeType(tt.eq); got != tt.want {
+				t.Errorf("mapGrenadeType(%v) = %v, want %v", tt.eq, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapHitGroup(t *testing.T) {
+	tests := []struct {
+		hg   events.HitGroup
+		want string
+	}{
+		{events.HitGroupHead, "Head"},
+		{events.HitGroupChest, "Chest"},
+		{events.HitGroupRightLeg, "RightLeg"},
+		{events.HitGroupGear, "Gear"},
+		{events.HitGroupGeneric, "Generic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := mapHitGroup(tt.hg); got != tt.want {
+				t.Errorf("mapHitGroup(%d) = %s, want %s", tt.hg, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRatingFormulaDeterministic(t *testing.T) {
 	// same inputs should always produce same output
-	r1 := CalculateRating(20, 15, 5, 30, 15, 70, 80)
-	r2 := CalculateRating(20, 15, 5, 30, 15, 70, 80)
+	in := RatingInput{
+		Kills: 20, Deaths: 15, RoundsPlayed: 30, KAST: 70, ADR: 80,
+		KillRounds: map[int]int{0: 15, 1: 10, 2: 5},
+		FirstKills: 4, FirstDeaths: 3, ClutchOpponentsBeaten: 1,
+	}
+	r1 := CalculateRating(in)
+	r2 := CalculateRating(in)
 	if r1 != r2 {
 		t.Errorf("rating not deterministic: %f != %f", r1, r2)
 	}
@@ -452,9 +693,21 @@ func TestRatingFormulaDeterministic(t *testing.T) {
 
 func TestRatingRelativeOrdering(t *testing.T) {
 	// a player with better stats should have a higher rating
-	good := CalculateRating(25, 10, 8, 25, 15, 85, 100)
-	avg := CalculateRating(15, 15, 5, 25, 10, 65, 70)
-	bad := CalculateRating(5, 20, 2, 25, 5, 30, 30)
+	good := CalculateRating(RatingInput{
+		Kills: 25, Deaths: 10, RoundsPlayed: 25, KAST: 85, ADR: 100,
+		KillRounds: map[int]int{0: 7, 1: 11, 2: 4, 3: 2},
+		FirstKills: 5, FirstDeaths: 2, ClutchOpponentsBeaten: 1,
+	})
+	avg := CalculateRating(RatingInput{
+		Kills: 15, Deaths: 15, RoundsPlayed: 25, KAST: 65, ADR: 70,
+		KillRounds: map[int]int{0: 12, 1: 11, 2: 2},
+		FirstKills: 2, FirstDeaths: 2,
+	})
+	bad := CalculateRating(RatingInput{
+		Kills: 5, Deaths: 20, RoundsPlayed: 25, KAST: 30, ADR: 30,
+		KillRounds: map[int]int{0: 20, 1: 5},
+		FirstKills: 0, FirstDeaths: 4,
+	})
 
 	if good <= avg {
 		t.Errorf("good rating (%f) should be > avg rating (%f)", good, avg)
@@ -464,6 +717,65 @@ func TestRatingRelativeOrdering(t *testing.T) {
 	}
 }
 
+func TestAssignTeams(t *testing.T) {
+	teamOf := make(map[uint64]int)
+	sides := [2]Side{SideCT, SideT}
+
+	// first half: players 1-2 on CT, 3-4 on T
+	sides = assignTeams(teamOf, sides, map[uint64]Side{1: SideCT, 2: SideCT, 3: SideT, 4: SideT})
+	if sides != [2]Side{SideCT, SideT} {
+		t.Fatalf("first half sides = %v, want [CT T]", sides)
+	}
+	if teamOf[1] != 0 || teamOf[2] != 0 || teamOf[3] != 1 || teamOf[4] != 1 {
+		t.Fatalf("first half teams = %v", teamOf)
+	}
+
+	// halftime: everyone swaps, and player 5 replaces player 2
+	sides = assignTeams(teamOf, sides, map[uint64]Side{1: SideT, 5: SideT, 3: SideCT, 4: SideCT})
+	if sides != [2]Side{SideT, SideCT} {
+		t.Fatalf("second half sides = %v, want [T CT]", sides)
+	}
+	if teamOf[5] != 0 {
+		t.Errorf("substitute joined team %d, want 0", teamOf[5])
+	}
+
+	// a single player on the wrong side doesn't flip the teams
+	sides = assignTeams(teamOf, sides, map[uint64]Side{1: SideT, 5: SideT, 3: SideCT, 4: SideT})
+	if sides != [2]Side{SideT, SideCT} {
+		t.Errorf("sides after one mismatch = %v, want [T CT]", sides)
+	}
+
+	// overtime swap
+	sides = assignTeams(teamOf, sides, map[uint64]Side{1: SideCT, 5: SideCT, 3: SideT, 4: SideT})
+	if sides != [2]Side{SideCT, SideT} {
+		t.Errorf("overtime sides = %v, want [CT T]", sides)
+	}
+}
+
+func TestRoundWinnerTeam(t *testing.T) {
+	tests := []struct {
+		name   string
+		sides  [2]Side
+		winner Side
+		want   int
+	}{
+		{"team 0 on CT wins as CT", [2]Side{SideCT, SideT}, SideCT, 0},
+		{"team 1 on T wins as T", [2]Side{SideCT, SideT}, SideT, 1},
+		{"team 0 on T wins as T", [2]Side{SideT, SideCT}, SideT, 0},
+		{"team 1 on CT wins as CT", [2]Side{SideT, SideCT}, SideCT, 1},
+		{"sides not recorded", [2]Side{}, SideT, -1},
+		{"unknown winning side", [2]Side{SideCT, SideT}, Side(-1), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Round{Winner: tt.winner, TeamSides: tt.sides}
+			if got := r.WinnerTeam(); got != tt.want {
+				t.Errorf("WinnerTeam() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsPistolRound(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -499,7 +811,7 @@ func TestPlayerTrackerTradeKills(t *testing.T) {
 	pt.recordKill(1, false)
 	pt.recordTradeKill(1)
 	pt.markSurvived(1)
-	pt.recordDamage(100)
+	pt.recordDamage(1, 100)
 
 	player := pt.finalize(1)
 	if player.Stats.TradeKills != 1 {
@@ -520,6 +832,37 @@ func TestPlayerTrackerFlashAssists(t *testing.T) {
 	}
 }
 
+func TestPlayerTrackerFlashEffectiveness(t *testing.T) {
+	pt := newPlayerTracker(1, "Support", "CT")
+
+	pt.recordEnemyFlashed(2 * time.Second)
+	pt.recordEnemyFlashed(3 * time.Second)
+	pt.recordEnemyFlashed(1 * time.Second)
+	pt.recordTeammateFlashed()
+
+	// one flash blinded two enemies that were both killed, another led to one kill
+	pt.recordFlashKill(100)
+	pt.recordFlashKill(100)
+	pt.recordFlashKill(101)
+
+	player := pt.finalize(1)
+	if player.Stats.EnemiesFlashed != 3 {
+		t.Errorf("enemies flashed = %d, want 3", player.Stats.EnemiesFlashed)
+	}
+	if player.Stats.TeammatesFlashed != 1 {
+		t.Errorf("teammates flashed = %d, want 1", player.Stats.TeammatesFlashed)
+	}
+	if math.Abs(player.Stats.EnemyBlindDuration-6.0) > 0.001 {
+		t.Errorf("enemy blind duration = %f, want 6.0", player.Stats.EnemyBlindDuration)
+	}
+	if math.Abs(player.Stats.AvgEnemyBlindDuration-2.0) > 0.001 {
+		t.Errorf("avg enemy blind duration = %f, want 2.0", player.Stats.AvgEnemyBlindDuration)
+	}
+	if player.Stats.FlashesLeadingToKill != 2 {
+		t.Errorf("flashes leading to kill = %d, want 2", player.Stats.FlashesLeadingToKill)
+	}
+}
+
 func TestPlayerTrackerFirstKillsDeath(t *testing.T) {
 	pt := newPlayerTracker(1, "EntryFragger", "T")
 
@@ -538,3 +881,202 @@ func TestPlayerTrackerFirstKillsDeath(t *testing.T) {
 		t.Errorf("first deaths = %d, want 1", player.Stats.FirstDeaths)
 	}
 }
+
+func TestProgressPercent(t *testing.T) {
+	tests := []struct {
+		name string
+		read int64
+		size int64
+		want float64
+	}{
+		{"unknown size", 500, 0, 0},
+		{"nothing read", 0, 1000, 0},
+		{"half", 500, 1000, 50},
+		{"complete", 1000, 1000, 100},
+		{"read ahead past size", 1200, 1000, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := progressPercent(tt.read, tt.size); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("progressPercent(%d, %d) = %f, want %f", tt.read, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputSize(t *testing.T) {
+	r := bytes.NewReader(make([]byte, 100))
+	if got := inputSize(r); got != 100 {
+		t.Errorf("inputSize(bytes.Reader) = %d, want 100", got)
+	}
+
+	if _, err := r.Seek(40, io.SeekStart); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	if got := inputSize(r); got != 60 {
+		t.Errorf("inputSize after seek = %d, want 60", got)
+	}
+
+	if got := inputSize(io.LimitReader(r, 10)); got != 0 {
+		t.Errorf("inputSize(unsized reader) = %d, want 0", got)
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	cr := &countingReader{r: bytes.NewReader(make([]byte, 300))}
+	if _, err := io.Copy(io.Discard, cr); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if cr.n != 300 {
+		t.Errorf("counted %d bytes, want 300", cr.n)
+	}
+}
+
+func TestParseContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ParseContext(ctx, bytes.NewReader([]byte("HL2DEMO")), DefaultOptions())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseContext error = %v, want context.Canceled", err)
+	}
+}
+
+// panicReader panics on the first read, as the demo library does on some
+// corrupt demos.
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) {
+	panic("index out of range")
+}
+
+func TestParseContextPanic(t *testing.T) {
+	m, err := ParseContext(context.Background(), panicReader{}, DefaultOptions())
+	if m != nil || err == nil || err.Error() != "parser panic: index out of range" {
+		t.Errorf("ParseContext = %v, %v, want parser panic error", m, err)
+	}
+}
+
+func TestDemos(t *testing.T) {
+	const demo = "PBDEMS2\x00demo body"
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(demo))
+	gw.Close()
+
+	var zs bytes.Buffer
+	zw, err := zstd.NewWriter(&zs)
+	if err != nil {
+		t.Fatalf("zstd writer: %v", err)
+	}
+	zw.Write([]byte(demo))
+	zw.Close()
+
+	var archive bytes.Buffer
+	aw := zip.NewWriter(&archive)
+	for _, f := range []struct{ name, body string }{
+		{"maps/first.dem", demo},
+		{"readme.txt", "not a demo"},
+		{"second.DEM", demo + " 2"},
+	} {
+		w, _ := aw.Create(f.name)
+		w.Write([]byte(f.body))
+	}
+	aw.Close()
+
+	var empty bytes.Buffer
+	zip.NewWriter(&empty).Close()
+
+	type found struct{ name, body string }
+	tests := []struct {
+		name   string
+		input  []byte
+		format Format
+		want   []found
+		err    error
+	}{
+		{"plain", []byte(demo), FormatDemo, []found{{"", demo}}, nil},
+		{"gzip", gz.Bytes(), FormatGzip, []found{{"", demo}}, nil},
+		{"zstd", zs.Bytes(), FormatZstd, []found{{"", demo}}, nil},
+		{"zip", archive.Bytes(), FormatZip, []found{{"maps/first.dem", demo}, {"second.DEM", demo + " 2"}}, nil},
+		{"empty zip", empty.Bytes(), FormatZip, nil, ErrNoDemos},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.input); got != tt.format {
+				t.Errorf("format: got %s, want %s", got, tt.format)
+			}
+
+			// a plain io.Reader forces zip archives through a temp file
+			for _, r := range []io.Reader{bytes.NewReader(tt.input), io.MultiReader(bytes.NewReader(tt.input))} {
+				var got []found
+				err := Demos(r, func(name string, demo io.Reader) error {
+					b, err := io.ReadAll(demo)
+					got = append(got, found{name, string(b)})
+					return err
+				})
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error: got %v, want %v", err, tt.err)
+				}
+				if len(got) != len(tt.want) {
+					t.Fatalf("demos: got %v, want %v", got, tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("demo %d: got %v, want %v", i, got[i], tt.want[i])
+					}
+				}
+			}
+		})
+	}
+
+	// bzip2 has no writer in the standard library; check detection only
+	if got := DetectFormat([]byte("BZh91AY&SY")); got != FormatBzip2 {
+		t.Errorf("bzip2 format: got %s", got)
+	}
+}
+
+func TestDemosTooLarge(t *testing.T) {
+	const demo = "PBDEMS2\x00demo body"
+	limit := int64(len(demo))
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(demo + " and more"))
+	gw.Close()
+
+	var archive bytes.Buffer
+	aw := zip.NewWriter(&archive)
+	w, _ := aw.Create("big.dem")
+	w.Write([]byte(demo + " and more"))
+	aw.Close()
+
+	tests := []struct {
+		name   string
+		input  []byte
+		err    error
+		called bool
+	}{
+		{"exact fit", []byte(demo), nil, true},
+		{"gzip over the limit", gz.Bytes(), ErrDemoTooLarge, true},
+		// the recorded size is checked before the file is opened
+		{"zip over the limit", archive.Bytes(), ErrDemoTooLarge, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			err := demos(bytes.NewReader(tt.input), limit, func(name string, demo io.Reader) error {
+				called = true
+				_, err := io.Copy(io.Discard, demo)
+				return err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error: got %v, want %v", err, tt.err)
+			}
+			if called != tt.called {
+				t.Errorf("fn called: got %v, want %v", called, tt.called)
+			}
+		})
+	}
+}
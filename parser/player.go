@@ -2,12 +2,18 @@ package parser
 
 import (
 	"time"
+
+	common "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
 )
 
 // tradeWindow is the maximum time between a teammate's death and a kill
 // on their killer for it to count as a trade.
 const tradeWindow = 5 * time.Second
 
+// flashKillWindow is the maximum time between an enemy being flashed and
+// their death for the flash to count as leading to the kill.
+const flashKillWindow = 3 * time.Second
+
 // playerTracker accumulates per-player stats across the match.
 type playerTracker struct {
 	steamID uint64
@@ -25,6 +31,15 @@ type playerTracker struct {
 	firstKills    int
 	firstDeaths   int
 
+	// sum of opponents faced in won clutches, for Impact
+	clutchOpponentsBeaten int
+
+	// flashbang effectiveness
+	enemiesFlashed   int
+	teammatesFlashed int
+	enemyBlindTime   time.Duration
+	flashKills       map[int64]bool // flash ID -> led to a kill
+
 	// per-round tracking for KAST
 	roundKill     map[int]bool // round -> had a kill
 	roundAssist   map[int]bool // round -> had an assist
@@ -32,6 +47,10 @@ type playerTracker struct {
 	roundTraded   map[int]bool // round -> was traded (died but teammate traded)
 	roundDeath    map[int]bool // round -> died this round
 
+	// per-round totals for player round rows
+	roundAssists map[int]int // round -> assists
+	roundDamage  map[int]int // round -> damage dealt
+
 	// multi-kill tracking: round -> kill count
 	roundKillCount map[int]int
 
@@ -48,7 +67,10 @@ func newPlayerTracker(steamID uint64, name, team string) *playerTracker {
 		roundSurvived:  make(map[int]bool),
 		roundTraded:    make(map[int]bool),
 		roundDeath:     make(map[int]bool),
+		roundAssists:   make(map[int]int),
+		roundDamage:    make(map[int]int),
 		roundKillCount: make(map[int]int),
+		flashKills:     make(map[int64]bool),
 	}
 }
 
@@ -69,6 +91,7 @@ func (pt *playerTracker) recordDeath(round int) {
 func (pt *playerTracker) recordAssist(round int) {
 	pt.assists++
 	pt.roundAssist[round] = true
+	pt.roundAssists[round]++
 }
 
 func (pt *playerTracker) recordFlashAssist(round int) {
@@ -76,8 +99,9 @@ func (pt *playerTracker) recordFlashAssist(round int) {
 	pt.roundAssist[round] = true
 }
 
-func (pt *playerTracker) recordDamage(damage int) {
+func (pt *playerTracker) recordDamage(round, damage int) {
 	pt.totalDamage += damage
+	pt.roundDamage[round] += damage
 }
 
 func (pt *playerTracker) recordUtilityDamage(damage int) {
@@ -104,6 +128,45 @@ func (pt *playerTracker) recordFirstDeath(round int) {
 	pt.firstDeaths++
 }
 
+func (pt *playerTracker) recordClutchWin(opponents int) {
+	pt.clutchOpponentsBeaten += opponents
+}
+
+func (pt *playerTracker) recordEnemyFlashed(duration time.Duration) {
+	pt.enemiesFlashed++
+	pt.enemyBlindTime += duration
+}
+
+func (pt *playerTracker) recordTeammateFlashed() {
+	pt.teammatesFlashed++
+}
+
+// recordFlashKill marks a flash as having led to a kill. A single flash
+// that blinds several enemies who are then killed only counts once.
+func (pt *playerTracker) recordFlashKill(flashID int64) {
+	pt.flashKills[flashID] = true
+}
+
+// roundStats returns the player's contribution to a single round.
+func (pt *playerTracker) roundStats(round int, side Side, econ playerEconomy) PlayerRound {
+	pr := PlayerRound{
+		SteamID:        pt.steamID,
+		Side:           side,
+		Kills:          pt.roundKillCount[round],
+		Assists:        pt.roundAssists[round],
+		Damage:         pt.roundDamage[round],
+		Survived:       pt.roundSurvived[round],
+		Traded:         pt.roundTraded[round],
+		EquipmentValue: econ.equipmentValue,
+		MoneySpent:     econ.moneySpent,
+	}
+	if pt.roundDeath[round] {
+		pr.Deaths = 1
+	}
+	pr.KAST = pt.roundKill[round] || pt.roundAssist[round] || pr.Survived || pr.Traded
+	return pr
+}
+
 func (pt *playerTracker) finalize(totalRounds int) Player {
 	pt.roundsPlayed = totalRounds
 
@@ -125,15 +188,32 @@ func (pt *playerTracker) finalize(totalRounds int) Player {
 		}
 	}
 
-	rating := CalculateRating(pt.kills, pt.deaths, pt.assists, totalRounds, survived, kastPct, adr)
+	var avgBlind float64
+	if pt.enemiesFlashed > 0 {
+		avgBlind = pt.enemyBlindTime.Seconds() / float64(pt.enemiesFlashed)
+	}
 
 	multiKills := make(map[int]int)
+	killRounds := make(map[int]int)
 	for _, count := range pt.roundKillCount {
+		killRounds[count]++
 		if count >= 2 {
 			multiKills[count]++
 		}
 	}
 
+	ratingIn := RatingInput{
+		Kills:                 pt.kills,
+		Deaths:                pt.deaths,
+		RoundsPlayed:          totalRounds,
+		KAST:                  kastPct,
+		ADR:                   adr,
+		KillRounds:            killRounds,
+		FirstKills:            pt.firstKills,
+		FirstDeaths:           pt.firstDeaths,
+		ClutchOpponentsBeaten: pt.clutchOpponentsBeaten,
+	}
+
 	return Player{
 		SteamID: pt.steamID,
 		Name:    pt.name,
@@ -148,7 +228,10 @@ func (pt *playerTracker) finalize(totalRounds int) Player {
 			FlashAssists:  pt.flashAssists,
 			UtilityDamage: pt.utilityDamage,
 			TradeKills:    pt.tradeKills,
-			Rating:        rating,
+			Rating:        CalculateRating(ratingIn),
+			KPR:           CalculateKPR(pt.kills, totalRounds),
+			DPR:           CalculateDPR(pt.deaths, totalRounds),
+			Impact:        EstimateImpact(ratingIn),
 			TotalDamage:   pt.totalDamage,
 			Headshots:     pt.headshots,
 			RoundsPlayed:  totalRounds,
@@ -156,6 +239,12 @@ func (pt *playerTracker) finalize(totalRounds int) Player {
 			FirstKills:    pt.firstKills,
 			FirstDeaths:   pt.firstDeaths,
 			MultiKills:    multiKills,
+
+			EnemiesFlashed:        pt.enemiesFlashed,
+			TeammatesFlashed:      pt.teammatesFlashed,
+			EnemyBlindDuration:    pt.enemyBlindTime.Seconds(),
+			AvgEnemyBlindDuration: avgBlind,
+			FlashesLeadingToKill:  len(pt.flashKills),
 		},
 	}
 }
@@ -168,3 +257,12 @@ type recentDeath struct {
 	time           time.Duration
 	round          int
 }
+
+// blindInfo records who flashed a player for flash-kill attribution.
+type blindInfo struct {
+	flasherSteamID uint64
+	flasherTeam    common.Team
+	flashID        int64
+	time           time.Duration
+	round          int
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"io"
+	"os"
+)
+
+// progressFrames is how many demo frames are parsed between progress
+// reports. At 64 frames per second this is roughly every 16s of game time.
+const progressFrames = 1024
+
+// Progress describes how far a parse has got.
+type Progress struct {
+	Ticks     int     // in-game ticks parsed so far
+	BytesRead int64   // bytes consumed from the input
+	Percent   float64 // BytesRead as a percentage of the input size, 0 if unknown
+	Round     int     // current round number, 0 before the match starts
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// inputSize returns the number of unread bytes in r, or 0 when it cannot
+// be determined without consuming the reader.
+func inputSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case *os.File:
+		fi, err := v.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0
+		}
+		off, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0
+		}
+		return fi.Size() - off
+	}
+	return 0
+}
+
+// progressPercent returns read as a percentage of size, capped at 100.
+func progressPercent(read, size int64) float64 {
+	if size <= 0 {
+		return 0
+	}
+	pct := float64(read) / float64(size) * 100
+	if pct > 100 {
+		pct = 100
+	}
+	return pct
+}
+
+// reportProgress calls the Progress callback, if any, with the current
+// position of the parse.
+func (s *parseState) reportProgress(cr *countingReader, size int64) {
+	if s.opts.Progress == nil {
+		return
+	}
+	s.opts.Progress(Progress{
+		Ticks:     s.p.GameState().IngameTick(),
+		BytesRead: cr.n,
+		Percent:   progressPercent(cr.n, size),
+		Round:     s.roundNum,
+	})
+}
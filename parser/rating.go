@@ -0,0 +1,119 @@
+package parser
+
+// RatingInput holds the per-match totals that feed EstimateImpact and
+// CalculateRating.
+type RatingInput struct {
+	Kills        int
+	Deaths       int
+	RoundsPlayed int
+	KAST         float64 // percentage, 0-100
+	ADR          float64
+
+	// KillRounds maps a round kill count to the number of rounds with
+	// exactly that many kills. Unlike PlayerStats.MultiKills it includes
+	// single-kill rounds.
+	KillRounds map[int]int
+
+	FirstKills  int
+	FirstDeaths int
+
+	// ClutchOpponentsBeaten sums the opponents faced across won clutches,
+	// so a 1v3 win counts three times as much as a 1v1.
+	ClutchOpponentsBeaten int
+}
+
+// Impact and rating coefficients.
+//
+// HLTV has not published how it computes Impact. The usual community
+// regression, Impact = 2.13*KPR + 0.42*APR - 0.41, is fitted to kills and
+// assists alone, so EstimateImpact is our own approximation built from
+// the events Impact is described as rewarding. Each component is
+// normalised so that an average player scores roughly 1.0:
+//   - multi-kills use the HLTV 1.0 weighting (n² per n-kill round) and
+//     its average of 1.277 per round;
+//   - opening duels count a first kill fully and a first death half, with
+//     an average net of 0.05 per round (one opening duel per round shared
+//     across ten players);
+//   - clutches count opponents beaten, averaging about 0.011 per round.
+//
+// The rating itself uses the community-derived HLTV 2.0 regression
+// weights for KAST, KPR, DPR, Impact and ADR.
+const (
+	multiKillAvg     = 1.277
+	openingAvg       = 0.05
+	openingDeathCost = 0.5
+	clutchAvg        = 0.011
+
+	impactMultiKillWeight = 0.6
+	impactOpeningWeight   = 0.25
+	impactClutchWeight    = 0.15
+
+	ratingKASTWeight   = 0.0073
+	ratingKPRWeight    = 0.3591
+	ratingDPRWeight    = -0.5329
+	ratingImpactWeight = 0.2372
+	ratingADRWeight    = 0.0032
+	ratingIntercept    = 0.1587
+)
+
+// CalculateKPR computes kills per round.
+func CalculateKPR(kills, roundsPlayed int) float64 {
+	if roundsPlayed == 0 {
+		return 0
+	}
+	return float64(kills) / float64(roundsPlayed)
+}
+
+// CalculateDPR computes deaths per round.
+func CalculateDPR(deaths, roundsPlayed int) float64 {
+	if roundsPlayed == 0 {
+		return 0
+	}
+	return float64(deaths) / float64(roundsPlayed)
+}
+
+// EstimateImpact approximates HLTV's unpublished Impact sub-rating from
+// multi-kills, opening duels and clutches. An average player scores about
+// 1.0.
+//
+//	Impact ≈ 0.6*multiKill + 0.25*opening + 0.15*clutch
+//
+// where each component is its per-round value divided by the average
+// described above. It will not reproduce HLTV's figures exactly.
+func EstimateImpact(in RatingInput) float64 {
+	if in.RoundsPlayed == 0 {
+		return 0
+	}
+	rp := float64(in.RoundsPlayed)
+
+	multi := 0.0
+	for n, rounds := range in.KillRounds {
+		if n <= 0 {
+			continue
+		}
+		multi += float64(n*n) * float64(rounds)
+	}
+	multi = multi / rp / multiKillAvg
+
+	opening := (float64(in.FirstKills) - openingDeathCost*float64(in.FirstDeaths)) / rp / openingAvg
+	clutch := float64(in.ClutchOpponentsBeaten) / rp / clutchAvg
+
+	return impactMultiKillWeight*multi + impactOpeningWeight*opening + impactClutchWeight*clutch
+}
+
+// CalculateRating computes an HLTV 2.0-style rating:
+//
+//	Rating = 0.0073*KAST + 0.3591*KPR - 0.5329*DPR + 0.2372*Impact + 0.0032*ADR + 0.1587
+//
+// KAST is a percentage, Impact comes from EstimateImpact.
+func CalculateRating(in RatingInput) float64 {
+	if in.RoundsPlayed == 0 {
+		return 0
+	}
+	return ratingKASTWeight*in.KAST +
+		ratingKPRWeight*CalculateKPR(in.Kills, in.RoundsPlayed) +
+		ratingDPRWeight*CalculateDPR(in.Deaths, in.RoundsPlayed) +
+		ratingImpactWeight*EstimateImpact(in) +
+		ratingADRWeight*in.ADR +
+		ratingIntercept
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"time"
+
+	events "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/events"
+)
+
+// Options configures optional parser behaviour.
+type Options struct {
+	// SampleInterval is the in-game time between player state samples used
+	// for round replays. Zero disables sampling.
+	SampleInterval time.Duration
+
+	// Progress, if set, is called periodically during ParseContext and once
+	// more when parsing completes. It runs on the parsing goroutine.
+	Progress func(Progress)
+
+	// Size is the input size in bytes used for Progress.Percent. When zero
+	// it is detected from the reader where possible.
+	Size int64
+}
+
+// DefaultSampleInterval samples player state at 4 Hz, enough for a smooth
+// 2D replay without bloating storage.
+const DefaultSampleInterval = 250 * time.Millisecond
+
+// DefaultOptions returns the options used by Parse.
+func DefaultOptions() Options {
+	return Options{SampleInterval: DefaultSampleInterval}
+}
+
+// onFrameDone samples living players once every SampleInterval while a
+// round is in progress.
+func (s *parseState) onFrameDone(_ events.FrameDone) {
+	if s.roundNum == 0 || !s.roundLive || s.opts.SampleInterval <= 0 {
+		return
+	}
+
+	now := s.p.CurrentTime()
+	if s.hasSampled && now-s.lastSample < s.opts.SampleInterval {
+		return
+	}
+	s.lastSample = now
+	s.hasSampled = true
+
+	gs := s.p.GameState()
+	frame := ReplayFrame{Tick: gs.IngameTick()}
+	for _, pl := range gs.Participants().Playing() {
+		if pl == nil || pl.SteamID64 == 0 || !pl.IsAlive() {
+			continue
+		}
+		sample := PlayerSample{
+			SteamID:  pl.SteamID64,
+			Position: vecToPosition(pl.Position()),
+			Yaw:      float64(pl.ViewDirectionX()),
+			Pitch:    float64(pl.ViewDirectionY()),
+			Health:   pl.Health(),
+			Armor:    pl.Armor(),
+			Money:    pl.Money(),
+		}
+		if w := pl.ActiveWeapon(); w != nil {
+			sample.Weapon = w.String()
+		}
+		frame.Players = append(frame.Players, sample)
+	}
+	s.roundFrames = append(s.roundFrames, frame)
+}
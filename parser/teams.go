@@ -0,0 +1,93 @@
+package parser
+
+import (
+	common "github.com/markus-wa/demoinfocs-golang/v4/pkg/demoinfocs/common"
+)
+
+// Teams are identified by roster rather than by side, so halftime and
+// overtime swaps don't change which team is Match.Teams[0] and which is
+// Match.Teams[1]. The first team seen on CT becomes team 0.
+
+// teamSide converts a demoinfocs team to a Side. ok is false for
+// spectators and unassigned players.
+func teamSide(team common.Team) (side Side, ok bool) {
+	switch team {
+	case common.TeamCounterTerrorists:
+		return SideCT, true
+	case common.TeamTerrorists:
+		return SideT, true
+	default:
+		return SideCT, false
+	}
+}
+
+// assignTeams works out which side each team is playing from the players'
+// current sides. Players already assigned to a team vote on whether the
+// teams have swapped; unknown players then join the team on their side.
+// teamOf is updated in place and the new sides are returned.
+func assignTeams(teamOf map[uint64]int, sides [2]Side, current map[uint64]Side) [2]Side {
+	var stay, swap int
+	for steamID, side := range current {
+		idx, ok := teamOf[steamID]
+		if !ok {
+			continue
+		}
+		if side == sides[idx] {
+			stay++
+		} else {
+			swap++
+		}
+	}
+	if swap > stay {
+		sides[0], sides[1] = sides[1], sides[0]
+	}
+
+	for steamID, side := range current {
+		if _, ok := teamOf[steamID]; !ok {
+			teamOf[steamID] = teamOnSide(sides, side)
+		}
+	}
+	return sides
+}
+
+// teamOnSide returns the index of the team playing side.
+func teamOnSide(sides [2]Side, side Side) int {
+	if sides[0] == side {
+		return 0
+	}
+	return 1
+}
+
+// sideLabel is the display name used for a team without a clan name.
+func sideLabel(side Side) string {
+	if side == SideCT {
+		return "Counter-Terrorists"
+	}
+	return "Terrorists"
+}
+
+// updateTeams refreshes team membership, sides and clan names from the
+// current game state.
+func (s *parseState) updateTeams() {
+	gs := s.p.GameState()
+
+	current := make(map[uint64]Side)
+	for _, pl := range gs.Participants().Playing() {
+		if pl == nil || pl.SteamID64 == 0 {
+			continue
+		}
+		if side, ok := teamSide(pl.Team); ok {
+			current[pl.SteamID64] = side
+		}
+	}
+	s.teamSides = assignTeams(s.teamOf, s.teamSides, current)
+
+	// CS2 matchmaking demos leave ClanName empty, so keep the last
+	// non-empty name seen for each team
+	if ct := gs.TeamCounterTerrorists(); ct != nil && ct.ClanName() != "" {
+		s.teamNames[teamOnSide(s.teamSides, SideCT)] = ct.ClanName()
+	}
+	if t := gs.TeamTerrorists(); t != nil && t.ClanName() != "" {
+		s.teamNames[teamOnSide(s.teamSides, SideT)] = t.ClanName()
+	}
+}
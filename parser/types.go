@@ -15,8 +15,9 @@ type Match struct {
 // Team represents one side in the match.
 type Team struct {
 	Name       string
+	ClanName   string // empty when the demo has none, e.g. matchmaking
 	Score      int
-	StartedAs  Side
+	StartedAs  Side     // side played in the first round
 	Players    []uint64 // steam IDs
 	RoundsWon  int
 	RoundsLost int
@@ -41,6 +42,11 @@ func (s Side) String() string {
 	}
 }
 
+// MarshalText encodes the side by name, e.g. in JSON output.
+func (s Side) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // Player holds per-player stats for the entire match.
 type Player struct {
 	SteamID uint64
@@ -60,7 +66,10 @@ type PlayerStats struct {
 	FlashAssists   int
 	UtilityDamage  int
 	TradeKills     int
-	Rating         float64
+	Rating         float64 // HLTV 2.0-style rating, see CalculateRating
+	KPR            float64 // kills per round
+	DPR            float64 // deaths per round
+	Impact         float64 // see EstimateImpact
 	TotalDamage    int
 	Headshots      int
 	RoundsPlayed   int
@@ -68,6 +77,13 @@ type PlayerStats struct {
 	FirstKills     int
 	FirstDeaths    int
 	MultiKills     map[int]int // round kill count -> occurrences (e.g. 3 -> 2 means two 3Ks)
+
+	// flashbang effectiveness
+	EnemiesFlashed        int
+	TeammatesFlashed      int
+	EnemyBlindDuration    float64 // total seconds enemies were blinded
+	AvgEnemyBlindDuration float64 // seconds per enemy flashed
+	FlashesLeadingToKill  int     // flashes where a blinded enemy was killed within flashKillWindow
 }
 
 // Round captures events and outcome for a single round.
@@ -84,6 +100,42 @@ type Round struct {
 	Duration   time.Duration
 	BombPlant  *BombEvent
 	BombDefuse *BombEvent
+	Grenades   []GrenadeEvent
+	Damage     []DamageEvent
+	Frames     []ReplayFrame // sampled player states, empty when sampling is disabled
+	Players    []PlayerRound // per-player contribution to this round
+	TeamSides  [2]Side       // side played by Match.Teams[0] and Match.Teams[1]
+}
+
+// WinnerTeam returns the index into Match.Teams of the team that won the
+// round, or -1 if the winner is unknown because the teams' sides were not
+// recorded or neither played the winning side.
+func (r Round) WinnerTeam() int {
+	switch {
+	case r.TeamSides[0] == r.TeamSides[1]:
+		return -1
+	case r.Winner == r.TeamSides[0]:
+		return 0
+	case r.Winner == r.TeamSides[1]:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// PlayerRound holds one player's stats for a single round.
+type PlayerRound struct {
+	SteamID        uint64
+	Side           Side
+	Kills          int
+	Deaths         int
+	Assists        int
+	Damage         int
+	Survived       bool
+	Traded         bool
+	KAST           bool // had a kill, assist, survived or was traded
+	EquipmentValue int
+	MoneySpent     int
 }
 
 // WinMethod describes how a round was won.
@@ -112,6 +164,11 @@ func (w WinMethod) String() string {
 	}
 }
 
+// MarshalText encodes the win method by name, e.g. in JSON output.
+func (w WinMethod) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
 // KillEvent records a single kill during the match.
 type KillEvent struct {
 	Tick             int
@@ -132,6 +189,44 @@ type KillEvent struct {
 	Time             time.Duration
 }
 
+// DamageEvent records a single hit during the match.
+// For CS2 demos without PlayerHurt events, damage is derived from the
+// cumulative per-player damage counter: one event per attacker per round,
+// with no victim, weapon or position information.
+type DamageEvent struct {
+	Tick             int
+	RoundNumber      int
+	AttackerSteamID  uint64 // zero for world damage (falls, bomb)
+	AttackerName     string
+	AttackerPosition Position
+	VictimSteamID    uint64
+	VictimName       string
+	VictimPosition   Position
+	Weapon           string
+	HitGroup         string
+	HealthDamage     int // excludes overkill damage
+	ArmorDamage      int
+	Aggregate        bool // an attacker's damage total, not a single hit
+}
+
+// ReplayFrame holds the state of every living player at one sampled tick.
+type ReplayFrame struct {
+	Tick    int
+	Players []PlayerSample
+}
+
+// PlayerSample is a snapshot of a single living player.
+type PlayerSample struct {
+	SteamID  uint64
+	Position Position
+	Yaw      float64 // view direction, degrees
+	Pitch    float64
+	Health   int
+	Armor    int
+	Weapon   string // active weapon, empty when unknown
+	Money    int
+}
+
 // Position holds 3D game coordinates.
 type Position struct {
 	X float64
@@ -180,6 +275,11 @@ func (b BuyType) String() string {
 	}
 }
 
+// MarshalText encodes the buy type by name, e.g. in JSON output.
+func (b BuyType) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
 // ClassifyBuyType returns the buy type based on total team equipment value.
 func ClassifyBuyType(teamEquipmentValue int) BuyType {
 	switch {
@@ -207,6 +307,57 @@ func isPistolRound(roundNum int) bool {
 	return false
 }
 
+// GrenadeEvent records a single piece of utility thrown during the match.
+// DetonationTick is zero when the detonation was never observed (e.g. the
+// demo ended while the grenade was still in flight).
+type GrenadeEvent struct {
+	Tick               int // tick the grenade was thrown
+	RoundNumber        int
+	ThrowerSteamID     uint64
+	ThrowerName        string
+	Type               GrenadeType
+	ThrowPosition      Position
+	DetonationPosition Position
+	DetonationTick     int
+}
+
+// GrenadeType identifies the kind of utility in a GrenadeEvent.
+type GrenadeType int
+
+const (
+	GrenadeTypeUnknown GrenadeType = iota
+	GrenadeTypeSmoke
+	GrenadeTypeFlash
+	GrenadeTypeHE
+	GrenadeTypeMolotov
+	GrenadeTypeIncendiary
+	GrenadeTypeDecoy
+)
+
+func (g GrenadeType) String() string {
+	switch g {
+	case GrenadeTypeSmoke:
+		return "Smoke"
+	case GrenadeTypeFlash:
+		return "Flash"
+	case GrenadeTypeHE:
+		return "HE"
+	case GrenadeTypeMolotov:
+		return "Molotov"
+	case GrenadeTypeIncendiary:
+		return "Incendiary"
+	case GrenadeTypeDecoy:
+		return "Decoy"
+	default:
+		return "Unknown"
+	}
+}
+
+// MarshalText encodes the grenade type by name, e.g. in JSON output.
+func (g GrenadeType) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
 // BombEvent records a bomb plant or defuse.
 type BombEvent struct {
 	PlayerSteamID uint64
@@ -240,22 +391,3 @@ func CalculateHeadshotPct(headshots, kills int) float64 {
 	}
 	return float64(headshots) / float64(kills) * 100
 }
-
-// CalculateRating computes a simplified HLTV 2.0-style rating.
-// Formula components:
-//   - kills per round (KPR): kills / rounds
-//   - survival rate (SPR): survived / rounds
-//   - impact: (kills + 0.5*assists) / rounds weighted
-//
-// Simplified rating = 0.0073*KAST + 0.3591*KPR - 0.5329*(deaths/rounds) + 0.2372*(impact) + 0.0032*ADR + 0.1587
-func CalculateRating(kills, deaths, assists, roundsPlayed, survived int, kastPct, adr float64) float64 {
-	if roundsPlayed == 0 {
-		return 0
-	}
-	rp := float64(roundsPlayed)
-	kpr := float64(kills) / rp
-	dpr := float64(deaths) / rp
-	impact := (float64(kills) + 0.5*float64(assists)) / rp
-
-	return 0.0073*kastPct + 0.3591*kpr - 0.5329*dpr + 0.2372*impact + 0.0032*adr + 0.1587
-}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const ingestJobColumns = `id, file_name, demo_hash, demo_path, status, error, match_id, created_at, updated_at`
+
+// FindMatchByHash returns the ID of the match stored from the demo with
+// the given hash, or ErrNotFound.
+func (s *sqlStore) FindMatchByHash(ctx context.Context, demoHash string) (string, error) {
+	var id string
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM matches WHERE demo_hash = ?`, demoHash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("query match by hash: %w", err)
+	}
+	return id, nil
+}
+
+// CreateIngestJob stores a new job.
+func (s *sqlStore) CreateIngestJob(ctx context.Context, job IngestJob) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO ingest_jobs (id, file_name, demo_hash, demo_path, status, error, match_id, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.FileName, job.DemoHash, job.DemoPath, job.Status, job.Error, job.MatchID,
+		job.CreatedAt.Format(time.RFC3339Nano), job.UpdatedAt.Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return fmt.Errorf("insert ingest job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// ClaimIngestJob moves the oldest queued job to parsing and returns it. It
+// returns ErrNotFound when nothing is queued. The claim is a single
+// statement, so concurrent workers never receive the same job.
+func (s *sqlStore) ClaimIngestJob(ctx context.Context) (IngestJob, error) {
+	row := s.db.QueryRowContext(ctx,
+		`UPDATE ingest_jobs SET status = ?, updated_at = ?
+		 WHERE id = (SELECT id FROM ingest_jobs WHERE status = ? ORDER BY created_at, id LIMIT 1`+s.dialect.skipLocked+`)
+		 RETURNING `+ingestJobColumns,
+		JobParsing, time.Now().Format(time.RFC3339Nano), JobQueued,
+	)
+
+	job, err := scanIngestJob(row)
+	if err == sql.ErrNoRows {
+		return IngestJob{}, ErrNotFound
+	}
+	if err != nil {
+		return IngestJob{}, fmt.Errorf("claim ingest job: %w", err)
+	}
+	return job, nil
+}
+
+// UpdateIngestJob records a job's status, error and match ID.
+func (s *sqlStore) UpdateIngestJob(ctx context.Context, job IngestJob) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE ingest_jobs SET status = ?, error = ?, match_id = ?, updated_at = ? WHERE id = ?`,
+		job.Status, job.Error, job.MatchID, job.UpdatedAt.Format(time.RFC3339Nano), job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update ingest job %s: %w", job.ID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update ingest job %s: %w", job.ID, err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RequeueIngestJobs puts jobs left parsing or storing by a previous run
+// back in the queue and returns how many were requeued.
+func (s *sqlStore) RequeueIngestJobs(ctx context.Context) (int, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE ingest_jobs SET status = ?, updated_at = ? WHERE status IN (?, ?)`,
+		JobQueued, time.Now().Format(time.RFC3339Nano), JobParsing, JobStoring,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("requeue ingest jobs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("requeue ingest jobs: %w", err)
+	}
+	return int(n), nil
+}
+
+// GetIngestJob returns a job by ID, or ErrNotFound.
+func (s *sqlStore) GetIngestJob(ctx context.Context, id string) (IngestJob, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+ingestJobColumns+` FROM ingest_jobs WHERE id = ?`, id,
+	)
+	job, err := scanIngestJob(row)
+	if err == sql.ErrNoRows {
+		return IngestJob{}, ErrNotFound
+	}
+	if err != nil {
+		return IngestJob{}, fmt.Errorf("query ingest job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// ListIngestJobs returns jobs newest first, paginated the same way as
+// ListMatches.
+func (s *sqlStore) ListIngestJobs(ctx context.Context, filter IngestJobFilter) ([]IngestJob, error) {
+	var (
+		clauses []string
+		args    []any
+	)
+
+	if filter.Status != "" {
+		clauses = append(clauses, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.CursorTime.IsZero() && filter.CursorID != "" {
+		clauses = append(clauses, "(created_at < ? OR (created_at = ? AND id < ?))")
+		ct := filter.CursorTime.Format(time.RFC3339Nano)
+		args = append(args, ct, ct, filter.CursorID)
+	}
+
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	query := fmt.Sprintf(
+		`SELECT %s FROM ingest_jobs %s ORDER BY created_at DESC, id DESC LIMIT ?`,
+		ingestJobColumns, where,
+	)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list ingest jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var out []IngestJob
+	for rows.Next() {
+		job, err := scanIngestJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ingest job: %w", err)
+		}
+		out = append(out, job)
+	}
+	return out, rows.Err()
+}
+
+// scanIngestJob scans a row of ingestJobColumns.
+func scanIngestJob(row interface{ Scan(...any) error }) (IngestJob, error) {
+	var (
+		job                    IngestJob
+		createdStr, updatedStr string
+	)
+	if err := row.Scan(&job.ID, &job.FileName, &job.DemoHash, &job.DemoPath, &job.Status, &job.Error, &job.MatchID,
+		&createdStr, &updatedStr); err != nil {
+		return IngestJob{}, err
+	}
+	job.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+	job.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedStr)
+	return job, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// leaderboardMetrics maps each metric to its column in the leaderboard
+// query.
+var leaderboardMetrics = map[string]string{
+	MetricRating:             "lb.rating",
+	MetricADR:                "lb.adr",
+	MetricKAST:               "lb.kast",
+	MetricHeadshotPct:        "lb.hs_pct",
+	MetricOpeningKillSuccess: "lb.opening_kill_success",
+	MetricClutchWinRate:      "lb.clutch_win_rate",
+}
+
+// GetLeaderboard ranks players by a metric over the matches picked by
+// filter, best first, returning one page. Players with equal values are
+// ordered by steam ID.
+func (s *sqlStore) GetLeaderboard(ctx context.Context, filter LeaderboardFilter) ([]LeaderboardEntry, error) {
+	metric, ok := leaderboardMetrics[filter.Metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard metric %q", filter.Metric)
+	}
+
+	matchFilter := PlayerFilter{MapName: filter.MapName, DateFrom: filter.DateFrom, DateTo: filter.DateTo}
+	clauses, matchArgs := playerFilterClauses("", matchFilter)
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	args := append([]any{}, matchArgs...)
+	args = append(args, filter.MinRounds, filter.MinMatches)
+	args = append(args, matchArgs...)
+
+	cursor := ""
+	if filter.CursorSteamID != "" {
+		cursor = "WHERE ranked.value < ? OR (ranked.value = ? AND ranked.steam_id > ?)"
+		args = append(args, filter.CursorValue, filter.CursorValue, filter.CursorSteamID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	// clutches are counted over the same matches as the stats; players
+	// without any rank last on clutch win rate
+	query := fmt.Sprintf(
+		`SELECT ranked.rank_no, ranked.steam_id, ranked.name, ranked.value,
+		        ranked.matches, ranked.rounds, ranked.kills, ranked.deaths,
+		        ranked.first_kills, ranked.first_deaths, ranked.clutches, ranked.clutches_won,
+		        ranked.rating, ranked.adr, ranked.kast, ranked.hs_pct,
+		        ranked.opening_kill_success, ranked.clutch_win_rate
+		 FROM (
+		   SELECT RANK() OVER (ORDER BY %[1]s DESC) AS rank_no, %[1]s AS value, lb.*
+		   FROM (
+		     SELECT agg.*,
+		            COALESCE(cl.clutches, 0) AS clutches, COALESCE(cl.clutches_won, 0) AS clutches_won,
+		            COALESCE(CAST(agg.first_kills AS DOUBLE PRECISION) * 100 / NULLIF(agg.first_kills + agg.first_deaths, 0), 0) AS opening_kill_success,
+		            COALESCE(CAST(cl.clutches_won AS DOUBLE PRECISION) * 100 / NULLIF(cl.clutches, 0), 0) AS clutch_win_rate
+		     FROM (
+		       SELECT pm.player_id, pm.steam_id, pm.name, %[2]s
+		       FROM (SELECT mp.player_id, p.steam_id, p.name, %[3]s %[4]s %[5]s) pm
+		       GROUP BY pm.player_id, pm.steam_id, pm.name
+		       HAVING SUM(pm.rounds) >= ? AND COUNT(*) >= ?
+		     ) agg
+		     LEFT JOIN (
+		       SELECT c.player_id, COUNT(*) AS clutches, SUM(c.success) AS clutches_won
+		       FROM clutches c
+		       JOIN rounds r ON r.id = c.round_id
+		       JOIN matches m ON m.id = r.match_id
+		       %[5]s
+		       GROUP BY c.player_id
+		     ) cl ON cl.player_id = agg.player_id
+		   ) lb
+		 ) ranked
+		 %[6]s
+		 ORDER BY ranked.value DESC, ranked.steam_id
+		 LIMIT ?`,
+		metric, careerColumns, playerMatchColumns, playerMatchesFrom, where, cursor,
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	var out []LeaderboardEntry
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.Rank, &e.SteamID, &e.Name, &e.Value,
+			&e.Matches, &e.RoundsPlayed, &e.Kills, &e.Deaths,
+			&e.FirstKills, &e.FirstDeaths, &e.Clutches, &e.ClutchesWon,
+			&e.Rating, &e.ADR, &e.KAST, &e.HeadshotPct,
+			&e.OpeningKillSuccess, &e.ClutchWinRate); err != nil {
+			return nil, fmt.Errorf("scan leaderboard entry: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
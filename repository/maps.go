@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// GetMapStats returns round outcomes summed per map over the matches
+// picked by filter, most played map first.
+func (s *sqlStore) GetMapStats(ctx context.Context, filter MapStatsFilter) ([]MapStats, error) {
+	tracked, args := trackedMatches(filter)
+
+	query := fmt.Sprintf(
+		`SELECT mt.map_name, COUNT(*),
+		        SUM(CASE WHEN (mt.tracks_a = 1 AND mt.tracks_b = 0 AND mt.score_a > mt.score_b)
+		                   OR (mt.tracks_b = 1 AND mt.tracks_a = 0 AND mt.score_b > mt.score_a) THEN 1 ELSE 0 END),
+		        SUM(CASE WHEN (mt.tracks_a = 1 AND mt.tracks_b = 0 AND mt.score_a < mt.score_b)
+		                   OR (mt.tracks_b = 1 AND mt.tracks_a = 0 AND mt.score_b < mt.score_a) THEN 1 ELSE 0 END)
+		 FROM (%s) mt
+		 WHERE mt.tracks_a = 1 OR mt.tracks_b = 1
+		 GROUP BY mt.map_name
+		 ORDER BY COUNT(*) DESC, mt.map_name`,
+		tracked,
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query map matches: %w", err)
+	}
+	defer rows.Close()
+
+	var out []MapStats
+	byMap := make(map[string]int)
+	for rows.Next() {
+		var ms MapStats
+		if err := rows.Scan(&ms.MapName, &ms.Matches, &ms.Wins, &ms.Losses); err != nil {
+			return nil, fmt.Errorf("scan map matches: %w", err)
+		}
+		byMap[ms.MapName] = len(out)
+		out = append(out, ms)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Each round counts for the tracked teams on the side they played it;
+	// rounds stored without team A's side are left out. The pistol rounds are the first round and the first round after the
+	// halftime side switch.
+	query = fmt.Sprintf(
+		`SELECT rt.map_name, COUNT(*),
+		        SUM(rt.tracks_ct), SUM(CASE WHEN rt.winner_team = 'CT' THEN rt.tracks_ct ELSE 0 END),
+		        SUM(rt.tracks_t), SUM(CASE WHEN rt.winner_team = 'T' THEN rt.tracks_t ELSE 0 END),
+		        SUM(rt.pistol * rt.tracks_ct), SUM(CASE WHEN rt.winner_team = 'CT' THEN rt.pistol * rt.tracks_ct ELSE 0 END),
+		        SUM(rt.pistol * rt.tracks_t), SUM(CASE WHEN rt.winner_team = 'T' THEN rt.pistol * rt.tracks_t ELSE 0 END),
+		        SUM(CASE WHEN rt.win_method = 'Elimination' THEN rt.tracks_won ELSE 0 END),
+		        SUM(CASE WHEN rt.win_method = 'BombExploded' THEN rt.tracks_won ELSE 0 END),
+		        SUM(CASE WHEN rt.win_method = 'BombDefused' THEN rt.tracks_won ELSE 0 END),
+		        SUM(CASE WHEN rt.win_method = 'TimeExpired' THEN rt.tracks_won ELSE 0 END),
+		        COALESCE(SUM(rt.duration_seconds) / NULLIF(SUM(CASE WHEN rt.duration_seconds > 0 THEN 1 ELSE 0 END), 0), 0),
+		        SUM(CASE WHEN rt.bomb_plant_site IS NOT NULL THEN rt.tracks_t ELSE 0 END),
+		        SUM(CASE WHEN rt.bomb_plant_site = 'A' THEN rt.tracks_t ELSE 0 END),
+		        SUM(CASE WHEN rt.bomb_plant_site = 'B' THEN rt.tracks_t ELSE 0 END)
+		 FROM (
+		   SELECT sides.*,
+		          CASE sides.winner_team WHEN 'CT' THEN sides.tracks_ct WHEN 'T' THEN sides.tracks_t ELSE 0 END AS tracks_won
+		   FROM (
+		     SELECT mt.map_name, r.winner_team, r.win_method, r.duration_seconds, r.bomb_plant_site,
+		            CASE r.team_a_side WHEN 'CT' THEN mt.tracks_a WHEN 'T' THEN mt.tracks_b ELSE 0 END AS tracks_ct,
+		            CASE r.team_a_side WHEN 'T' THEN mt.tracks_a WHEN 'CT' THEN mt.tracks_b ELSE 0 END AS tracks_t,
+		            CASE WHEN r.number = 1 OR r.number = (
+		                   SELECT MIN(r2.number) FROM rounds r2
+		                   WHERE r2.match_id = r.match_id AND r2.team_a_side <> ''
+		                     AND r2.team_a_side <> COALESCE(mt.team_a_started_as, 'CT')
+		                 ) THEN 1 ELSE 0 END AS pistol
+		     FROM rounds r
+		     JOIN (%s) mt ON mt.id = r.match_id
+		     WHERE (mt.tracks_a = 1 OR mt.tracks_b = 1) AND r.team_a_side <> ''
+		   ) sides
+		 ) rt
+		 GROUP BY rt.map_name`,
+		tracked,
+	)
+	rows, err = s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query map rounds: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			mapName string
+			rs      MapStats
+		)
+		if err := rows.Scan(&mapName, &rs.Rounds,
+			&rs.CTRounds, &rs.CTRoundsWon, &rs.TRounds, &rs.TRoundsWon,
+			&rs.CTPistolRounds, &rs.CTPistolRoundsWon, &rs.TPistolRounds, &rs.TPistolRoundsWon,
+			&rs.Eliminations, &rs.BombsExploded, &rs.BombsDefused, &rs.TimeExpired,
+			&rs.AvgRoundDuration, &rs.BombPlants, &rs.PlantsA, &rs.PlantsB); err != nil {
+			return nil, fmt.Errorf("scan map rounds: %w", err)
+		}
+		i, ok := byMap[mapName]
+		if !ok {
+			continue
+		}
+		rs.MapName = mapName
+		rs.Matches, rs.Wins, rs.Losses = out[i].Matches, out[i].Wins, out[i].Losses
+		out[i] = rs
+	}
+	return out, rows.Err()
+}
+
+// trackedMatches returns a query over the matches picked by filter, with
+// tracks_a and tracks_b set to 1 for the teams whose rounds are counted:
+// the player's or the named team, or both without either filter.
+func trackedMatches(filter MapStatsFilter) (string, []any) {
+	var (
+		tracksA, tracksB []string
+		clauses          []string
+		args, whereArgs  []any
+	)
+	from := "FROM matches m"
+	if filter.TeamName != "" {
+		tracksA = append(tracksA, "m.team_a = ?")
+		tracksB = append(tracksB, "m.team_b = ?")
+	}
+	if filter.SteamID != "" {
+		from += `
+		 JOIN match_players mp ON mp.match_id = m.id
+		 JOIN players p ON p.id = mp.player_id`
+		tracksA = append(tracksA, "mp.team = COALESCE(m.team_a_started_as, 'CT')")
+		tracksB = append(tracksB, "mp.team <> COALESCE(m.team_a_started_as, 'CT')")
+		clauses = append(clauses, "p.steam_id = ?")
+		whereArgs = append(whereArgs, filter.SteamID)
+	}
+	if filter.MapName != "" {
+		clauses = append(clauses, "m.map_name = ?")
+		whereArgs = append(whereArgs, filter.MapName)
+	}
+
+	if filter.TeamName != "" {
+		args = append(args, filter.TeamName, filter.TeamName)
+	}
+	args = append(args, whereArgs...)
+
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+	return fmt.Sprintf(
+		`SELECT m.id, m.map_name, m.score_a, m.score_b, m.team_a_started_as,
+		        %s AS tracks_a, %s AS tracks_b
+		 %s
+		 %s`,
+		trackedExpr(tracksA), trackedExpr(tracksB), from, where,
+	), args
+}
+
+// trackedExpr returns a 0 or 1 column that is 1 when every condition holds.
+func trackedExpr(conds []string) string {
+	if len(conds) == 0 {
+		return "1"
+	}
+	return "CASE WHEN " + strings.Join(conds, " AND ") + " THEN 1 ELSE 0 END"
+}
@@ -0,0 +1,5 @@
+ALTER TABLE match_players ADD COLUMN enemies_flashed INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN teammates_flashed INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN enemy_blind_duration REAL NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN avg_enemy_blind_duration REAL NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN flashes_leading_to_kill INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS damage_events (
+    id TEXT PRIMARY KEY,
+    round_id TEXT NOT NULL REFERENCES rounds(id),
+    tick INTEGER NOT NULL DEFAULT 0,
+    attacker_id TEXT REFERENCES players(id),
+    victim_id TEXT REFERENCES players(id),
+    attacker_steam_id TEXT,
+    victim_steam_id TEXT,
+    weapon TEXT NOT NULL DEFAULT '',
+    hit_group TEXT NOT NULL DEFAULT '',
+    health_damage INTEGER NOT NULL DEFAULT 0,
+    armor_damage INTEGER NOT NULL DEFAULT 0,
+    attacker_x REAL NOT NULL DEFAULT 0,
+    attacker_y REAL NOT NULL DEFAULT 0,
+    attacker_z REAL NOT NULL DEFAULT 0,
+    victim_x REAL NOT NULL DEFAULT 0,
+    victim_y REAL NOT NULL DEFAULT 0,
+    victim_z REAL NOT NULL DEFAULT 0,
+    aggregate INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_damage_events_round ON damage_events(round_id);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS round_replays (
+    round_id TEXT PRIMARY KEY REFERENCES rounds(id),
+    frame_count INTEGER NOT NULL,
+    data BLOB NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE match_players ADD COLUMN kpr REAL NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN dpr REAL NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN impact REAL NOT NULL DEFAULT 0;
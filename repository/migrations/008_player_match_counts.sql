@@ -0,0 +1,10 @@
+ALTER TABLE match_players ADD COLUMN headshots INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN total_damage INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN survived INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN first_kills INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN first_deaths INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN trade_kills INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN two_k INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN three_k INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN four_k INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE match_players ADD COLUMN five_k INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS player_rounds (
+    round_id TEXT NOT NULL REFERENCES rounds(id),
+    player_id TEXT NOT NULL REFERENCES players(id),
+    side TEXT NOT NULL,
+    kills INTEGER NOT NULL DEFAULT 0,
+    deaths INTEGER NOT NULL DEFAULT 0,
+    assists INTEGER NOT NULL DEFAULT 0,
+    damage INTEGER NOT NULL DEFAULT 0,
+    survived INTEGER NOT NULL DEFAULT 0,
+    traded INTEGER NOT NULL DEFAULT 0,
+    kast INTEGER NOT NULL DEFAULT 0,
+    equipment_value INTEGER NOT NULL DEFAULT 0,
+    money_spent INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (round_id, player_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_rounds_player ON player_rounds(player_id);
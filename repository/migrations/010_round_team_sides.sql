@@ -0,0 +1,6 @@
+ALTER TABLE rounds ADD COLUMN team_a_side TEXT NOT NULL DEFAULT '';
+ALTER TABLE rounds ADD COLUMN team_a_won INTEGER NOT NULL DEFAULT 0;
+
+-- Existing matches keep an empty side: their team A is whichever team was
+-- on CT at the end of the demo, so the sides of earlier rounds cannot be
+-- told apart without reprocessing the demo.
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS ingest_jobs (
+    id TEXT PRIMARY KEY,
+    file_name TEXT NOT NULL DEFAULT '',
+    demo_hash TEXT NOT NULL,
+    demo_path TEXT NOT NULL DEFAULT '', -- the upload spooled to disk
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    match_id TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_status ON ingest_jobs(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_created_at ON ingest_jobs(created_at, id);
@@ -0,0 +1,3 @@
+-- Version of the parser each match was produced with, so outdated matches
+-- can be reprocessed. Matches stored before this migration are version 0.
+ALTER TABLE matches ADD COLUMN parser_version INTEGER NOT NULL DEFAULT 0;
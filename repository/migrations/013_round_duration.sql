@@ -0,0 +1,4 @@
+-- Length of each round in seconds, from freeze-time end to round end.
+-- Rounds stored before this migration have 0; reprocess those demos to
+-- fill it in.
+ALTER TABLE rounds ADD COLUMN duration_seconds REAL NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Teams persist across matches. A team with a clan name is identified by
+-- it; one without is matched to an earlier team by roster overlap. Each
+-- match side links to its team. Matches stored before this migration have
+-- no teams until reprocessed.
+CREATE TABLE IF NOT EXISTS teams (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    clan_name TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teams_clan_name ON teams(clan_name) WHERE clan_name <> '';
+
+ALTER TABLE matches ADD COLUMN team_a_id TEXT REFERENCES teams(id);
+ALTER TABLE matches ADD COLUMN team_b_id TEXT REFERENCES teams(id);
+
+CREATE INDEX IF NOT EXISTS idx_matches_team_a ON matches(team_a_id);
+CREATE INDEX IF NOT EXISTS idx_matches_team_b ON matches(team_b_id);
@@ -0,0 +1,182 @@
+-- The PostgreSQL schema matches the SQLite one after its migration 012, so
+-- both backends run the same queries. Timestamps are stored as RFC 3339
+-- text and flags as 0 or 1, as they are in SQLite. Keys and timestamps use
+-- the C collation so they sort bytewise, the way SQLite compares them.
+
+CREATE TABLE IF NOT EXISTS matches (
+    id TEXT COLLATE "C" PRIMARY KEY,
+    map_name TEXT NOT NULL,
+    date TEXT COLLATE "C" NOT NULL,
+    duration_seconds INTEGER NOT NULL,
+    team_a TEXT NOT NULL,
+    team_b TEXT NOT NULL,
+    score_a INTEGER NOT NULL,
+    score_b INTEGER NOT NULL,
+    demo_hash TEXT NOT NULL UNIQUE,
+    team_a_started_as TEXT DEFAULT 'CT',
+    parser_version INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT COLLATE "C" NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS players (
+    id TEXT PRIMARY KEY,
+    steam_id TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS match_players (
+    match_id TEXT NOT NULL REFERENCES matches(id),
+    player_id TEXT NOT NULL REFERENCES players(id),
+    team TEXT NOT NULL,
+    kills INTEGER NOT NULL DEFAULT 0,
+    deaths INTEGER NOT NULL DEFAULT 0,
+    assists INTEGER NOT NULL DEFAULT 0,
+    adr DOUBLE PRECISION NOT NULL DEFAULT 0,
+    kast DOUBLE PRECISION NOT NULL DEFAULT 0,
+    hs_pct DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rating DOUBLE PRECISION NOT NULL DEFAULT 0,
+    flash_assists INTEGER NOT NULL DEFAULT 0,
+    utility_damage INTEGER NOT NULL DEFAULT 0,
+    enemies_flashed INTEGER NOT NULL DEFAULT 0,
+    teammates_flashed INTEGER NOT NULL DEFAULT 0,
+    enemy_blind_duration DOUBLE PRECISION NOT NULL DEFAULT 0,
+    avg_enemy_blind_duration DOUBLE PRECISION NOT NULL DEFAULT 0,
+    flashes_leading_to_kill INTEGER NOT NULL DEFAULT 0,
+    kpr DOUBLE PRECISION NOT NULL DEFAULT 0,
+    dpr DOUBLE PRECISION NOT NULL DEFAULT 0,
+    impact DOUBLE PRECISION NOT NULL DEFAULT 0,
+    headshots INTEGER NOT NULL DEFAULT 0,
+    total_damage INTEGER NOT NULL DEFAULT 0,
+    survived INTEGER NOT NULL DEFAULT 0,
+    first_kills INTEGER NOT NULL DEFAULT 0,
+    first_deaths INTEGER NOT NULL DEFAULT 0,
+    trade_kills INTEGER NOT NULL DEFAULT 0,
+    two_k INTEGER NOT NULL DEFAULT 0,
+    three_k INTEGER NOT NULL DEFAULT 0,
+    four_k INTEGER NOT NULL DEFAULT 0,
+    five_k INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (match_id, player_id)
+);
+
+CREATE TABLE IF NOT EXISTS rounds (
+    id TEXT PRIMARY KEY,
+    match_id TEXT NOT NULL REFERENCES matches(id),
+    number INTEGER NOT NULL,
+    winner_team TEXT NOT NULL,
+    win_method TEXT NOT NULL,
+    first_kill_player_id TEXT,
+    first_death_player_id TEXT,
+    first_kill_steam_id TEXT,
+    first_death_steam_id TEXT,
+    first_kill_weapon TEXT,
+    first_kill_round_time DOUBLE PRECISION,
+    bomb_plant_steam_id TEXT,
+    bomb_plant_site TEXT,
+    bomb_plant_round_time DOUBLE PRECISION,
+    bomb_defuse_steam_id TEXT,
+    bomb_defuse_round_time DOUBLE PRECISION,
+    team_a_side TEXT NOT NULL DEFAULT '',
+    team_a_won INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS clutches (
+    round_id TEXT NOT NULL REFERENCES rounds(id),
+    player_id TEXT NOT NULL REFERENCES players(id),
+    player_steam_id TEXT,
+    opponents INTEGER NOT NULL,
+    success INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (round_id, player_id)
+);
+
+CREATE TABLE IF NOT EXISTS economy_rounds (
+    round_id TEXT NOT NULL REFERENCES rounds(id),
+    team TEXT NOT NULL,
+    spend INTEGER NOT NULL DEFAULT 0,
+    equipment_value INTEGER NOT NULL DEFAULT 0,
+    buy_type TEXT NOT NULL,
+    PRIMARY KEY (round_id, team)
+);
+
+CREATE TABLE IF NOT EXISTS kill_events (
+    id TEXT COLLATE "C" PRIMARY KEY,
+    round_id TEXT NOT NULL REFERENCES rounds(id),
+    attacker_id TEXT REFERENCES players(id),
+    victim_id TEXT REFERENCES players(id),
+    attacker_steam_id TEXT,
+    victim_steam_id TEXT,
+    weapon TEXT NOT NULL,
+    headshot INTEGER NOT NULL DEFAULT 0,
+    attacker_x DOUBLE PRECISION NOT NULL DEFAULT 0,
+    attacker_y DOUBLE PRECISION NOT NULL DEFAULT 0,
+    attacker_z DOUBLE PRECISION NOT NULL DEFAULT 0,
+    victim_x DOUBLE PRECISION NOT NULL DEFAULT 0,
+    victim_y DOUBLE PRECISION NOT NULL DEFAULT 0,
+    victim_z DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS damage_events (
+    id TEXT COLLATE "C" PRIMARY KEY,
+    round_id TEXT NOT NULL REFERENCES rounds(id),
+    tick INTEGER NOT NULL DEFAULT 0,
+    attacker_id TEXT REFERENCES players(id),
+    victim_id TEXT REFERENCES players(id),
+    attacker_steam_id TEXT,
+    victim_steam_id TEXT,
+    weapon TEXT NOT NULL DEFAULT '',
+    hit_group TEXT NOT NULL DEFAULT '',
+    health_damage INTEGER NOT NULL DEFAULT 0,
+    armor_damage INTEGER NOT NULL DEFAULT 0,
+    attacker_x DOUBLE PRECISION NOT NULL DEFAULT 0,
+    attacker_y DOUBLE PRECISION NOT NULL DEFAULT 0,
+    attacker_z DOUBLE PRECISION NOT NULL DEFAULT 0,
+    victim_x DOUBLE PRECISION NOT NULL DEFAULT 0,
+    victim_y DOUBLE PRECISION NOT NULL DEFAULT 0,
+    victim_z DOUBLE PRECISION NOT NULL DEFAULT 0,
+    aggregate INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS round_replays (
+    round_id TEXT PRIMARY KEY REFERENCES rounds(id),
+    frame_count INTEGER NOT NULL,
+    data BYTEA NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS player_rounds (
+    round_id TEXT NOT NULL REFERENCES rounds(id),
+    player_id TEXT NOT NULL REFERENCES players(id),
+    side TEXT NOT NULL,
+    kills INTEGER NOT NULL DEFAULT 0,
+    deaths INTEGER NOT NULL DEFAULT 0,
+    assists INTEGER NOT NULL DEFAULT 0,
+    damage INTEGER NOT NULL DEFAULT 0,
+    survived INTEGER NOT NULL DEFAULT 0,
+    traded INTEGER NOT NULL DEFAULT 0,
+    kast INTEGER NOT NULL DEFAULT 0,
+    equipment_value INTEGER NOT NULL DEFAULT 0,
+    money_spent INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (round_id, player_id)
+);
+
+CREATE TABLE IF NOT EXISTS ingest_jobs (
+    id TEXT COLLATE "C" PRIMARY KEY,
+    file_name TEXT NOT NULL DEFAULT '',
+    demo_hash TEXT NOT NULL,
+    demo_path TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    match_id TEXT NOT NULL DEFAULT '',
+    created_at TEXT COLLATE "C" NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_matches_created_at ON matches(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_matches_map ON matches(map_name);
+CREATE INDEX IF NOT EXISTS idx_match_players_match ON match_players(match_id);
+CREATE INDEX IF NOT EXISTS idx_match_players_player ON match_players(player_id);
+CREATE INDEX IF NOT EXISTS idx_rounds_match ON rounds(match_id);
+CREATE INDEX IF NOT EXISTS idx_kill_events_round ON kill_events(round_id);
+CREATE INDEX IF NOT EXISTS idx_economy_rounds_round ON economy_rounds(round_id);
+CREATE INDEX IF NOT EXISTS idx_damage_events_round ON damage_events(round_id);
+CREATE INDEX IF NOT EXISTS idx_player_rounds_player ON player_rounds(player_id);
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_status ON ingest_jobs(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_created_at ON ingest_jobs(created_at, id);
@@ -0,0 +1,2 @@
+-- Length of each round in seconds, as in SQLite migration 013.
+ALTER TABLE rounds ADD COLUMN IF NOT EXISTS duration_seconds DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+-- Teams persist across matches, as in SQLite migration 014.
+CREATE TABLE IF NOT EXISTS teams (
+    id TEXT COLLATE "C" PRIMARY KEY,
+    name TEXT NOT NULL,
+    clan_name TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teams_clan_name ON teams(clan_name) WHERE clan_name <> '';
+
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS team_a_id TEXT COLLATE "C" REFERENCES teams(id);
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS team_b_id TEXT COLLATE "C" REFERENCES teams(id);
+
+CREATE INDEX IF NOT EXISTS idx_matches_team_a ON matches(team_a_id);
+CREATE INDEX IF NOT EXISTS idx_matches_team_b ON matches(team_b_id);
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// playerMatchesFrom joins each match a player played with their stats in
+// it; the queries below filter it with playerFilterClauses.
+const playerMatchesFrom = `FROM match_players mp
+	 JOIN players p ON p.id = mp.player_id
+	 JOIN matches m ON m.id = mp.match_id`
+
+// playerMatchColumns are the per-match values career stats are built from,
+// selected from playerMatchesFrom. The player's team is the one that
+// started on their side. Rounds played are counted from player_rounds,
+// falling back to the match score for matches stored without them.
+const playerMatchColumns = `m.map_name,
+	        CASE WHEN mp.team = COALESCE(m.team_a_started_as, 'CT') THEN m.score_a ELSE m.score_b END AS rounds_won,
+	        CASE WHEN mp.team = COALESCE(m.team_a_started_as, 'CT') THEN m.score_b ELSE m.score_a END AS rounds_lost,
+	        COALESCE(NULLIF((SELECT COUNT(*) FROM player_rounds pr JOIN rounds r ON r.id = pr.round_id
+	                         WHERE r.match_id = mp.match_id AND pr.player_id = mp.player_id), 0),
+	                 m.score_a + m.score_b) AS rounds,
+	        mp.kills, mp.deaths, mp.assists, mp.headshots, mp.total_damage,
+	        mp.first_kills, mp.first_deaths, mp.trade_kills, mp.flash_assists, mp.utility_damage,
+	        mp.two_k, mp.three_k, mp.four_k, mp.five_k,
+	        mp.adr, mp.kast, mp.hs_pct, mp.rating, mp.kpr, mp.dpr, mp.impact`
+
+// careerColumns aggregate rows of playerMatchColumns, aliased pm, in the
+// order careerDest lists them.
+const careerColumns = `COUNT(*) AS matches,
+	        SUM(CASE WHEN pm.rounds_won > pm.rounds_lost THEN 1 ELSE 0 END) AS wins,
+	        SUM(CASE WHEN pm.rounds_won < pm.rounds_lost THEN 1 ELSE 0 END) AS losses,
+	        SUM(pm.rounds) AS rounds, SUM(pm.kills) AS kills, SUM(pm.deaths) AS deaths, SUM(pm.assists) AS assists,
+	        SUM(pm.headshots) AS headshots, SUM(pm.total_damage) AS total_damage,
+	        SUM(pm.first_kills) AS first_kills, SUM(pm.first_deaths) AS first_deaths, SUM(pm.trade_kills) AS trade_kills,
+	        SUM(pm.flash_assists) AS flash_assists, SUM(pm.utility_damage) AS utility_damage,
+	        SUM(pm.two_k) AS two_k, SUM(pm.three_k) AS three_k, SUM(pm.four_k) AS four_k, SUM(pm.five_k) AS five_k,
+	        COALESCE(SUM(pm.adr * pm.rounds) / NULLIF(SUM(pm.rounds), 0), 0) AS adr,
+	        COALESCE(SUM(pm.kast * pm.rounds) / NULLIF(SUM(pm.rounds), 0), 0) AS kast,
+	        COALESCE(SUM(pm.hs_pct * pm.kills) / NULLIF(SUM(pm.kills), 0), 0) AS hs_pct,
+	        COALESCE(SUM(pm.rating * pm.rounds) / NULLIF(SUM(pm.rounds), 0), 0) AS rating,
+	        COALESCE(SUM(pm.kpr * pm.rounds) / NULLIF(SUM(pm.rounds), 0), 0) AS kpr,
+	        COALESCE(SUM(pm.dpr * pm.rounds) / NULLIF(SUM(pm.rounds), 0), 0) AS dpr,
+	        COALESCE(SUM(pm.impact * pm.rounds) / NULLIF(SUM(pm.rounds), 0), 0) AS impact`
+
+// GetPlayer returns the player with the given steam ID, or ErrNotFound.
+func (s *sqlStore) GetPlayer(ctx context.Context, steamID string) (Player, error) {
+	var p Player
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, steam_id, name FROM players WHERE steam_id = ?`, steamID,
+	).Scan(&p.ID, &p.SteamID, &p.Name)
+	if err == sql.ErrNoRows {
+		return Player{}, ErrNotFound
+	}
+	if err != nil {
+		return Player{}, fmt.Errorf("query player %s: %w", steamID, err)
+	}
+	return p, nil
+}
+
+// ListPlayerMatches returns a page of the matches a player played, newest
+// first, with their stats in each.
+func (s *sqlStore) ListPlayerMatches(ctx context.Context, steamID string, filter PlayerFilter) ([]PlayerMatch, error) {
+	clauses, args := playerFilterClauses(steamID, filter)
+	if !filter.CursorTime.IsZero() && filter.CursorID != "" {
+		clauses = append(clauses, "(m.created_at < ? OR (m.created_at = ? AND m.id < ?))")
+		ct := filter.CursorTime.Format(time.RFC3339Nano)
+		args = append(args, ct, ct, filter.CursorID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	query := fmt.Sprintf(
+		`SELECT m.id, m.date, m.duration_seconds, m.team_a, m.team_b, m.score_a, m.score_b,
+		        COALESCE(m.team_a_started_as, 'CT'), m.created_at, COALESCE(m.team_a_id, ''), COALESCE(m.team_b_id, ''),
+		        mp.player_id, p.name, mp.team,
+		        mp.enemies_flashed, mp.teammates_flashed, mp.enemy_blind_duration,
+		        mp.avg_enemy_blind_duration, mp.flashes_leading_to_kill, mp.survived,
+		        %s
+		 %s
+		 WHERE %s
+		 ORDER BY m.created_at DESC, m.id DESC LIMIT ?`,
+		playerMatchColumns, playerMatchesFrom, strings.Join(clauses, " AND "),
+	)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list matches of player %s: %w", steamID, err)
+	}
+	defer rows.Close()
+
+	var out []PlayerMatch
+	for rows.Next() {
+		var (
+			pm                  PlayerMatch
+			dateStr, createdStr string
+		)
+		ms, ps := &pm.Match, &pm.Stats
+		if err := rows.Scan(&ms.ID, &dateStr, &ms.DurationSeconds, &ms.TeamA, &ms.TeamB, &ms.ScoreA, &ms.ScoreB,
+			&ms.TeamAStartedAs, &createdStr, &ms.TeamAID, &ms.TeamBID,
+			&ps.PlayerID, &ps.Name, &ps.Team,
+			&ps.EnemiesFlashed, &ps.TeammatesFlashed, &ps.EnemyBlindDuration,
+			&ps.AvgEnemyBlindDuration, &ps.FlashesLeadingToKill, &ps.Survived,
+			&ms.MapName, &pm.RoundsWon, &pm.RoundsLost, &pm.RoundsPlayed,
+			&ps.Kills, &ps.Deaths, &ps.Assists, &ps.Headshots, &ps.TotalDamage,
+			&ps.FirstKills, &ps.FirstDeaths, &ps.TradeKills, &ps.FlashAssists, &ps.UtilityDamage,
+			&ps.TwoK, &ps.ThreeK, &ps.FourK, &ps.FiveK,
+			&ps.ADR, &ps.KAST, &ps.HeadshotPct, &ps.Rating, &ps.KPR, &ps.DPR, &ps.Impact); err != nil {
+			return nil, fmt.Errorf("scan player match: %w", err)
+		}
+		ms.Date, _ = time.Parse(time.RFC3339, dateStr)
+		ms.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+		ps.MatchID = ms.ID
+		ps.SteamID = steamID
+		out = append(out, pm)
+	}
+	return out, rows.Err()
+}
+
+// GetPlayerMapStats returns a player's stats summed per map over the
+// matches picked by filter, most played map first.
+func (s *sqlStore) GetPlayerMapStats(ctx context.Context, steamID string, filter PlayerFilter) ([]PlayerMapStats, error) {
+	clauses, args := playerFilterClauses(steamID, filter)
+	query := fmt.Sprintf(
+		`SELECT pm.map_name, %s
+		 FROM (SELECT %s %s WHERE %s) pm
+		 GROUP BY pm.map_name
+		 ORDER BY COUNT(*) DESC, pm.map_name`,
+		careerColumns, playerMatchColumns, playerMatchesFrom, strings.Join(clauses, " AND "),
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query map stats of player %s: %w", steamID, err)
+	}
+	defer rows.Close()
+
+	var out []PlayerMapStats
+	for rows.Next() {
+		var ms PlayerMapStats
+		dest := append([]any{&ms.MapName}, careerDest(&ms)...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan player map stats: %w", err)
+		}
+		out = append(out, ms)
+	}
+	return out, rows.Err()
+}
+
+// GetPlayerSideStats returns a player's per-round stats summed per map
+// and side over the matches picked by filter.
+func (s *sqlStore) GetPlayerSideStats(ctx context.Context, steamID string, filter PlayerFilter) ([]PlayerSideStats, error) {
+	clauses, args := playerFilterClauses(steamID, filter)
+	query := fmt.Sprintf(
+		`SELECT m.map_name, pr.side, COUNT(*),
+		        SUM(CASE WHEN r.winner_team = pr.side THEN 1 ELSE 0 END),
+		        SUM(pr.kills), SUM(pr.deaths), SUM(pr.assists), SUM(pr.damage), SUM(pr.survived), SUM(pr.kast)
+		 FROM player_rounds pr
+		 JOIN rounds r ON r.id = pr.round_id
+		 JOIN matches m ON m.id = r.match_id
+		 JOIN players p ON p.id = pr.player_id
+		 WHERE %s
+		 GROUP BY m.map_name, pr.side
+		 ORDER BY m.map_name, pr.side`,
+		strings.Join(clauses, " AND "),
+	)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query side stats of player %s: %w", steamID, err)
+	}
+	defer rows.Close()
+
+	var out []PlayerSideStats
+	for rows.Next() {
+		var ss PlayerSideStats
+		if err := rows.Scan(&ss.MapName, &ss.Side, &ss.Rounds, &ss.RoundsWon,
+			&ss.Kills, &ss.Deaths, &ss.Assists, &ss.Damage, &ss.Survived, &ss.KAST); err != nil {
+			return nil, fmt.Errorf("scan player side stats: %w", err)
+		}
+		out = append(out, ss)
+	}
+	return out, rows.Err()
+}
+
+// sharedMatchesFrom joins the match_players rows, aliased mpa and mpb, of
+// two players, aliased pa and pb, in the matches m both played.
+const sharedMatchesFrom = `FROM match_players mpa
+	 JOIN players pa ON pa.id = mpa.player_id
+	 JOIN match_players mpb ON mpb.match_id = mpa.match_id
+	 JOIN players pb ON pb.id = mpb.player_id
+	 JOIN matches m ON m.id = mpa.match_id
+	 WHERE pa.steam_id = ? AND pb.steam_id = ?`
+
+// GetPlayerHeadToHead compares two players over the matches both played.
+// Kills of a teammate do not count as kills of one by the other.
+func (s *sqlStore) GetPlayerHeadToHead(ctx context.Context, steamA, steamB string) (PlayerHeadToHead, error) {
+	var h PlayerHeadToHead
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+		        COALESCE(SUM(CASE WHEN mpa.team <> mpb.team THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN mpa.team <> mpb.team AND
+		                       (CASE WHEN mpa.team = COALESCE(m.team_a_started_as, 'CT') THEN m.score_a - m.score_b
+		                             ELSE m.score_b - m.score_a END) > 0 THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN mpa.team <> mpb.team AND
+		                       (CASE WHEN mpa.team = COALESCE(m.team_a_started_as, 'CT') THEN m.score_a - m.score_b
+		                             ELSE m.score_b - m.score_a END) < 0 THEN 1 ELSE 0 END), 0)
+		 `+sharedMatchesFrom,
+		steamA, steamB,
+	).Scan(&h.Matches, &h.Opposed, &h.WinsA, &h.WinsB)
+	if err != nil {
+		return PlayerHeadToHead{}, fmt.Errorf("query matches of %s and %s: %w", steamA, steamB, err)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT pm.steam_id, %s
+		 FROM (SELECT p.steam_id, %s %s
+		       WHERE p.steam_id IN (?, ?) AND mp.match_id IN (SELECT mpa.match_id %s)) pm
+		 GROUP BY pm.steam_id`,
+		careerColumns, playerMatchColumns, playerMatchesFrom, sharedMatchesFrom,
+	)
+	rows, err := s.db.QueryContext(ctx, query, steamA, steamB, steamA, steamB)
+	if err != nil {
+		return PlayerHeadToHead{}, fmt.Errorf("query stats of %s and %s: %w", steamA, steamB, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			steamID string
+			ms      PlayerMapStats
+		)
+		if err := rows.Scan(append([]any{&steamID}, careerDest(&ms)...)...); err != nil {
+			return PlayerHeadToHead{}, fmt.Errorf("scan head to head stats: %w", err)
+		}
+		if steamID == steamA {
+			h.StatsA = ms
+		} else {
+			h.StatsB = ms
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return PlayerHeadToHead{}, err
+	}
+
+	err = s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(CASE WHEN pa.steam_id = ? THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN pa.steam_id = ? THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN pa.steam_id = ? THEN k.headshot ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN pa.steam_id = ? THEN k.headshot ELSE 0 END), 0)
+		 FROM kill_events k
+		 JOIN rounds r ON r.id = k.round_id
+		 JOIN players pa ON pa.id = k.attacker_id
+		 JOIN players pv ON pv.id = k.victim_id
+		 JOIN match_players mpa ON mpa.match_id = r.match_id AND mpa.player_id = k.attacker_id
+		 JOIN match_players mpv ON mpv.match_id = r.match_id AND mpv.player_id = k.victim_id
+		 WHERE ((pa.steam_id = ? AND pv.steam_id = ?) OR (pa.steam_id = ? AND pv.steam_id = ?))
+		   AND mpa.team <> mpv.team`,
+		steamA, steamB, steamA, steamB,
+		steamA, steamB, steamB, steamA,
+	).Scan(&h.KillsA, &h.KillsB, &h.HeadshotKillsA, &h.HeadshotKillsB)
+	if err != nil {
+		return PlayerHeadToHead{}, fmt.Errorf("query duels of %s and %s: %w", steamA, steamB, err)
+	}
+	return h, nil
+}
+
+// playerFilterClauses returns the WHERE clauses selecting a player's
+// matches, over tables aliased p for players and m for matches. Without a
+// steam ID the clauses select every player's matches.
+func playerFilterClauses(steamID string, filter PlayerFilter) ([]string, []any) {
+	var (
+		clauses []string
+		args    []any
+	)
+	if steamID != "" {
+		clauses = append(clauses, "p.steam_id = ?")
+		args = append(args, steamID)
+	}
+	if filter.MapName != "" {
+		clauses = append(clauses, "m.map_name = ?")
+		args = append(args, filter.MapName)
+	}
+	if !filter.DateFrom.IsZero() {
+		clauses = append(clauses, "m.date >= ?")
+		args = append(args, filter.DateFrom.Format(time.RFC3339))
+	}
+	if !filter.DateTo.IsZero() {
+		clauses = append(clauses, "m.date <= ?")
+		args = append(args, filter.DateTo.Format(time.RFC3339))
+	}
+	return clauses, args
+}
+
+// careerDest returns the scan destinations for careerColumns.
+func careerDest(ms *PlayerMapStats) []any {
+	return []any{&ms.Matches, &ms.Wins, &ms.Losses,
+		&ms.RoundsPlayed, &ms.Kills, &ms.Deaths, &ms.Assists, &ms.Headshots, &ms.TotalDamage,
+		&ms.FirstKills, &ms.FirstDeaths, &ms.TradeKills, &ms.FlashAssists, &ms.UtilityDamage,
+		&ms.TwoK, &ms.ThreeK, &ms.FourK, &ms.FiveK,
+		&ms.ADR, &ms.KAST, &ms.HeadshotPct, &ms.Rating, &ms.KPR, &ms.DPR, &ms.Impact}
+}
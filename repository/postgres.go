@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Postgres implements Repository backed by a PostgreSQL database, for a
+// shared instance with many concurrent writers.
+type Postgres struct {
+	*sqlStore
+}
+
+// postgresMigrations lists the PostgreSQL schema changes in order. They
+// are numbered independently of the SQLite migrations.
+var postgresMigrations = []migration{
+	{1, "migrations/postgres/001_initial.sql"},
+	{2, "migrations/postgres/002_round_duration.sql"},
+	{3, "migrations/postgres/003_teams.sql"},
+}
+
+var postgresDialect = dialect{
+	bind: bindPostgres,
+	isDuplicateHash: func(err error) bool {
+		var pgErr *pgconn.PgError
+		return errors.As(err, &pgErr) && pgErr.Code == "23505" && strings.Contains(pgErr.ConstraintName, "demo_hash")
+	},
+	lockMigrations: `SELECT pg_advisory_xact_lock(hashtext('cs2stats schema_version'))`,
+	skipLocked:     ` FOR UPDATE SKIP LOCKED`,
+}
+
+// NewPostgres connects to the PostgreSQL database at a postgres:// URL and
+// runs migrations.
+func NewPostgres(dsn string) (*Postgres, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	s, err := newSQLStore(db, postgresDialect, postgresMigrations)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Postgres{s}, nil
+}
+
+// bindPostgres numbers the ? placeholders of a query as $1, $2 and so on,
+// leaving question marks inside string literals alone.
+func bindPostgres(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var (
+		b      strings.Builder
+		n      int
+		quoted bool
+	)
+	b.Grow(len(query) + 8)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
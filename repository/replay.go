@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Replay blobs are delta-encoded and deflated. Layout before compression:
+//
+//	version byte
+//	player table: uvarint count, then uvarint-length-prefixed steam IDs
+//	weapon table: uvarint count, then uvarint-length-prefixed names
+//	uvarint frame count, then per frame:
+//	    varint tick delta, uvarint sample count, then per sample:
+//	        uvarint player index, uvarint weapon index,
+//	        varint deltas of x, y, z, yaw, pitch, health, armor, money
+//
+// Deltas are taken against the same player's previous sample, so a
+// player standing still costs a handful of bytes per frame. Positions are
+// quantized to 1/16 unit and view angles to 1/10 degree.
+const replayVersion = 1
+
+const (
+	positionScale = 16
+	angleScale    = 10
+)
+
+// replayState holds the last quantized values seen for one player.
+type replayState struct {
+	x, y, z, yaw, pitch, health, armor, money int64
+}
+
+func encodeReplay(frames []ReplayFrame) ([]byte, error) {
+	var (
+		playerIdx = make(map[string]uint64)
+		players   []string
+		weaponIdx = make(map[string]uint64)
+		weapons   []string
+	)
+	for _, f := range frames {
+		for _, p := range f.Players {
+			if _, ok := playerIdx[p.SteamID]; !ok {
+				playerIdx[p.SteamID] = uint64(len(players))
+				players = append(players, p.SteamID)
+			}
+			if _, ok := weaponIdx[p.Weapon]; !ok {
+				weaponIdx[p.Weapon] = uint64(len(weapons))
+				weapons = append(weapons, p.Weapon)
+			}
+		}
+	}
+
+	buf := []byte{replayVersion}
+	buf = appendStrings(buf, players)
+	buf = appendStrings(buf, weapons)
+	buf = binary.AppendUvarint(buf, uint64(len(frames)))
+
+	prev := make([]replayState, len(players))
+	var prevTick int64
+	for _, f := range frames {
+		buf = binary.AppendVarint(buf, int64(f.Tick)-prevTick)
+		prevTick = int64(f.Tick)
+		buf = binary.AppendUvarint(buf, uint64(len(f.Players)))
+
+		for _, p := range f.Players {
+			idx := playerIdx[p.SteamID]
+			buf = binary.AppendUvarint(buf, idx)
+			buf = binary.AppendUvarint(buf, weaponIdx[p.Weapon])
+
+			cur := replayState{
+				x:      quantize(p.X, positionScale),
+				y:      quantize(p.Y, positionScale),
+				z:      quantize(p.Z, positionScale),
+				yaw:    quantize(p.Yaw, angleScale),
+				pitch:  quantize(p.Pitch, angleScale),
+				health: int64(p.Health),
+				armor:  int64(p.Armor),
+				money:  int64(p.Money),
+			}
+			last := prev[idx]
+			for _, d := range [...]int64{
+				cur.x - last.x, cur.y - last.y, cur.z - last.z,
+				cur.yaw - last.yaw, cur.pitch - last.pitch,
+				cur.health - last.health, cur.armor - last.armor, cur.money - last.money,
+			} {
+				buf = binary.AppendVarint(buf, d)
+			}
+			prev[idx] = cur
+		}
+	}
+
+	var out bytes.Buffer
+	w, err := flate.NewWriter(&out, flate.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("create deflate writer: %w", err)
+	}
+	if _, err := w.Write(buf); err != nil {
+		return nil, fmt.Errorf("compress replay: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("compress replay: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func decodeReplay(data []byte) ([]ReplayFrame, error) {
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decompress replay: %w", err)
+	}
+	r := bytes.NewReader(raw)
+
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read replay version: %w", err)
+	}
+	if version != replayVersion {
+		return nil, fmt.Errorf("unsupported replay version %d", version)
+	}
+
+	players, err := readStrings(r)
+	if err != nil {
+		return nil, fmt.Errorf("read player table: %w", err)
+	}
+	weapons, err := readStrings(r)
+	if err != nil {
+		return nil, fmt.Errorf("read weapon table: %w", err)
+	}
+	nFrames, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("read frame count: %w", err)
+	}
+
+	prev := make([]replayState, len(players))
+	frames := make([]ReplayFrame, 0, min(nFrames, uint64(r.Len())))
+	var tick int64
+	for i := uint64(0); i < nFrames; i++ {
+		dt, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("read frame %d tick: %w", i, err)
+		}
+		tick += dt
+		nSamples, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("read frame %d sample count: %w", i, err)
+		}
+
+		f := ReplayFrame{Tick: int(tick), Players: make([]ReplaySample, 0, min(nSamples, uint64(r.Len())))}
+		for j := uint64(0); j < nSamples; j++ {
+			idx, err := binary.ReadUvarint(r)
+			if err != nil || idx >= uint64(len(players)) {
+				return nil, fmt.Errorf("read frame %d player index: invalid", i)
+			}
+			widx, err := binary.ReadUvarint(r)
+			if err != nil || widx >= uint64(len(weapons)) {
+				return nil, fmt.Errorf("read frame %d weapon index: invalid", i)
+			}
+
+			var d [8]int64
+			for k := range d {
+				if d[k], err = binary.ReadVarint(r); err != nil {
+					return nil, fmt.Errorf("read frame %d sample: %w", i, err)
+				}
+			}
+			st := &prev[idx]
+			st.x += d[0]
+			st.y += d[1]
+			st.z += d[2]
+			st.yaw += d[3]
+			st.pitch += d[4]
+			st.health += d[5]
+			st.armor += d[6]
+			st.money += d[7]
+
+			f.Players = append(f.Players, ReplaySample{
+				SteamID: players[idx],
+				X:       float64(st.x) / positionScale,
+				Y:       float64(st.y) / positionScale,
+				Z:       float64(st.z) / positionScale,
+				Yaw:     float64(st.yaw) / angleScale,
+				Pitch:   float64(st.pitch) / angleScale,
+				Health:  int(st.health),
+				Armor:   int(st.armor),
+				Weapon:  weapons[widx],
+				Money:   int(st.money),
+			})
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
+
+func quantize(v float64, scale int64) int64 {
+	return int64(math.Round(v * float64(scale)))
+}
+
+func appendStrings(buf []byte, ss []string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(ss)))
+	for _, s := range ss {
+		buf = binary.AppendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	}
+	return buf
+}
+
+func readStrings(r *bytes.Reader) ([]string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, fmt.Errorf("string table length %d exceeds data", n)
+	}
+	out := make([]string, 0, n)
+	for i := uint64(0); i < n; i++ {
+		l, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if l > uint64(r.Len()) {
+			return nil, fmt.Errorf("string length %d exceeds data", l)
+		}
+		b := make([]byte, l)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		out = append(out, string(b))
+	}
+	return out, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Repository defines the data access interface for cs2stats.
+type Repository interface {
+	StoreMatch(ctx context.Context, m Match) (string, error)
+	GetMatch(ctx context.Context, id string) (Match, error)
+	ListMatches(ctx context.Context, filter MatchFilter) ([]MatchSummary, error)
+	GetPlayerStats(ctx context.Context, matchID string) ([]PlayerStats, error)
+	GetRounds(ctx context.Context, matchID string) ([]Round, error)
+	GetEconomy(ctx context.Context, matchID string) ([]EconomyRound, error)
+	GetKillPositions(ctx context.Context, matchID string) ([]KillEvent, error)
+	GetDamageEvents(ctx context.Context, matchID string) ([]DamageEvent, error)
+	GetRoundReplay(ctx context.Context, matchID string, roundNumber int) ([]ReplayFrame, error)
+	GetPlayerRounds(ctx context.Context, matchID, steamID string) ([]PlayerRound, error)
+	FindMatchByHash(ctx context.Context, demoHash string) (string, error)
+	ReplaceMatch(ctx context.Context, m Match) error
+	DeleteMatch(ctx context.Context, matchID string) error
+	ListMatchIDs(ctx context.Context, belowParserVersion int) ([]string, error)
+
+	GetPlayer(ctx context.Context, steamID string) (Player, error)
+	ListPlayerMatches(ctx context.Context, steamID string, filter PlayerFilter) ([]PlayerMatch, error)
+	GetPlayerMapStats(ctx context.Context, steamID string, filter PlayerFilter) ([]PlayerMapStats, error)
+	GetPlayerSideStats(ctx context.Context, steamID string, filter PlayerFilter) ([]PlayerSideStats, error)
+	GetLeaderboard(ctx context.Context, filter LeaderboardFilter) ([]LeaderboardEntry, error)
+	GetPlayerHeadToHead(ctx context.Context, steamA, steamB string) (PlayerHeadToHead, error)
+	GetMapStats(ctx context.Context, filter MapStatsFilter) ([]MapStats, error)
+
+	GetTeam(ctx context.Context, id string) (Team, error)
+	ListTeams(ctx context.Context, filter TeamFilter) ([]Team, error)
+	ListTeamHeadToHead(ctx context.Context, teamA, teamB string) ([]MatchSummary, error)
+
+	CreateIngestJob(ctx context.Context, job IngestJob) error
+	ClaimIngestJob(ctx context.Context) (IngestJob, error)
+	UpdateIngestJob(ctx context.Context, job IngestJob) error
+	RequeueIngestJobs(ctx context.Context) (int, error)
+	GetIngestJob(ctx context.Context, id string) (IngestJob, error)
+	ListIngestJobs(ctx context.Context, filter IngestJobFilter) ([]IngestJob, error)
+
+	Close() error
+}
+
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = fmt.Errorf("not found")
+
+// ErrDuplicateDemo is returned when a demo with the same hash already exists.
+var ErrDuplicateDemo = fmt.Errorf("duplicate demo")
+
+// Open opens the repository a DSN points at: a PostgreSQL database for a
+// postgres:// or postgresql:// URL, and otherwise a SQLite database file.
+func Open(dsn string) (Repository, error) {
+	if isPostgresDSN(dsn) {
+		return NewPostgres(dsn)
+	}
+	return New(dsn)
+}
+
+// RedactDSN returns dsn with any password replaced, for logging.
+func RedactDSN(dsn string) string {
+	if !isPostgresDSN(dsn) {
+		return dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "postgres://"
+	}
+	return u.Redacted()
+}
+
+func isPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
@@ -0,0 +1,946 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrations embed.FS
+
+// sqlStore implements Repository over database/sql. Queries are written
+// once, with ? placeholders, and run unchanged on every database through
+// its dialect. SQLite and Postgres wrap it with their own connection setup
+// and migrations.
+type sqlStore struct {
+	db      *boundDB
+	dialect dialect
+}
+
+// dialect describes what differs between the databases a sqlStore runs on.
+type dialect struct {
+	// bind rewrites the ? placeholders of a query into the database's own.
+	bind func(query string) string
+
+	// isDuplicateHash reports whether err is a unique constraint violation
+	// on matches.demo_hash.
+	isDuplicateHash func(err error) bool
+
+	// lockMigrations, if set, is run at the start of the migration
+	// transaction so concurrent processes migrate one at a time.
+	lockMigrations string
+
+	// skipLocked, if set, is appended to the query that picks the next
+	// queued ingest job, so concurrent claims pass over a job another
+	// transaction is claiming instead of waiting for it.
+	skipLocked string
+}
+
+// migration is a schema change applied once, in version order.
+type migration struct {
+	version int
+	file    string
+}
+
+// newSQLStore wraps an open database and applies the migrations it has
+// not seen yet.
+func newSQLStore(db *sql.DB, d dialect, all []migration) (*sqlStore, error) {
+	s := &sqlStore{db: &boundDB{db: db, bind: d.bind}, dialect: d}
+	if err := s.migrate(all); err != nil {
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+	return s, nil
+}
+
+// Close closes the underlying database connection.
+func (s *sqlStore) Close() error {
+	return s.db.db.Close()
+}
+
+// migrate applies the migrations newer than the recorded schema version in
+// a single transaction.
+func (s *sqlStore) migrate(all []migration) error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if s.dialect.lockMigrations != "" {
+		if _, err := tx.ExecContext(ctx, s.dialect.lockMigrations); err != nil {
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+	}
+
+	// create version tracking table
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("create schema_version table: %w", err)
+	}
+
+	var current int
+	row := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`)
+	if err := row.Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	for _, m := range all {
+		if m.version <= current {
+			continue
+		}
+		data, err := migrations.ReadFile(m.file)
+		if err != nil {
+			return fmt.Errorf("read migration %s: %w", m.file, err)
+		}
+		// migration files hold no parameters, so they skip bind
+		_, err = tx.tx.ExecContext(ctx, string(data))
+		if err != nil {
+			return fmt.Errorf("execute migration %s: %w", m.file, err)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_version (version) VALUES (?)`, m.version)
+		if err != nil {
+			return fmt.Errorf("record migration version %d: %w", m.version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migrations: %w", err)
+	}
+	return nil
+}
+
+// boundDB is a database handle that binds the placeholders of every query
+// for its dialect.
+type boundDB struct {
+	db   *sql.DB
+	bind func(query string) string
+}
+
+func (c *boundDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.db.ExecContext(ctx, c.bind(query), args...)
+}
+
+func (c *boundDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.db.QueryContext(ctx, c.bind(query), args...)
+}
+
+func (c *boundDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.db.QueryRowContext(ctx, c.bind(query), args...)
+}
+
+func (c *boundDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*boundTx, error) {
+	tx, err := c.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &boundTx{tx: tx, bind: c.bind}, nil
+}
+
+// boundTx is a transaction that binds placeholders like boundDB.
+type boundTx struct {
+	tx   *sql.Tx
+	bind func(query string) string
+}
+
+func (t *boundTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, t.bind(query), args...)
+}
+
+func (t *boundTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.tx.QueryRowContext(ctx, t.bind(query), args...)
+}
+
+func (t *boundTx) Commit() error   { return t.tx.Commit() }
+func (t *boundTx) Rollback() error { return t.tx.Rollback() }
+
+// StoreMatch stores a new match with all its data in a single
+// transaction. Each side with a TeamAID or TeamBID is linked to a team:
+// the team with the side's clan name or, without one, the team most of
+// the side's players have played for, and otherwise a new team stored
+// under the given ID. It returns ErrDuplicateDemo if a match with the
+// same demo hash is stored.
+func (s *sqlStore) StoreMatch(ctx context.Context, m Match) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	teamA, teamB, err := resolveTeams(ctx, tx, m)
+	if err != nil {
+		return "", err
+	}
+
+	// insert match
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO matches (id, map_name, date, duration_seconds, team_a, team_b, score_a, score_b, demo_hash, team_a_started_as, parser_version, created_at,
+		 team_a_id, team_b_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.ID, m.MapName, m.Date.Format(time.RFC3339), m.DurationSeconds,
+		m.TeamA, m.TeamB, m.ScoreA, m.ScoreB, m.DemoHash, m.TeamAStartedAs, m.ParserVersion, m.CreatedAt.Format(time.RFC3339Nano),
+		nullString(teamA), nullString(teamB),
+	)
+	if err != nil {
+		if s.dialect.isDuplicateHash(err) {
+			return "", ErrDuplicateDemo
+		}
+		return "", fmt.Errorf("insert match: %w", err)
+	}
+
+	if err := insertMatchData(ctx, tx, m); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return m.ID, nil
+}
+
+// ReplaceMatch replaces the parsed data of a stored match with m in a
+// single transaction: the match row is updated in place and its players,
+// rounds, kills, economy and other per-round rows are deleted and inserted
+// again. The match keeps its ID, demo hash and creation time, its sides
+// are linked to teams as by StoreMatch, and players and teams left without
+// any match are deleted. It returns ErrNotFound if no match has m.ID.
+func (s *sqlStore) ReplaceMatch(ctx context.Context, m Match) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// resolved before the old rows go, so a side keeps its team when its
+	// roster is unchanged
+	teamA, teamB, err := resolveTeams(ctx, tx, m)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE matches SET map_name = ?, date = ?, duration_seconds = ?, team_a = ?, team_b = ?,
+		 score_a = ?, score_b = ?, team_a_started_as = ?, parser_version = ?, team_a_id = ?, team_b_id = ?
+		 WHERE id = ?`,
+		m.MapName, m.Date.Format(time.RFC3339), m.DurationSeconds, m.TeamA, m.TeamB,
+		m.ScoreA, m.ScoreB, m.TeamAStartedAs, m.ParserVersion, nullString(teamA), nullString(teamB),
+		m.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update match %s: %w", m.ID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update match %s: %w", m.ID, err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	if err := deleteMatchData(ctx, tx, m.ID); err != nil {
+		return err
+	}
+	if err := insertMatchData(ctx, tx, m); err != nil {
+		return err
+	}
+	if err := prunePlayers(ctx, tx); err != nil {
+		return err
+	}
+	if err := pruneTeams(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteMatch deletes a match with its players, rounds, kills, economy and
+// other per-round rows in a single transaction, then deletes players and
+// teams left without any match. It returns ErrNotFound if no match has matchID.
+func (s *sqlStore) DeleteMatch(ctx context.Context, matchID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteMatchData(ctx, tx, matchID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM matches WHERE id = ?`, matchID)
+	if err != nil {
+		return fmt.Errorf("delete match %s: %w", matchID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete match %s: %w", matchID, err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	if err := prunePlayers(ctx, tx); err != nil {
+		return err
+	}
+	if err := pruneTeams(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListMatchIDs returns the IDs of all matches, oldest first. If
+// belowParserVersion is positive only matches produced by an older parser
+// version are returned.
+func (s *sqlStore) ListMatchIDs(ctx context.Context, belowParserVersion int) ([]string, error) {
+	query := `SELECT id FROM matches ORDER BY created_at, id`
+	var args []any
+	if belowParserVersion > 0 {
+		query = `SELECT id FROM matches WHERE parser_version < ? ORDER BY created_at, id`
+		args = append(args, belowParserVersion)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list match IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan match ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// matchRoundTables lists the tables keyed by round, children first.
+var matchRoundTables = []string{
+	"round_replays", "player_rounds", "damage_events", "kill_events", "economy_rounds", "clutches",
+}
+
+// deleteMatchData deletes every row that belongs to a match except the
+// match row itself.
+func deleteMatchData(ctx context.Context, tx *boundTx, matchID string) error {
+	for _, table := range matchRoundTables {
+		_, err := tx.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE round_id IN (SELECT id FROM rounds WHERE match_id = ?)`, matchID,
+		)
+		if err != nil {
+			return fmt.Errorf("delete %s for match %s: %w", table, matchID, err)
+		}
+	}
+	for _, table := range []string{"rounds", "match_players"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE match_id = ?`, matchID); err != nil {
+			return fmt.Errorf("delete %s for match %s: %w", table, matchID, err)
+		}
+	}
+	return nil
+}
+
+// prunePlayers deletes players who no longer appear in any match.
+func prunePlayers(ctx context.Context, tx *boundTx) error {
+	_, err := tx.ExecContext(ctx,
+		`DELETE FROM players WHERE id NOT IN (SELECT player_id FROM match_players)
+		 AND id NOT IN (SELECT player_id FROM clutches)
+		 AND id NOT IN (SELECT player_id FROM player_rounds)
+		 AND id NOT IN (SELECT attacker_id FROM kill_events WHERE attacker_id IS NOT NULL)
+		 AND id NOT IN (SELECT victim_id FROM kill_events WHERE victim_id IS NOT NULL)
+		 AND id NOT IN (SELECT attacker_id FROM damage_events WHERE attacker_id IS NOT NULL)
+		 AND id NOT IN (SELECT victim_id FROM damage_events WHERE victim_id IS NOT NULL)`,
+	)
+	if err != nil {
+		return fmt.Errorf("prune players: %w", err)
+	}
+	return nil
+}
+
+// insertMatchData inserts the players, rounds and per-round rows of a
+// match whose row is already stored.
+func insertMatchData(ctx context.Context, tx *boundTx, m Match) error {
+	var err error
+	// upsert players and insert match_players, in steam ID order so that
+	// concurrent writers sharing players lock them in the same order
+	players := slices.Clone(m.Players)
+	slices.SortStableFunc(players, func(a, b PlayerStats) int { return strings.Compare(a.SteamID, b.SteamID) })
+	playerIDs := make(map[string]string, len(players))
+	for _, ps := range players {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO players (id, steam_id, name) VALUES (?, ?, ?)
+			 ON CONFLICT(steam_id) DO UPDATE SET name = excluded.name`,
+			ps.PlayerID, ps.SteamID, ps.Name,
+		)
+		if err != nil {
+			return fmt.Errorf("upsert player %s: %w", ps.SteamID, err)
+		}
+
+		// resolve the actual player ID (may differ from ps.PlayerID on conflict)
+		var playerID string
+		err = tx.QueryRowContext(ctx,
+			`SELECT id FROM players WHERE steam_id = ?`, ps.SteamID,
+		).Scan(&playerID)
+		if err != nil {
+			return fmt.Errorf("resolve player ID for %s: %w", ps.SteamID, err)
+		}
+		playerIDs[ps.PlayerID] = playerID
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO match_players (match_id, player_id, team, kills, deaths, assists, adr, kast, hs_pct, rating, flash_assists, utility_damage,
+			 enemies_flashed, teammates_flashed, enemy_blind_duration, avg_enemy_blind_duration, flashes_leading_to_kill,
+			 kpr, dpr, impact,
+			 headshots, total_damage, survived, first_kills, first_deaths, trade_kills,
+			 two_k, three_k, four_k, five_k)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.ID, playerID, ps.Team, ps.Kills, ps.Deaths, ps.Assists,
+			ps.ADR, ps.KAST, ps.HeadshotPct, ps.Rating, ps.FlashAssists, ps.UtilityDamage,
+			ps.EnemiesFlashed, ps.TeammatesFlashed, ps.EnemyBlindDuration, ps.AvgEnemyBlindDuration, ps.FlashesLeadingToKill,
+			ps.KPR, ps.DPR, ps.Impact,
+			ps.Headshots, ps.TotalDamage, ps.Survived, ps.FirstKills, ps.FirstDeaths, ps.TradeKills,
+			ps.TwoK, ps.ThreeK, ps.FourK, ps.FiveK,
+		)
+		if err != nil {
+			return fmt.Errorf("insert match_player %s: %w", ps.SteamID, err)
+		}
+	}
+
+	// rows below reference players by the ID they were mapped with, which
+	// is replaced by the stored ID for players seen in earlier matches
+	resolve := func(id string) string {
+		if stored, ok := playerIDs[id]; ok {
+			return stored
+		}
+		return id
+	}
+
+	// insert rounds, clutches, economy
+	for _, r := range m.Rounds {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO rounds (id, match_id, number, winner_team, win_method,
+			 first_kill_player_id, first_death_player_id,
+			 first_kill_steam_id, first_death_steam_id, first_kill_weapon, first_kill_round_time,
+			 bomb_plant_steam_id, bomb_plant_site, bomb_plant_round_time,
+			 bomb_defuse_steam_id, bomb_defuse_round_time, team_a_side, team_a_won, duration_seconds)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.ID, m.ID, r.Number, r.WinnerTeam, r.WinMethod,
+			nullString(resolve(r.FirstKillPlayerID)), nullString(resolve(r.FirstDeathPlayerID)),
+			nullString(r.FirstKillSteamID), nullString(r.FirstDeathSteamID),
+			nullString(r.FirstKillWeapon), nullFloat(r.FirstKillRoundTime),
+			nullString(r.BombPlantSteamID), nullString(r.BombPlantSite), nullFloat(r.BombPlantRoundTime),
+			nullString(r.BombDefuseSteamID), nullFloat(r.BombDefuseRoundTime),
+			r.TeamASide, boolToInt(r.TeamAWon), r.DurationSeconds,
+		)
+		if err != nil {
+			return fmt.Errorf("insert round %d: %w", r.Number, err)
+		}
+
+		if r.Clutch != nil {
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO clutches (round_id, player_id, player_steam_id, opponents, success) VALUES (?, ?, ?, ?, ?)`,
+				r.ID, resolve(r.Clutch.PlayerID), nullString(r.Clutch.PlayerSteamID), r.Clutch.Opponents, boolToInt(r.Clutch.Success),
+			)
+			if err != nil {
+				return fmt.Errorf("insert clutch round %d: %w", r.Number, err)
+			}
+		}
+	}
+
+	// insert economy rounds
+	for _, e := range m.Economy {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO economy_rounds (round_id, team, spend, equipment_value, buy_type) VALUES (?, ?, ?, ?, ?)`,
+			e.RoundID, e.Team, e.Spend, e.EquipmentValue, e.BuyType,
+		)
+		if err != nil {
+			return fmt.Errorf("insert economy round %s/%s: %w", e.RoundID, e.Team, err)
+		}
+	}
+
+	// insert kill events
+	for _, ke := range m.KillEvents {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO kill_events (id, round_id, attacker_id, victim_id, attacker_steam_id, victim_steam_id, weapon, headshot, attacker_x, attacker_y, attacker_z, victim_x, victim_y, victim_z)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ke.ID, ke.RoundID, nullString(resolve(ke.Attacker)), nullString(resolve(ke.Victim)),
+			nullString(ke.AttackerSteamID), nullString(ke.VictimSteamID),
+			ke.Weapon, boolToInt(ke.Headshot),
+			ke.AttackerX, ke.AttackerY, ke.AttackerZ,
+			ke.VictimX, ke.VictimY, ke.VictimZ,
+		)
+		if err != nil {
+			return fmt.Errorf("insert kill event: %w", err)
+		}
+	}
+
+	// insert damage events
+	for _, de := range m.DamageEvents {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO damage_events (id, round_id, tick, attacker_id, victim_id, attacker_steam_id, victim_steam_id,
+			 weapon, hit_group, health_damage, armor_damage,
+			 attacker_x, attacker_y, attacker_z, victim_x, victim_y, victim_z, aggregate)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			de.ID, de.RoundID, de.Tick, nullString(resolve(de.Attacker)), nullString(resolve(de.Victim)),
+			nullString(de.AttackerSteamID), nullString(de.VictimSteamID),
+			de.Weapon, de.HitGroup, de.HealthDamage, de.ArmorDamage,
+			de.AttackerX, de.AttackerY, de.AttackerZ,
+			de.VictimX, de.VictimY, de.VictimZ, boolToInt(de.Aggregate),
+		)
+		if err != nil {
+			return fmt.Errorf("insert damage event: %w", err)
+		}
+	}
+
+	// insert per-player round rows; the player ID is resolved by steam ID
+	// since the player may already exist under another ID
+	for _, pr := range m.PlayerRounds {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO player_rounds (round_id, player_id, side, kills, deaths, assists, damage,
+			 survived, traded, kast, equipment_value, money_spent)
+			 VALUES (?, (SELECT id FROM players WHERE steam_id = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			pr.RoundID, pr.SteamID, pr.Side, pr.Kills, pr.Deaths, pr.Assists, pr.Damage,
+			boolToInt(pr.Survived), boolToInt(pr.Traded), boolToInt(pr.KAST),
+			pr.EquipmentValue, pr.MoneySpent,
+		)
+		if err != nil {
+			return fmt.Errorf("insert player round %s/%s: %w", pr.RoundID, pr.SteamID, err)
+		}
+	}
+
+	// insert round replays
+	for _, rr := range m.Replays {
+		if len(rr.Frames) == 0 {
+			continue
+		}
+		data, err := encodeReplay(rr.Frames)
+		if err != nil {
+			return fmt.Errorf("encode replay for round %s: %w", rr.RoundID, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO round_replays (round_id, frame_count, data) VALUES (?, ?, ?)`,
+			rr.RoundID, len(rr.Frames), data,
+		)
+		if err != nil {
+			return fmt.Errorf("insert replay for round %s: %w", rr.RoundID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *sqlStore) GetMatch(ctx context.Context, id string) (Match, error) {
+	var m Match
+	var dateStr, createdStr string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT m.id, m.map_name, m.date, m.duration_seconds, m.team_a, m.team_b, m.score_a, m.score_b, m.demo_hash,
+		        COALESCE(m.team_a_started_as, 'CT'), m.parser_version, m.created_at,
+		        COALESCE(m.team_a_id, ''), COALESCE(m.team_b_id, ''), COALESCE(ta.clan_name, ''), COALESCE(tb.clan_name, '')
+		 FROM matches m
+		 LEFT JOIN teams ta ON ta.id = m.team_a_id
+		 LEFT JOIN teams tb ON tb.id = m.team_b_id
+		 WHERE m.id = ?`, id,
+	).Scan(&m.ID, &m.MapName, &dateStr, &m.DurationSeconds, &m.TeamA, &m.TeamB,
+		&m.ScoreA, &m.ScoreB, &m.DemoHash, &m.TeamAStartedAs, &m.ParserVersion, &createdStr,
+		&m.TeamAID, &m.TeamBID, &m.TeamAClanName, &m.TeamBClanName)
+	if err == sql.ErrNoRows {
+		return Match{}, ErrNotFound
+	}
+	if err != nil {
+		return Match{}, fmt.Errorf("query match %s: %w", id, err)
+	}
+
+	m.Date, _ = time.Parse(time.RFC3339, dateStr)
+	m.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+
+	return m, nil
+}
+
+func (s *sqlStore) ListMatches(ctx context.Context, filter MatchFilter) ([]MatchSummary, error) {
+	var (
+		clauses []string
+		args    []any
+	)
+
+	if filter.MapName != "" {
+		clauses = append(clauses, "m.map_name = ?")
+		args = append(args, filter.MapName)
+	}
+	if !filter.DateFrom.IsZero() {
+		clauses = append(clauses, "m.date >= ?")
+		args = append(args, filter.DateFrom.Format(time.RFC3339))
+	}
+	if !filter.DateTo.IsZero() {
+		clauses = append(clauses, "m.date <= ?")
+		args = append(args, filter.DateTo.Format(time.RFC3339))
+	}
+	if filter.PlayerSteam != "" {
+		clauses = append(clauses, `EXISTS (
+			SELECT 1 FROM match_players mp
+			JOIN players p ON p.id = mp.player_id
+			WHERE mp.match_id = m.id AND p.steam_id = ?
+		)`)
+		args = append(args, filter.PlayerSteam)
+	}
+	if filter.TeamID != "" {
+		clauses = append(clauses, "(m.team_a_id = ? OR m.team_b_id = ?)")
+		args = append(args, filter.TeamID, filter.TeamID)
+	}
+
+	// cursor-based pagination: older items (created_at < cursor OR same time with id < cursor)
+	if !filter.CursorTime.IsZero() && filter.CursorID != "" {
+		clauses = append(clauses, "(m.created_at < ? OR (m.created_at = ? AND m.id < ?))")
+		ct := filter.CursorTime.Format(time.RFC3339Nano)
+		args = append(args, ct, ct, filter.CursorID)
+	}
+
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	query := fmt.Sprintf(
+		`SELECT %s FROM matches m %s ORDER BY m.created_at DESC, m.id DESC LIMIT ?`,
+		matchSummaryColumns, where,
+	)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list matches: %w", err)
+	}
+	defer rows.Close()
+
+	var results []MatchSummary
+	for rows.Next() {
+		ms, err := scanMatchSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan match summary: %w", err)
+		}
+		results = append(results, ms)
+	}
+	return results, rows.Err()
+}
+
+// matchSummaryColumns are the columns of matches m scanMatchSummary reads.
+const matchSummaryColumns = `m.id, m.map_name, m.date, m.duration_seconds, m.team_a, m.team_b, m.score_a, m.score_b,
+	        COALESCE(m.team_a_started_as, 'CT'), m.created_at, COALESCE(m.team_a_id, ''), COALESCE(m.team_b_id, '')`
+
+// scanMatchSummary reads a row of matchSummaryColumns.
+func scanMatchSummary(rows *sql.Rows) (MatchSummary, error) {
+	var (
+		ms                  MatchSummary
+		dateStr, createdStr string
+	)
+	if err := rows.Scan(&ms.ID, &ms.MapName, &dateStr, &ms.DurationSeconds,
+		&ms.TeamA, &ms.TeamB, &ms.ScoreA, &ms.ScoreB, &ms.TeamAStartedAs, &createdStr,
+		&ms.TeamAID, &ms.TeamBID); err != nil {
+		return MatchSummary{}, err
+	}
+	ms.Date, _ = time.Parse(time.RFC3339, dateStr)
+	ms.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdStr)
+	return ms, nil
+}
+
+func (s *sqlStore) GetPlayerStats(ctx context.Context, matchID string) ([]PlayerStats, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT mp.match_id, mp.player_id, p.steam_id, p.name, mp.team,
+		        mp.kills, mp.deaths, mp.assists, mp.adr, mp.kast, mp.hs_pct,
+		        mp.rating, mp.flash_assists, mp.utility_damage,
+		        mp.enemies_flashed, mp.teammates_flashed, mp.enemy_blind_duration,
+		        mp.avg_enemy_blind_duration, mp.flashes_leading_to_kill,
+		        mp.kpr, mp.dpr, mp.impact,
+		        mp.headshots, mp.total_damage, mp.survived, mp.first_kills, mp.first_deaths, mp.trade_kills,
+		        mp.two_k, mp.three_k, mp.four_k, mp.five_k
+		 FROM match_players mp
+		 JOIN players p ON p.id = mp.player_id
+		 WHERE mp.match_id = ?
+		 ORDER BY mp.rating DESC`, matchID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query player stats for match %s: %w", matchID, err)
+	}
+	defer rows.Close()
+
+	var stats []PlayerStats
+	for rows.Next() {
+		var ps PlayerStats
+		if err := rows.Scan(&ps.MatchID, &ps.PlayerID, &ps.SteamID, &ps.Name, &ps.Team,
+			&ps.Kills, &ps.Deaths, &ps.Assists, &ps.ADR, &ps.KAST, &ps.HeadshotPct,
+			&ps.Rating, &ps.FlashAssists, &ps.UtilityDamage,
+			&ps.EnemiesFlashed, &ps.TeammatesFlashed, &ps.EnemyBlindDuration,
+			&ps.AvgEnemyBlindDuration, &ps.FlashesLeadingToKill,
+			&ps.KPR, &ps.DPR, &ps.Impact,
+			&ps.Headshots, &ps.TotalDamage, &ps.Survived, &ps.FirstKills, &ps.FirstDeaths, &ps.TradeKills,
+			&ps.TwoK, &ps.ThreeK, &ps.FourK, &ps.FiveK); err != nil {
+			return nil, fmt.Errorf("scan player stats: %w", err)
+		}
+		stats = append(stats, ps)
+	}
+	return stats, rows.Err()
+}
+
+func (s *sqlStore) GetRounds(ctx context.Context, matchID string) ([]Round, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT r.id, r.match_id, r.number, r.winner_team, r.win_method,
+		        COALESCE(r.first_kill_player_id, ''), COALESCE(r.first_death_player_id, ''),
+		        COALESCE(r.first_kill_steam_id, ''), COALESCE(r.first_death_steam_id, ''),
+		        COALESCE(r.first_kill_weapon, ''), COALESCE(r.first_kill_round_time, 0),
+		        COALESCE(r.bomb_plant_steam_id, ''), COALESCE(r.bomb_plant_site, ''),
+		        COALESCE(r.bomb_plant_round_time, 0),
+		        COALESCE(r.bomb_defuse_steam_id, ''), COALESCE(r.bomb_defuse_round_time, 0),
+		        r.team_a_side, r.team_a_won, r.duration_seconds
+		 FROM rounds r
+		 WHERE r.match_id = ?
+		 ORDER BY r.number`, matchID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query rounds for match %s: %w", matchID, err)
+	}
+	defer rows.Close()
+
+	var rounds []Round
+	for rows.Next() {
+		var (
+			r        Round
+			teamAWon int
+		)
+		if err := rows.Scan(&r.ID, &r.MatchID, &r.Number, &r.WinnerTeam, &r.WinMethod,
+			&r.FirstKillPlayerID, &r.FirstDeathPlayerID,
+			&r.FirstKillSteamID, &r.FirstDeathSteamID,
+			&r.FirstKillWeapon, &r.FirstKillRoundTime,
+			&r.BombPlantSteamID, &r.BombPlantSite, &r.BombPlantRoundTime,
+			&r.BombDefuseSteamID, &r.BombDefuseRoundTime,
+			&r.TeamASide, &teamAWon, &r.DurationSeconds); err != nil {
+			return nil, fmt.Errorf("scan round: %w", err)
+		}
+		r.TeamAWon = teamAWon != 0
+		rounds = append(rounds, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// load clutches
+	for i := range rounds {
+		var c Clutch
+		var success int
+		err := s.db.QueryRowContext(ctx,
+			`SELECT round_id, player_id, COALESCE(player_steam_id, ''), opponents, success FROM clutches WHERE round_id = ?`,
+			rounds[i].ID,
+		).Scan(&c.RoundID, &c.PlayerID, &c.PlayerSteamID, &c.Opponents, &success)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query clutch for round %s: %w", rounds[i].ID, err)
+		}
+		c.Success = success != 0
+		rounds[i].Clutch = &c
+	}
+
+	return rounds, nil
+}
+
+func (s *sqlStore) GetEconomy(ctx context.Context, matchID string) ([]EconomyRound, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT er.round_id, r.match_id, r.number, er.team, er.spend, er.equipment_value, er.buy_type,
+		        er.team = COALESCE(NULLIF(r.team_a_side, ''), 'CT')
+		 FROM economy_rounds er
+		 JOIN rounds r ON r.id = er.round_id
+		 WHERE r.match_id = ?
+		 ORDER BY r.number, er.team`, matchID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query economy for match %s: %w", matchID, err)
+	}
+	defer rows.Close()
+
+	var econ []EconomyRound
+	for rows.Next() {
+		var e EconomyRound
+		if err := rows.Scan(&e.RoundID, &e.MatchID, &e.RoundNumber, &e.Team,
+			&e.Spend, &e.EquipmentValue, &e.BuyType, &e.TeamA); err != nil {
+			return nil, fmt.Errorf("scan economy round: %w", err)
+		}
+		econ = append(econ, e)
+	}
+	return econ, rows.Err()
+}
+
+func (s *sqlStore) GetKillPositions(ctx context.Context, matchID string) ([]KillEvent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ke.id, ke.round_id, r.match_id, r.number,
+		        COALESCE(ke.attacker_id, ''), COALESCE(ke.victim_id, ''),
+		        COALESCE(ke.attacker_steam_id, ''), COALESCE(ke.victim_steam_id, ''),
+		        ke.weapon, ke.headshot,
+		        ke.attacker_x, ke.attacker_y, ke.attacker_z,
+		        ke.victim_x, ke.victim_y, ke.victim_z
+		 FROM kill_events ke
+		 JOIN rounds r ON r.id = ke.round_id
+		 WHERE r.match_id = ?
+		 ORDER BY r.number, ke.id`, matchID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query kill positions for match %s: %w", matchID, err)
+	}
+	defer rows.Close()
+
+	var kills []KillEvent
+	for rows.Next() {
+		var ke KillEvent
+		var hs int
+		if err := rows.Scan(&ke.ID, &ke.RoundID, &ke.MatchID, &ke.RoundNum,
+			&ke.Attacker, &ke.Victim, &ke.AttackerSteamID, &ke.VictimSteamID,
+			&ke.Weapon, &hs,
+			&ke.AttackerX, &ke.AttackerY, &ke.AttackerZ,
+			&ke.VictimX, &ke.VictimY, &ke.VictimZ); err != nil {
+			return nil, fmt.Errorf("scan kill event: %w", err)
+		}
+		ke.Headshot = hs != 0
+		kills = append(kills, ke)
+	}
+	return kills, rows.Err()
+}
+
+func (s *sqlStore) GetDamageEvents(ctx context.Context, matchID string) ([]DamageEvent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT de.id, de.round_id, r.match_id, r.number, de.tick,
+		        COALESCE(de.attacker_id, ''), COALESCE(de.victim_id, ''),
+		        COALESCE(de.attacker_steam_id, ''), COALESCE(de.victim_steam_id, ''),
+		        de.weapon, de.hit_group, de.health_damage, de.armor_damage,
+		        de.attacker_x, de.attacker_y, de.attacker_z,
+		        de.victim_x, de.victim_y, de.victim_z, de.aggregate
+		 FROM damage_events de
+		 JOIN rounds r ON r.id = de.round_id
+		 WHERE r.match_id = ?
+		 ORDER BY r.number, de.tick, de.id`, matchID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query damage events for match %s: %w", matchID, err)
+	}
+	defer rows.Close()
+
+	var damage []DamageEvent
+	for rows.Next() {
+		var (
+			de        DamageEvent
+			aggregate int
+		)
+		if err := rows.Scan(&de.ID, &de.RoundID, &de.MatchID, &de.RoundNum, &de.Tick,
+			&de.Attacker, &de.Victim, &de.AttackerSteamID, &de.VictimSteamID,
+			&de.Weapon, &de.HitGroup, &de.HealthDamage, &de.ArmorDamage,
+			&de.AttackerX, &de.AttackerY, &de.AttackerZ,
+			&de.VictimX, &de.VictimY, &de.VictimZ, &aggregate); err != nil {
+			return nil, fmt.Errorf("scan damage event: %w", err)
+		}
+		de.Aggregate = aggregate != 0
+		damage = append(damage, de)
+	}
+	return damage, rows.Err()
+}
+
+func (s *sqlStore) GetRoundReplay(ctx context.Context, matchID string, roundNumber int) ([]ReplayFrame, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT rr.data
+		 FROM round_replays rr
+		 JOIN rounds r ON r.id = rr.round_id
+		 WHERE r.match_id = ? AND r.number = ?`, matchID, roundNumber,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query replay for match %s round %d: %w", matchID, roundNumber, err)
+	}
+
+	frames, err := decodeReplay(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode replay for match %s round %d: %w", matchID, roundNumber, err)
+	}
+	return frames, nil
+}
+
+func nullString(s string) sql.NullString {
+	if s == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: s, Valid: true}
+}
+
+func nullFloat(f float64) sql.NullFloat64 {
+	if f == 0 {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: f, Valid: true}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// GetPlayerRounds returns per-player round rows for a match, ordered by
+// round number. If steamID is non-empty only that player's rows are
+// returned. Each row carries its team's buy type for the round.
+func (s *sqlStore) GetPlayerRounds(ctx context.Context, matchID, steamID string) ([]PlayerRound, error) {
+	query := `SELECT pr.round_id, r.match_id, r.number, pr.player_id, p.steam_id, p.name, pr.side,
+	        pr.kills, pr.deaths, pr.assists, pr.damage, pr.survived, pr.traded, pr.kast,
+	        pr.equipment_value, pr.money_spent, COALESCE(er.buy_type, '')
+	 FROM player_rounds pr
+	 JOIN rounds r ON r.id = pr.round_id
+	 JOIN players p ON p.id = pr.player_id
+	 LEFT JOIN economy_rounds er ON er.round_id = pr.round_id AND er.team = pr.side
+	 WHERE r.match_id = ?`
+	args := []any{matchID}
+	if steamID != "" {
+		query += ` AND p.steam_id = ?`
+		args = append(args, steamID)
+	}
+	query += ` ORDER BY r.number, p.steam_id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query player rounds for match %s: %w", matchID, err)
+	}
+	defer rows.Close()
+
+	var out []PlayerRound
+	for rows.Next() {
+		var (
+			pr                     PlayerRound
+			survived, traded, kast int
+		)
+		if err := rows.Scan(&pr.RoundID, &pr.MatchID, &pr.RoundNumber, &pr.PlayerID, &pr.SteamID, &pr.Name, &pr.Side,
+			&pr.Kills, &pr.Deaths, &pr.Assists, &pr.Damage, &survived, &traded, &kast,
+			&pr.EquipmentValue, &pr.MoneySpent, &pr.BuyType); err != nil {
+			return nil, fmt.Errorf("scan player round: %w", err)
+		}
+		pr.Survived = survived != 0
+		pr.Traded = traded != 0
+		pr.KAST = kast != 0
+		out = append(out, pr)
+	}
+	return out, rows.Err()
+}
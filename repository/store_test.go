@@ -0,0 +1,1576 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// conformance is the suite every Repository implementation must pass.
+// Each test gets a fresh, empty repository.
+var conformance = []struct {
+	name string
+	test func(t *testing.T, repo *sqlStore)
+}{
+	{"StoreAndGetMatch", testStoreAndGetMatch},
+	{"GetMatchNotFound", testGetMatchNotFound},
+	{"DuplicateDemo", testDuplicateDemo},
+	{"ReplaceMatch", testReplaceMatch},
+	{"DeleteMatch", testDeleteMatch},
+	{"ListMatches", testListMatches},
+	{"GetPlayerStats", testGetPlayerStats},
+	{"GetRounds", testGetRounds},
+	{"GetEconomy", testGetEconomy},
+	{"GetKillPositions", testGetKillPositions},
+	{"GetDamageEvents", testGetDamageEvents},
+	{"GetPlayerRounds", testGetPlayerRounds},
+	{"GetRoundReplay", testGetRoundReplay},
+	{"PlayerUpsert", testPlayerUpsert},
+	{"PlayerCareer", testPlayerCareer},
+	{"Leaderboard", testLeaderboard},
+	{"MapStats", testMapStats},
+	{"Teams", testTeams},
+	{"TeamHeadToHead", testTeamHeadToHead},
+	{"PlayerHeadToHead", testPlayerHeadToHead},
+	{"IngestJobLifecycle", testIngestJobLifecycle},
+	{"FindMatchByHash", testFindMatchByHash},
+}
+
+// runConformance runs the suite against repositories opened by open.
+func runConformance(t *testing.T, open func(t *testing.T) *sqlStore) {
+	for _, c := range conformance {
+		t.Run(c.name, func(t *testing.T) {
+			c.test(t, open(t))
+		})
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	runConformance(t, func(t *testing.T) *sqlStore {
+		repo, err := New(":memory:")
+		if err != nil {
+			t.Fatalf("create test repo: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo.sqlStore
+	})
+}
+
+// TestPostgres runs the suite against the PostgreSQL database at the URL
+// in CS2STATS_TEST_POSTGRES, giving each test a schema of its own that is
+// dropped afterwards. It is skipped when the variable is not set.
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("CS2STATS_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("CS2STATS_TEST_POSTGRES not set")
+	}
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	defer admin.Close()
+
+	runConformance(t, func(t *testing.T) *sqlStore {
+		schema := fmt.Sprintf("cs2stats_test_%d", time.Now().UnixNano())
+		if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+			t.Fatalf("create schema: %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+				t.Errorf("drop schema: %v", err)
+			}
+		})
+
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatalf("parse CS2STATS_TEST_POSTGRES: %v", err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+
+		repo, err := NewPostgres(u.String())
+		if err != nil {
+			t.Fatalf("create test repo: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo.sqlStore
+	})
+}
+
+func TestBindPostgres(t *testing.T) {
+	for query, want := range map[string]string{
+		`SELECT id FROM matches`:                      `SELECT id FROM matches`,
+		`UPDATE t SET a = ?, b = ? WHERE id = ?`:      `UPDATE t SET a = $1, b = $2 WHERE id = $3`,
+		`SELECT '?' FROM t WHERE a = ? AND b = 'x?y'`: `SELECT '?' FROM t WHERE a = $1 AND b = 'x?y'`,
+	} {
+		if got := bindPostgres(query); got != want {
+			t.Errorf("bindPostgres(%q): got %q, want %q", query, got, want)
+		}
+	}
+}
+
+func seedMatch(t *testing.T, repo *sqlStore) Match {
+	t.Helper()
+	now := time.Now().Truncate(time.Second)
+	m := Match{
+		ID:              "match-001",
+		MapName:         "de_dust2",
+		Date:            now.Add(-time.Hour),
+		DurationSeconds: 2400,
+		TeamA:           "Team Alpha",
+		TeamB:           "Team Beta",
+		ScoreA:          16,
+		ScoreB:          12,
+		DemoHash:        "abc123hash",
+		TeamAStartedAs:  "CT",
+		CreatedAt:       now,
+		Players: []PlayerStats{
+			{
+				PlayerID: "p1", SteamID: "76561198001", Name: "Player One",
+				Team: "CT", Kills: 25, Deaths: 15, Assists: 5,
+				ADR: 85.3, KAST: 72.0, HeadshotPct: 55.0, Rating: 1.25,
+				KPR: 1.0, DPR: 0.6, Impact: 1.4,
+				Headshots: 14, TotalDamage: 2134, Survived: 10,
+				FirstKills: 6, FirstDeaths: 2, TradeKills: 3,
+				TwoK: 4, ThreeK: 2, FourK: 1,
+				FlashAssists: 3, UtilityDamage: 120,
+				EnemiesFlashed: 14, TeammatesFlashed: 2, EnemyBlindDuration: 31.5,
+				AvgEnemyBlindDuration: 2.25, FlashesLeadingToKill: 4,
+			},
+			{
+				PlayerID: "p2", SteamID: "76561198002", Name: "Player Two",
+				Team: "T", Kills: 18, Deaths: 20, Assists: 7,
+				ADR: 70.1, KAST: 65.0, HeadshotPct: 40.0, Rating: 0.95,
+				FlashAssists: 1, UtilityDamage: 80,
+			},
+		},
+		Rounds: []Round{
+			{
+				ID: "r1", Number: 1, WinnerTeam: "CT", WinMethod: "Elimination",
+				FirstKillPlayerID: "p1", FirstDeathPlayerID: "p2",
+				FirstKillSteamID: "76561198001", FirstDeathSteamID: "76561198002",
+				FirstKillWeapon: "AK-47", FirstKillRoundTime: 5.3,
+				TeamASide: "CT", TeamAWon: true,
+			},
+			{
+				ID: "r2", Number: 2, WinnerTeam: "T", WinMethod: "BombExploded",
+				FirstKillPlayerID: "p2", FirstDeathPlayerID: "p1",
+				FirstKillSteamID: "76561198002", FirstDeathSteamID: "76561198001",
+				FirstKillWeapon: "AWP", FirstKillRoundTime: 12.7,
+				BombPlantSteamID: "76561198002", BombPlantSite: "B",
+				BombPlantRoundTime: 35.2,
+				TeamASide: "T", TeamAWon: true,
+				Clutch: &Clutch{
+					RoundID: "r2", PlayerID: "p2", PlayerSteamID: "76561198002", Opponents: 2, Success: true,
+				},
+			},
+		},
+		Economy: []EconomyRound{
+			{RoundID: "r1", Team: "CT", Spend: 4100, EquipmentValue: 4500, BuyType: "Eco"},
+			{RoundID: "r1", Team: "T", Spend: 3900, EquipmentValue: 4200, BuyType: "Eco"},
+			{RoundID: "r2", Team: "CT", Spend: 16000, EquipmentValue: 20000, BuyType: "Full"},
+			{RoundID: "r2", Team: "T", Spend: 12000, EquipmentValue: 14000, BuyType: "Force"},
+		},
+		KillEvents: []KillEvent{
+			{
+				ID: "k1", RoundID: "r1", Attacker: "p1", Victim: "p2",
+				AttackerSteamID: "76561198001", VictimSteamID: "76561198002",
+				Weapon: "AK-47", Headshot: true,
+				AttackerX: 100.5, AttackerY: 200.3, AttackerZ: 10.0,
+				VictimX: 300.1, VictimY: 400.2, VictimZ: 10.0,
+			},
+			{
+				ID: "k2", RoundID: "r2", Attacker: "p2", Victim: "p1",
+				AttackerSteamID: "76561198002", VictimSteamID: "76561198001",
+				Weapon: "AWP", Headshot: false,
+				AttackerX: 150.0, AttackerY: 250.0, AttackerZ: 12.0,
+				VictimX: 350.0, VictimY: 450.0, VictimZ: 12.0,
+			},
+		},
+		DamageEvents: []DamageEvent{
+			{
+				ID: "d1", RoundID: "r1", Tick: 1200, Attacker: "p1", Victim: "p2",
+				AttackerSteamID: "76561198001", VictimSteamID: "76561198002",
+				Weapon: "AK-47", HitGroup: "Head", HealthDamage: 100, ArmorDamage: 12,
+				AttackerX: 100.5, AttackerY: 200.3, AttackerZ: 10.0,
+				VictimX: 300.1, VictimY: 400.2, VictimZ: 10.0,
+			},
+			{
+				ID: "d2", RoundID: "r2", Tick: 5000, Attacker: "p1",
+				AttackerSteamID: "76561198001", HealthDamage: 45, Aggregate: true,
+			},
+		},
+		PlayerRounds: []PlayerRound{
+			{RoundID: "r1", PlayerID: "p1", SteamID: "76561198001", Side: "CT", Kills: 1, Damage: 100, Survived: true, KAST: true, EquipmentValue: 900, MoneySpent: 700},
+			{RoundID: "r1", PlayerID: "p2", SteamID: "76561198002", Side: "T", Deaths: 1, EquipmentValue: 850, MoneySpent: 650},
+			{RoundID: "r2", PlayerID: "p1", SteamID: "76561198001", Side: "CT", Deaths: 1, Damage: 45, Traded: true, KAST: true, EquipmentValue: 4700, MoneySpent: 3100},
+			{RoundID: "r2", PlayerID: "p2", SteamID: "76561198002", Side: "T", Kills: 1, Assists: 1, Survived: true, KAST: true, EquipmentValue: 3700, MoneySpent: 2900},
+		},
+		Replays: []RoundReplay{
+			{
+				RoundID: "r1",
+				Frames: []ReplayFrame{
+					{Tick: 1000, Players: []ReplaySample{
+						{SteamID: "76561198001", X: 100.5, Y: -200.25, Z: 10, Yaw: 90.5, Pitch: -3.2, Health: 100, Armor: 100, Weapon: "AK-47", Money: 800},
+						{SteamID: "76561198002", X: 300, Y: 400, Z: 10, Yaw: 270, Health: 100, Weapon: "Glock-18", Money: 3200},
+					}},
+					{Tick: 1016, Players: []ReplaySample{
+						{SteamID: "76561198001", X: 104.0625, Y: -198, Z: 10, Yaw: 88, Pitch: -3.2, Health: 74, Armor: 92, Weapon: "AK-47", Money: 800},
+					}},
+				},
+			},
+		},
+	}
+
+	id, err := repo.StoreMatch(context.Background(), m)
+	if err != nil {
+		t.Fatalf("seed match: %v", err)
+	}
+	if id != "match-001" {
+		t.Fatalf("expected match ID match-001, got %s", id)
+	}
+	return m
+}
+
+func testStoreAndGetMatch(t *testing.T, repo *sqlStore) {
+	want := seedMatch(t, repo)
+
+	got, err := repo.GetMatch(context.Background(), "match-001")
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+
+	if got.ID != want.ID {
+		t.Errorf("ID: got %s, want %s", got.ID, want.ID)
+	}
+	if got.MapName != want.MapName {
+		t.Errorf("MapName: got %s, want %s", got.MapName, want.MapName)
+	}
+	if got.DurationSeconds != want.DurationSeconds {
+		t.Errorf("DurationSeconds: got %d, want %d", got.DurationSeconds, want.DurationSeconds)
+	}
+	if got.TeamA != want.TeamA {
+		t.Errorf("TeamA: got %s, want %s", got.TeamA, want.TeamA)
+	}
+	if got.ScoreA != want.ScoreA {
+		t.Errorf("ScoreA: got %d, want %d", got.ScoreA, want.ScoreA)
+	}
+	if got.ScoreB != want.ScoreB {
+		t.Errorf("ScoreB: got %d, want %d", got.ScoreB, want.ScoreB)
+	}
+	if got.DemoHash != want.DemoHash {
+		t.Errorf("DemoHash: got %s, want %s", got.DemoHash, want.DemoHash)
+	}
+	if got.TeamAStartedAs != "CT" {
+		t.Errorf("TeamAStartedAs: got %s, want CT", got.TeamAStartedAs)
+	}
+}
+
+func testGetMatchNotFound(t *testing.T, repo *sqlStore) {
+
+	_, err := repo.GetMatch(context.Background(), "nonexistent")
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testDuplicateDemo(t *testing.T, repo *sqlStore) {
+	seedMatch(t, repo)
+
+	dup := Match{
+		ID: "match-002", MapName: "de_inferno", DemoHash: "abc123hash",
+		Date: time.Now(), CreatedAt: time.Now(),
+		TeamA: "A", TeamB: "B",
+	}
+	_, err := repo.StoreMatch(context.Background(), dup)
+	if err != ErrDuplicateDemo {
+		t.Errorf("expected ErrDuplicateDemo, got %v", err)
+	}
+}
+
+func testReplaceMatch(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	orig := seedMatch(t, repo)
+
+	// a reparse yields fresh round and player IDs and different numbers
+	m := Match{
+		ID: orig.ID, MapName: orig.MapName, Date: orig.Date, DurationSeconds: 2500,
+		TeamA: orig.TeamA, TeamB: orig.TeamB, ScoreA: 1, ScoreB: 0,
+		DemoHash: "ignored", TeamAStartedAs: "T", ParserVersion: 2, CreatedAt: time.Now(),
+		Players: []PlayerStats{
+			{PlayerID: "p9", SteamID: "76561198001", Name: "Player One", Team: "T", Kills: 1},
+		},
+		Rounds: []Round{
+			{ID: "r9", Number: 1, WinnerTeam: "T", WinMethod: "Elimination", FirstKillPlayerID: "p9", TeamASide: "T", TeamAWon: true},
+		},
+		Economy: []EconomyRound{
+			{RoundID: "r9", Team: "T", Spend: 800, EquipmentValue: 900, BuyType: "Pistol"},
+		},
+		KillEvents: []KillEvent{
+			{ID: "k9", RoundID: "r9", Attacker: "p9", AttackerSteamID: "76561198001", Weapon: "Glock-18"},
+		},
+	}
+	if err := repo.ReplaceMatch(ctx, m); err != nil {
+		t.Fatalf("replace match: %v", err)
+	}
+
+	got, err := repo.GetMatch(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	if got.ScoreA != 1 || got.DurationSeconds != 2500 || got.ParserVersion != 2 || got.TeamAStartedAs != "T" {
+		t.Errorf("match: got score %d duration %d version %d started %s", got.ScoreA, got.DurationSeconds, got.ParserVersion, got.TeamAStartedAs)
+	}
+	if got.DemoHash != orig.DemoHash || !got.CreatedAt.Equal(orig.CreatedAt) {
+		t.Errorf("demo hash and creation time must be kept: got %s %v", got.DemoHash, got.CreatedAt)
+	}
+
+	rounds, err := repo.GetRounds(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("get rounds: %v", err)
+	}
+	if len(rounds) != 1 || rounds[0].ID != "r9" || rounds[0].Clutch != nil || rounds[0].FirstKillPlayerID != "p1" {
+		t.Errorf("rounds: got %+v", rounds)
+	}
+	players, err := repo.GetPlayerStats(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("get player stats: %v", err)
+	}
+	if len(players) != 1 || players[0].Kills != 1 {
+		t.Errorf("players: got %+v", players)
+	}
+	economy, err := repo.GetEconomy(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("get economy: %v", err)
+	}
+	if len(economy) != 1 || economy[0].BuyType != "Pistol" {
+		t.Errorf("economy: got %+v", economy)
+	}
+	kills, err := repo.GetKillPositions(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("get kills: %v", err)
+	}
+	if len(kills) != 1 || kills[0].Weapon != "Glock-18" {
+		t.Errorf("kills: got %+v", kills)
+	}
+	damage, err := repo.GetDamageEvents(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("get damage events: %v", err)
+	}
+	if len(damage) != 0 {
+		t.Errorf("damage events: got %d, want 0", len(damage))
+	}
+
+	// only matches from older parser versions are listed as outdated
+	for version, want := range map[int]int{0: 1, 2: 0, 3: 1} {
+		ids, err := repo.ListMatchIDs(ctx, version)
+		if err != nil {
+			t.Fatalf("list match IDs below %d: %v", version, err)
+		}
+		if len(ids) != want {
+			t.Errorf("match IDs below version %d: got %v, want %d", version, ids, want)
+		}
+	}
+
+	m.ID = "missing"
+	if err := repo.ReplaceMatch(ctx, m); err != ErrNotFound {
+		t.Errorf("replace missing match: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testDeleteMatch(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	orig := seedMatch(t, repo)
+
+	// Player One also played another match and must be kept
+	other := Match{
+		ID: "match-002", MapName: "de_inferno", Date: orig.Date, DurationSeconds: 1200,
+		TeamA: "A", TeamB: "B", DemoHash: "other-hash", CreatedAt: orig.CreatedAt.Add(time.Second),
+		Players: []PlayerStats{
+			{PlayerID: "p5", SteamID: "76561198001", Name: "Player One", Team: "CT", Kills: 3},
+		},
+	}
+	if _, err := repo.StoreMatch(ctx, other); err != nil {
+		t.Fatalf("store other match: %v", err)
+	}
+
+	if err := repo.DeleteMatch(ctx, orig.ID); err != nil {
+		t.Fatalf("delete match: %v", err)
+	}
+	if _, err := repo.GetMatch(ctx, orig.ID); err != ErrNotFound {
+		t.Errorf("get deleted match: expected ErrNotFound, got %v", err)
+	}
+	if _, err := repo.FindMatchByHash(ctx, orig.DemoHash); err != ErrNotFound {
+		t.Errorf("find deleted demo hash: expected ErrNotFound, got %v", err)
+	}
+
+	// nothing that belonged to the match is left behind
+	for _, table := range []string{"rounds", "match_players", "clutches", "economy_rounds", "kill_events", "damage_events", "player_rounds", "round_replays"} {
+		var n int
+		if err := repo.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		want := 0
+		if table == "match_players" {
+			want = 1
+		}
+		if n != want {
+			t.Errorf("%s: got %d rows, want %d", table, n, want)
+		}
+	}
+	var steamIDs []string
+	rows, err := repo.db.QueryContext(ctx, `SELECT steam_id FROM players`)
+	if err != nil {
+		t.Fatalf("list players: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan player: %v", err)
+		}
+		steamIDs = append(steamIDs, id)
+	}
+	if len(steamIDs) != 1 || steamIDs[0] != "76561198001" {
+		t.Errorf("players: got %v, want only 76561198001", steamIDs)
+	}
+
+	if _, err := repo.GetMatch(ctx, other.ID); err != nil {
+		t.Errorf("get other match: %v", err)
+	}
+	if err := repo.DeleteMatch(ctx, orig.ID); err != ErrNotFound {
+		t.Errorf("delete missing match: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testListMatches(t *testing.T, repo *sqlStore) {
+	now := time.Now().Truncate(time.Second)
+
+	matches := []Match{
+		{
+			ID: "m1", MapName: "de_dust2", Date: now.Add(-3 * time.Hour),
+			DurationSeconds: 2400, TeamA: "A", TeamB: "B", ScoreA: 16, ScoreB: 10,
+			DemoHash: "hash1", CreatedAt: now.Add(-3 * time.Hour),
+			Players: []PlayerStats{
+				{PlayerID: "p1", SteamID: "steam1", Name: "P1", Team: "CT"},
+			},
+		},
+		{
+			ID: "m2", MapName: "de_inferno", Date: now.Add(-2 * time.Hour),
+			DurationSeconds: 2200, TeamA: "C", TeamB: "D", ScoreA: 14, ScoreB: 16,
+			DemoHash: "hash2", CreatedAt: now.Add(-2 * time.Hour),
+			Players: []PlayerStats{
+				{PlayerID: "p2", SteamID: "steam2", Name: "P2", Team: "T"},
+			},
+		},
+		{
+			ID: "m3", MapName: "de_dust2", Date: now.Add(-time.Hour),
+			DurationSeconds: 1800, TeamA: "E", TeamB: "F", ScoreA: 16, ScoreB: 5,
+			DemoHash: "hash3", CreatedAt: now.Add(-time.Hour),
+			Players: []PlayerStats{
+				{PlayerID: "p1-m3", SteamID: "steam1", Name: "P1", Team: "CT"},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, m := range matches {
+		if _, err := repo.StoreMatch(ctx, m); err != nil {
+			t.Fatalf("store match %s: %v", m.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter MatchFilter
+		want   int
+		first  string
+	}{
+		{
+			name:   "all matches",
+			filter: MatchFilter{},
+			want:   3,
+			first:  "m3",
+		},
+		{
+			name:   "filter by map",
+			filter: MatchFilter{MapName: "de_dust2"},
+			want:   2,
+			first:  "m3",
+		},
+		{
+			name:   "filter by player steam ID",
+			filter: MatchFilter{PlayerSteam: "steam2"},
+			want:   1,
+			first:  "m2",
+		},
+		{
+			name:   "filter by date range",
+			filter: MatchFilter{DateFrom: now.Add(-150 * time.Minute), DateTo: now.Add(-90 * time.Minute)},
+			want:   1,
+			first:  "m2",
+		},
+		{
+			name:   "limit results",
+			filter: MatchFilter{Limit: 2},
+			want:   2,
+			first:  "m3",
+		},
+		{
+			name:   "cursor pagination",
+			filter: MatchFilter{CursorTime: now.Add(-time.Hour), CursorID: "m3"},
+			want:   2,
+			first:  "m2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.ListMatches(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("list matches: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("count: got %d, want %d", len(got), tt.want)
+			}
+			if len(got) > 0 && got[0].ID != tt.first {
+				t.Errorf("first ID: got %s, want %s", got[0].ID, tt.first)
+			}
+		})
+	}
+}
+
+func testGetPlayerStats(t *testing.T, repo *sqlStore) {
+	seedMatch(t, repo)
+
+	stats, err := repo.GetPlayerStats(context.Background(), "match-001")
+	if err != nil {
+		t.Fatalf("get player stats: %v", err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 players, got %d", len(stats))
+	}
+
+	// ordered by rating DESC, so Player One first
+	p := stats[0]
+	if p.SteamID != "76561198001" {
+		t.Errorf("first player SteamID: got %s, want 76561198001", p.SteamID)
+	}
+	if p.Kills != 25 {
+		t.Errorf("kills: got %d, want 25", p.Kills)
+	}
+	if p.Rating != 1.25 {
+		t.Errorf("rating: got %f, want 1.25", p.Rating)
+	}
+	if p.KPR != 1.0 || p.DPR != 0.6 || p.Impact != 1.4 {
+		t.Errorf("rating breakdown: got kpr %f dpr %f impact %f, want 1.0/0.6/1.4", p.KPR, p.DPR, p.Impact)
+	}
+	if p.Headshots != 14 || p.TotalDamage != 2134 || p.Survived != 10 {
+		t.Errorf("headshots/damage/survived: got %d/%d/%d, want 14/2134/10", p.Headshots, p.TotalDamage, p.Survived)
+	}
+	if p.FirstKills != 6 || p.FirstDeaths != 2 || p.TradeKills != 3 {
+		t.Errorf("first kills/deaths/trades: got %d/%d/%d, want 6/2/3", p.FirstKills, p.FirstDeaths, p.TradeKills)
+	}
+	if p.TwoK != 4 || p.ThreeK != 2 || p.FourK != 1 || p.FiveK != 0 {
+		t.Errorf("multi-kills: got %d/%d/%d/%d, want 4/2/1/0", p.TwoK, p.ThreeK, p.FourK, p.FiveK)
+	}
+	if p.EnemiesFlashed != 14 || p.TeammatesFlashed != 2 {
+		t.Errorf("flashed: got %d enemies/%d teammates, want 14/2", p.EnemiesFlashed, p.TeammatesFlashed)
+	}
+	if p.EnemyBlindDuration != 31.5 || p.AvgEnemyBlindDuration != 2.25 {
+		t.Errorf("blind duration: got %f total/%f avg, want 31.5/2.25", p.EnemyBlindDuration, p.AvgEnemyBlindDuration)
+	}
+	if p.FlashesLeadingToKill != 4 {
+		t.Errorf("flashes leading to kill: got %d, want 4", p.FlashesLeadingToKill)
+	}
+}
+
+func testGetRounds(t *testing.T, repo *sqlStore) {
+	seedMatch(t, repo)
+
+	rounds, err := repo.GetRounds(context.Background(), "match-001")
+	if err != nil {
+		t.Fatalf("get rounds: %v", err)
+	}
+
+	if len(rounds) != 2 {
+		t.Fatalf("expected 2 rounds, got %d", len(rounds))
+	}
+
+	r1 := rounds[0]
+	if r1.Number != 1 {
+		t.Errorf("round 1 number: got %d", r1.Number)
+	}
+	if r1.WinnerTeam != "CT" {
+		t.Errorf("round 1 winner: got %s, want CT", r1.WinnerTeam)
+	}
+	if r1.Clutch != nil {
+		t.Error("round 1 should have no clutch")
+	}
+	if r1.FirstKillSteamID != "76561198001" {
+		t.Errorf("round 1 first kill steam ID: got %s, want 76561198001", r1.FirstKillSteamID)
+	}
+	if r1.FirstDeathSteamID != "76561198002" {
+		t.Errorf("round 1 first death steam ID: got %s, want 76561198002", r1.FirstDeathSteamID)
+	}
+	if r1.FirstKillWeapon != "AK-47" {
+		t.Errorf("round 1 first kill weapon: got %s, want AK-47", r1.FirstKillWeapon)
+	}
+	if r1.FirstKillRoundTime != 5.3 {
+		t.Errorf("round 1 first kill round time: got %f, want 5.3", r1.FirstKillRoundTime)
+	}
+
+	if r1.TeamASide != "CT" || !r1.TeamAWon {
+		t.Errorf("round 1 team A: got side %s won %v, want CT/true", r1.TeamASide, r1.TeamAWon)
+	}
+
+	r2 := rounds[1]
+	if r2.TeamASide != "T" || !r2.TeamAWon {
+		t.Errorf("round 2 team A: got side %s won %v, want T/true", r2.TeamASide, r2.TeamAWon)
+	}
+	if r2.Clutch == nil {
+		t.Fatal("round 2 should have a clutch")
+	}
+	if !r2.Clutch.Success {
+		t.Error("round 2 clutch should be successful")
+	}
+	if r2.Clutch.Opponents != 2 {
+		t.Errorf("clutch opponents: got %d, want 2", r2.Clutch.Opponents)
+	}
+	if r2.Clutch.PlayerSteamID != "76561198002" {
+		t.Errorf("clutch player steam ID: got %s, want 76561198002", r2.Clutch.PlayerSteamID)
+	}
+	if r2.BombPlantSteamID != "76561198002" {
+		t.Errorf("round 2 bomb plant steam ID: got %s, want 76561198002", r2.BombPlantSteamID)
+	}
+	if r2.BombPlantSite != "B" {
+		t.Errorf("round 2 bomb plant site: got %s, want B", r2.BombPlantSite)
+	}
+	if r2.BombPlantRoundTime != 35.2 {
+		t.Errorf("round 2 bomb plant round time: got %f, want 35.2", r2.BombPlantRoundTime)
+	}
+	if r2.BombDefuseSteamID != "" {
+		t.Errorf("round 2 should have no defuse, got steam ID %s", r2.BombDefuseSteamID)
+	}
+}
+
+func testGetEconomy(t *testing.T, repo *sqlStore) {
+	seedMatch(t, repo)
+
+	econ, err := repo.GetEconomy(context.Background(), "match-001")
+	if err != nil {
+		t.Fatalf("get economy: %v", err)
+	}
+
+	if len(econ) != 4 {
+		t.Fatalf("expected 4 economy rows, got %d", len(econ))
+	}
+
+	// r1 CT
+	if econ[0].BuyType != "Eco" {
+		t.Errorf("r1 CT buy type: got %s, want Eco", econ[0].BuyType)
+	}
+	// r2 CT
+	if econ[2].BuyType != "Full" {
+		t.Errorf("r2 CT buy type: got %s, want Full", econ[2].BuyType)
+	}
+
+	// team A played CT in r1 and T in r2
+	if !econ[0].TeamA || econ[1].TeamA {
+		t.Errorf("r1 team A: got CT %v / T %v, want true/false", econ[0].TeamA, econ[1].TeamA)
+	}
+	if econ[2].TeamA || !econ[3].TeamA {
+		t.Errorf("r2 team A: got CT %v / T %v, want false/true", econ[2].TeamA, econ[3].TeamA)
+	}
+}
+
+func testGetKillPositions(t *testing.T, repo *sqlStore) {
+	seedMatch(t, repo)
+
+	kills, err := repo.GetKillPositions(context.Background(), "match-001")
+	if err != nil {
+		t.Fatalf("get kill positions: %v", err)
+	}
+
+	if len(kills) != 2 {
+		t.Fatalf("expected 2 kills, got %d", len(kills))
+	}
+
+	k1 := kills[0]
+	if k1.Weapon != "AK-47" {
+		t.Errorf("kill 1 weapon: got %s, want AK-47", k1.Weapon)
+	}
+	if !k1.Headshot {
+		t.Error("kill 1 should be headshot")
+	}
+	if k1.AttackerX != 100.5 {
+		t.Errorf("kill 1 attacker X: got %f, want 100.5", k1.AttackerX)
+	}
+	if k1.AttackerSteamID != "76561198001" {
+		t.Errorf("kill 1 attacker steam ID: got %s, want 76561198001", k1.AttackerSteamID)
+	}
+	if k1.VictimSteamID != "76561198002" {
+		t.Errorf("kill 1 victim steam ID: got %s, want 76561198002", k1.VictimSteamID)
+	}
+
+	k2 := kills[1]
+	if k2.Headshot {
+		t.Error("kill 2 should not be headshot")
+	}
+	if k2.AttackerSteamID != "76561198002" {
+		t.Errorf("kill 2 attacker steam ID: got %s, want 76561198002", k2.AttackerSteamID)
+	}
+	if k2.VictimSteamID != "76561198001" {
+		t.Errorf("kill 2 victim steam ID: got %s, want 76561198001", k2.VictimSteamID)
+	}
+}
+
+func testGetDamageEvents(t *testing.T, repo *sqlStore) {
+	seedMatch(t, repo)
+
+	damage, err := repo.GetDamageEvents(context.Background(), "match-001")
+	if err != nil {
+		t.Fatalf("get damage events: %v", err)
+	}
+
+	if len(damage) != 2 {
+		t.Fatalf("expected 2 damage events, got %d", len(damage))
+	}
+
+	d1 := damage[0]
+	if d1.RoundNum != 1 {
+		t.Errorf("damage 1 round: got %d, want 1", d1.RoundNum)
+	}
+	if d1.HitGroup != "Head" || d1.HealthDamage != 100 || d1.ArmorDamage != 12 {
+		t.Errorf("damage 1: got %s/%d/%d, want Head/100/12", d1.HitGroup, d1.HealthDamage, d1.ArmorDamage)
+	}
+	if d1.VictimSteamID != "76561198002" || d1.Aggregate {
+		t.Errorf("damage 1: got victim steam ID %s aggregate %v, want 76561198002 hit", d1.VictimSteamID, d1.Aggregate)
+	}
+
+	// entity-derived damage is an aggregate with no victim
+	d2 := damage[1]
+	if d2.Victim != "" || d2.VictimSteamID != "" || !d2.Aggregate {
+		t.Errorf("damage 2 should be an aggregate with no victim, got %q/%q aggregate %v", d2.Victim, d2.VictimSteamID, d2.Aggregate)
+	}
+	if d2.HealthDamage != 45 {
+		t.Errorf("damage 2 health damage: got %d, want 45", d2.HealthDamage)
+	}
+}
+
+func testGetPlayerRounds(t *testing.T, repo *sqlStore) {
+	seedMatch(t, repo)
+	ctx := context.Background()
+
+	all, err := repo.GetPlayerRounds(ctx, "match-001", "")
+	if err != nil {
+		t.Fatalf("get player rounds: %v", err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("expected 4 player rounds, got %d", len(all))
+	}
+
+	rows, err := repo.GetPlayerRounds(ctx, "match-001", "76561198001")
+	if err != nil {
+		t.Fatalf("get player rounds for player: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rounds for player, got %d", len(rows))
+	}
+
+	r1 := rows[0]
+	if r1.RoundNumber != 1 || r1.Side != "CT" || r1.Name != "Player One" {
+		t.Errorf("round 1: got number %d side %s name %s, want 1/CT/Player One", r1.RoundNumber, r1.Side, r1.Name)
+	}
+	if r1.Kills != 1 || r1.Damage != 100 || !r1.Survived || !r1.KAST {
+		t.Errorf("round 1 stats: got %+v", r1)
+	}
+	if r1.EquipmentValue != 900 || r1.MoneySpent != 700 {
+		t.Errorf("round 1 economy: got %d/%d, want 900/700", r1.EquipmentValue, r1.MoneySpent)
+	}
+	if r1.BuyType != "Eco" {
+		t.Errorf("round 1 buy type: got %s, want Eco", r1.BuyType)
+	}
+
+	r2 := rows[1]
+	if r2.Deaths != 1 || !r2.Traded || r2.Survived || r2.BuyType != "Full" {
+		t.Errorf("round 2: got %+v", r2)
+	}
+}
+
+func testGetRoundReplay(t *testing.T, repo *sqlStore) {
+	seed := seedMatch(t, repo)
+
+	frames, err := repo.GetRoundReplay(context.Background(), "match-001", 1)
+	if err != nil {
+		t.Fatalf("get round replay: %v", err)
+	}
+
+	want := seed.Replays[0].Frames
+	if len(frames) != len(want) {
+		t.Fatalf("expected %d frames, got %d", len(want), len(frames))
+	}
+	for i := range want {
+		if frames[i].Tick != want[i].Tick {
+			t.Errorf("frame %d tick: got %d, want %d", i, frames[i].Tick, want[i].Tick)
+		}
+		if len(frames[i].Players) != len(want[i].Players) {
+			t.Fatalf("frame %d: expected %d players, got %d", i, len(want[i].Players), len(frames[i].Players))
+		}
+		for j, w := range want[i].Players {
+			// quantized values in the seed are exactly representable
+			if got := frames[i].Players[j]; got != w {
+				t.Errorf("frame %d player %d: got %+v, want %+v", i, j, got, w)
+			}
+		}
+	}
+
+	// round 2 has no replay
+	_, err = repo.GetRoundReplay(context.Background(), "match-001", 2)
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for round without replay, got %v", err)
+	}
+}
+
+func testPlayerUpsert(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	// first match with player name "OldName"
+	m1 := Match{
+		ID: "m1", MapName: "de_dust2", Date: now, DurationSeconds: 1200,
+		TeamA: "A", TeamB: "B", DemoHash: "hash-a", CreatedAt: now,
+		Players: []PlayerStats{
+			{PlayerID: "p-a", SteamID: "steam-99", Name: "OldName", Team: "CT", Kills: 10},
+		},
+	}
+	if _, err := repo.StoreMatch(ctx, m1); err != nil {
+		t.Fatalf("store m1: %v", err)
+	}
+
+	// second match, same steam ID, new player ID, updated name; rows that
+	// reference the new ID must be stored against the existing player
+	m2 := Match{
+		ID: "m2", MapName: "de_dust2", Date: now, DurationSeconds: 1200,
+		TeamA: "A", TeamB: "B", DemoHash: "hash-b", CreatedAt: now.Add(time.Second),
+		Players: []PlayerStats{
+			{PlayerID: "p-b", SteamID: "steam-99", Name: "NewName", Team: "T", Kills: 15},
+		},
+		Rounds: []Round{
+			{
+				ID: "m2-r1", Number: 1, WinnerTeam: "T", WinMethod: "TerroristsWin",
+				FirstKillPlayerID: "p-b",
+				Clutch:            &Clutch{RoundID: "m2-r1", PlayerID: "p-b", PlayerSteamID: "steam-99", Opponents: 1, Success: true},
+			},
+		},
+		KillEvents: []KillEvent{
+			{ID: "m2-k1", RoundID: "m2-r1", Attacker: "p-b", AttackerSteamID: "steam-99", Weapon: "ak47"},
+		},
+	}
+	if _, err := repo.StoreMatch(ctx, m2); err != nil {
+		t.Fatalf("store m2: %v", err)
+	}
+
+	// the player name should be updated
+	stats, err := repo.GetPlayerStats(ctx, "m2")
+	if err != nil {
+		t.Fatalf("get player stats: %v", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 player, got %d", len(stats))
+	}
+	// the upsert should have updated the name to NewName
+	if stats[0].Name != "NewName" {
+		t.Errorf("player name: got %s, want NewName", stats[0].Name)
+	}
+
+	rounds, err := repo.GetRounds(ctx, "m2")
+	if err != nil {
+		t.Fatalf("get rounds: %v", err)
+	}
+	if rounds[0].Clutch == nil || rounds[0].Clutch.PlayerID != "p-a" || rounds[0].FirstKillPlayerID != "p-a" {
+		t.Errorf("round 1 player references: got %+v", rounds[0])
+	}
+}
+
+func testPlayerCareer(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	seed := seedMatch(t, repo) // de_dust2, p1 on team A, 2 player rounds, won 16-12
+
+	// p1 starts as CT on team B and wins 16-10; no player rounds are
+	// stored, so the score gives the rounds played
+	m2 := Match{
+		ID: "match-002", MapName: "de_dust2", Date: seed.Date.Add(-24 * time.Hour),
+		DurationSeconds: 2000, TeamA: "C", TeamB: "D", ScoreA: 10, ScoreB: 16,
+		DemoHash: "hash-002", TeamAStartedAs: "T", CreatedAt: seed.CreatedAt.Add(-time.Minute),
+		Players: []PlayerStats{
+			{PlayerID: "p1-m2", SteamID: "76561198001", Name: "Player One", Team: "CT",
+				Kills: 15, Deaths: 20, ADR: 60, KAST: 60, HeadshotPct: 20, Rating: 0.75},
+		},
+	}
+	m3 := Match{
+		ID: "match-003", MapName: "de_inferno", Date: seed.Date.Add(-48 * time.Hour),
+		DurationSeconds: 1500, TeamA: "E", TeamB: "F", ScoreA: 5, ScoreB: 13,
+		DemoHash: "hash-003", TeamAStartedAs: "CT", CreatedAt: seed.CreatedAt.Add(-2 * time.Minute),
+		Players: []PlayerStats{
+			{PlayerID: "p1-m3", SteamID: "76561198001", Name: "Player One", Team: "CT",
+				Kills: 10, Deaths: 14, Rating: 0.9},
+		},
+	}
+	for _, m := range []Match{m2, m3} {
+		if _, err := repo.StoreMatch(ctx, m); err != nil {
+			t.Fatalf("store match %s: %v", m.ID, err)
+		}
+	}
+
+	p, err := repo.GetPlayer(ctx, "76561198001")
+	if err != nil {
+		t.Fatalf("get player: %v", err)
+	}
+	if p.ID != "p1" || p.Name != "Player One" {
+		t.Errorf("player: got %+v", p)
+	}
+	if _, err := repo.GetPlayer(ctx, "unknown"); err != ErrNotFound {
+		t.Errorf("unknown player: expected ErrNotFound, got %v", err)
+	}
+
+	history, err := repo.ListPlayerMatches(ctx, "76561198001", PlayerFilter{})
+	if err != nil {
+		t.Fatalf("list player matches: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected 3 matches, got %d", len(history))
+	}
+	first := history[0]
+	if first.Match.ID != "match-001" || first.Match.MapName != "de_dust2" || first.Stats.Kills != 25 || first.Stats.Name != "Player One" {
+		t.Errorf("newest match: got %+v", first)
+	}
+	if first.RoundsPlayed != 2 || first.RoundsWon != 16 || first.RoundsLost != 12 {
+		t.Errorf("newest match rounds: got %d played, %d-%d, want 2 played, 16-12", first.RoundsPlayed, first.RoundsWon, first.RoundsLost)
+	}
+	if h := history[1]; h.RoundsPlayed != 26 || h.RoundsWon != 16 || h.RoundsLost != 10 {
+		t.Errorf("team B match rounds: got %d played, %d-%d, want 26 played, 16-10", h.RoundsPlayed, h.RoundsWon, h.RoundsLost)
+	}
+	if h := history[2]; h.RoundsWon != 5 || h.RoundsLost != 13 {
+		t.Errorf("lost match: got %d-%d, want 5-13", h.RoundsWon, h.RoundsLost)
+	}
+
+	page, err := repo.ListPlayerMatches(ctx, "76561198001", PlayerFilter{
+		Limit: 1, CursorTime: first.Match.CreatedAt, CursorID: first.Match.ID,
+	})
+	if err != nil {
+		t.Fatalf("list player matches after cursor: %v", err)
+	}
+	if len(page) != 1 || page[0].Match.ID != "match-002" {
+		t.Errorf("page after cursor: got %+v", page)
+	}
+
+	maps, err := repo.GetPlayerMapStats(ctx, "76561198001", PlayerFilter{})
+	if err != nil {
+		t.Fatalf("get player map stats: %v", err)
+	}
+	if len(maps) != 2 || maps[0].MapName != "de_dust2" || maps[1].MapName != "de_inferno" {
+		t.Fatalf("maps: got %+v", maps)
+	}
+	dust := maps[0]
+	if dust.Matches != 2 || dust.Wins != 2 || dust.Losses != 0 || dust.RoundsPlayed != 28 || dust.Kills != 40 {
+		t.Errorf("de_dust2 totals: got %+v", dust)
+	}
+	// rates are weighted by rounds played, headshot percentage by kills
+	if want := (1.25*2 + 0.75*26) / 28; math.Abs(dust.Rating-want) > 1e-9 {
+		t.Errorf("de_dust2 rating: got %f, want %f", dust.Rating, want)
+	}
+	if want := (55.0*25 + 20*15) / 40; math.Abs(dust.HeadshotPct-want) > 1e-9 {
+		t.Errorf("de_dust2 HS%%: got %f, want %f", dust.HeadshotPct, want)
+	}
+	if inferno := maps[1]; inferno.Matches != 1 || inferno.Losses != 1 || inferno.RoundsPlayed != 18 {
+		t.Errorf("de_inferno totals: got %+v", inferno)
+	}
+
+	// filters pick the matches aggregated over
+	filtered, err := repo.GetPlayerMapStats(ctx, "76561198001", PlayerFilter{DateFrom: m2.Date})
+	if err != nil {
+		t.Fatalf("get player map stats from date: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].Matches != 2 {
+		t.Errorf("from date: got %+v", filtered)
+	}
+	filtered, err = repo.GetPlayerMapStats(ctx, "76561198001", PlayerFilter{MapName: "de_inferno"})
+	if err != nil {
+		t.Fatalf("get player map stats on map: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].MapName != "de_inferno" {
+		t.Errorf("on map: got %+v", filtered)
+	}
+
+	sides, err := repo.GetPlayerSideStats(ctx, "76561198001", PlayerFilter{})
+	if err != nil {
+		t.Fatalf("get player side stats: %v", err)
+	}
+	want := PlayerSideStats{
+		MapName: "de_dust2", Side: "CT", Rounds: 2, RoundsWon: 1,
+		Kills: 1, Deaths: 1, Damage: 145, Survived: 1, KAST: 2,
+	}
+	if len(sides) != 1 || sides[0] != want {
+		t.Errorf("sides: got %+v, want [%+v]", sides, want)
+	}
+}
+
+func testLeaderboard(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	seed := seedMatch(t, repo) // de_dust2, 2 rounds each; p2 won a clutch
+
+	m2 := Match{
+		ID: "match-002", MapName: "de_inferno", Date: seed.Date.Add(-24 * time.Hour),
+		DurationSeconds: 1500, TeamA: "C", TeamB: "D", ScoreA: 10, ScoreB: 5,
+		DemoHash: "hash-002", TeamAStartedAs: "CT", CreatedAt: seed.CreatedAt.Add(-time.Minute),
+		Players: []PlayerStats{
+			{PlayerID: "p1-m2", SteamID: "76561198001", Name: "Player One", Team: "CT", Rating: 0.5},
+			{PlayerID: "p3", SteamID: "76561198003", Name: "Player Three", Team: "T",
+				Rating: 1.5, FirstKills: 1, FirstDeaths: 3},
+		},
+	}
+	if _, err := repo.StoreMatch(ctx, m2); err != nil {
+		t.Fatalf("store match: %v", err)
+	}
+
+	steamIDs := func(entries []LeaderboardEntry) []string {
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.SteamID)
+		}
+		return ids
+	}
+	tests := []struct {
+		name   string
+		filter LeaderboardFilter
+		want   []string
+	}{
+		{"rating", LeaderboardFilter{Metric: MetricRating}, []string{"76561198003", "76561198002", "76561198001"}},
+		{"min rounds", LeaderboardFilter{Metric: MetricRating, MinRounds: 10}, []string{"76561198003", "76561198001"}},
+		{"min matches", LeaderboardFilter{Metric: MetricRating, MinMatches: 2}, []string{"76561198001"}},
+		{"map", LeaderboardFilter{Metric: MetricRating, MapName: "de_dust2"}, []string{"76561198001", "76561198002"}},
+		{"date", LeaderboardFilter{Metric: MetricRating, DateTo: m2.Date}, []string{"76561198003", "76561198001"}},
+		{"opening kills", LeaderboardFilter{Metric: MetricOpeningKillSuccess}, []string{"76561198001", "76561198003", "76561198002"}},
+		{"clutches", LeaderboardFilter{Metric: MetricClutchWinRate}, []string{"76561198002", "76561198001", "76561198003"}},
+		{"clutches on map", LeaderboardFilter{Metric: MetricClutchWinRate, MapName: "de_inferno"}, []string{"76561198001", "76561198003"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := repo.GetLeaderboard(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("get leaderboard: %v", err)
+			}
+			if got := steamIDs(entries); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("order: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	entries, err := repo.GetLeaderboard(ctx, LeaderboardFilter{Metric: MetricClutchWinRate})
+	if err != nil {
+		t.Fatalf("get leaderboard: %v", err)
+	}
+	if e := entries[0]; e.Rank != 1 || e.Value != 100 || e.Clutches != 1 || e.ClutchesWon != 1 || e.Name != "Player Two" {
+		t.Errorf("clutch leader: got %+v", e)
+	}
+	// equal values share a rank
+	if entries[1].Rank != 2 || entries[2].Rank != 2 {
+		t.Errorf("tied ranks: got %d and %d, want 2 and 2", entries[1].Rank, entries[2].Rank)
+	}
+	p1 := entries[1]
+	if p1.Matches != 2 || p1.RoundsPlayed != 17 || p1.OpeningKillSuccess != 75 {
+		t.Errorf("p1 totals: got %+v", p1)
+	}
+	if want := (1.25*2 + 0.5*15) / 17; math.Abs(p1.Rating-want) > 1e-9 {
+		t.Errorf("p1 rating: got %f, want %f", p1.Rating, want)
+	}
+
+	// pages continue after the cursor, keeping ranks
+	var pages []string
+	filter := LeaderboardFilter{Metric: MetricClutchWinRate, Limit: 1}
+	for range 4 {
+		page, err := repo.GetLeaderboard(ctx, filter)
+		if err != nil {
+			t.Fatalf("get leaderboard page: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		last := page[len(page)-1]
+		pages = append(pages, fmt.Sprintf("%s#%d", last.SteamID, last.Rank))
+		filter.CursorValue, filter.CursorSteamID = last.Value, last.SteamID
+	}
+	if want := "[76561198002#1 76561198001#2 76561198003#2]"; fmt.Sprint(pages) != want {
+		t.Errorf("pages: got %v, want %s", pages, want)
+	}
+
+	if _, err := repo.GetLeaderboard(ctx, LeaderboardFilter{Metric: "kd"}); err == nil {
+		t.Error("unknown metric: expected an error")
+	}
+}
+
+func testMapStats(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	seed := seedMatch(t, repo) // de_dust2, Team Alpha beat Team Beta; sides switch in round 2
+
+	// Team Beta starts on T, loses the pistol, then wins a T round and
+	// the first round after switching to CT.
+	m2 := Match{
+		ID: "match-002", MapName: "de_dust2", Date: seed.Date.Add(-24 * time.Hour),
+		DurationSeconds: 400, TeamA: "Team Beta", TeamB: "Team Gamma", ScoreA: 2, ScoreB: 1,
+		DemoHash: "hash-002", TeamAStartedAs: "T", CreatedAt: seed.CreatedAt.Add(-time.Minute),
+		Players: []PlayerStats{
+			{PlayerID: "p2-m2", SteamID: "76561198002", Name: "Player Two", Team: "T"},
+		},
+		Rounds: []Round{
+			{ID: "m2-r1", Number: 1, WinnerTeam: "CT", WinMethod: "BombDefused", BombPlantSite: "A",
+				TeamASide: "T", DurationSeconds: 100},
+			{ID: "m2-r2", Number: 2, WinnerTeam: "T", WinMethod: "Elimination",
+				TeamASide: "T", TeamAWon: true, DurationSeconds: 60},
+			{ID: "m2-r3", Number: 3, WinnerTeam: "CT", WinMethod: "TimeExpired",
+				TeamASide: "CT", TeamAWon: true, DurationSeconds: 116},
+			// stored before sides were recorded, so left out
+			{ID: "m2-r4", Number: 4, WinnerTeam: "T", WinMethod: "BombExploded", BombPlantSite: "B",
+				DurationSeconds: 30},
+		},
+	}
+	m3 := Match{
+		ID: "match-003", MapName: "de_inferno", Date: seed.Date.Add(-48 * time.Hour),
+		DurationSeconds: 900, TeamA: "Team Alpha", TeamB: "Team Gamma", ScoreA: 5, ScoreB: 10,
+		DemoHash: "hash-003", TeamAStartedAs: "CT", CreatedAt: seed.CreatedAt.Add(-2 * time.Minute),
+	}
+	for _, m := range []Match{m2, m3} {
+		if _, err := repo.StoreMatch(ctx, m); err != nil {
+			t.Fatalf("store match %s: %v", m.ID, err)
+		}
+	}
+
+	rounds, err := repo.GetRounds(ctx, m2.ID)
+	if err != nil {
+		t.Fatalf("get rounds: %v", err)
+	}
+	if rounds[0].DurationSeconds != 100 {
+		t.Errorf("round duration: got %f, want 100", rounds[0].DurationSeconds)
+	}
+
+	beta := MapStats{
+		MapName: "de_dust2", Matches: 2, Wins: 1, Losses: 1, Rounds: 5,
+		CTRounds: 2, CTRoundsWon: 1, TRounds: 3, TRoundsWon: 1,
+		CTPistolRounds: 2, CTPistolRoundsWon: 1, TPistolRounds: 2, TPistolRoundsWon: 0,
+		Eliminations: 1, TimeExpired: 1,
+		AvgRoundDuration: 92, BombPlants: 1, PlantsA: 1,
+	}
+	tests := []struct {
+		name   string
+		filter MapStatsFilter
+		want   []MapStats
+	}{
+		{"all", MapStatsFilter{}, []MapStats{
+			{
+				MapName: "de_dust2", Matches: 2, Rounds: 5,
+				CTRounds: 5, CTRoundsWon: 3, TRounds: 5, TRoundsWon: 2,
+				CTPistolRounds: 4, CTPistolRoundsWon: 3, TPistolRounds: 4, TPistolRoundsWon: 1,
+				Eliminations: 2, BombsExploded: 1, BombsDefused: 1, TimeExpired: 1,
+				AvgRoundDuration: 92, BombPlants: 2, PlantsA: 1, PlantsB: 1,
+			},
+			{MapName: "de_inferno", Matches: 1},
+		}},
+		{"map", MapStatsFilter{MapName: "de_inferno"}, []MapStats{{MapName: "de_inferno", Matches: 1}}},
+		{"team", MapStatsFilter{TeamName: "Team Beta"}, []MapStats{beta}},
+		{"player", MapStatsFilter{SteamID: "76561198002"}, []MapStats{beta}},
+		{"player's team", MapStatsFilter{SteamID: "76561198001", TeamName: "Team Alpha"}, []MapStats{
+			{
+				MapName: "de_dust2", Matches: 1, Wins: 1, Rounds: 2,
+				CTRounds: 1, CTRoundsWon: 1, TRounds: 1, TRoundsWon: 1,
+				CTPistolRounds: 1, CTPistolRoundsWon: 1, TPistolRounds: 1, TPistolRoundsWon: 1,
+				Eliminations: 1, BombsExploded: 1, BombPlants: 1, PlantsB: 1,
+			},
+		}},
+		{"team with losses", MapStatsFilter{TeamName: "Team Alpha", MapName: "de_inferno"}, []MapStats{
+			{MapName: "de_inferno", Matches: 1, Losses: 1},
+		}},
+		{"player not on team", MapStatsFilter{SteamID: "76561198002", TeamName: "Team Alpha"}, nil},
+		{"unknown team", MapStatsFilter{TeamName: "Team Omega"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetMapStats(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("get map stats: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d maps, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("map %d:\n got %+v\nwant %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func testTeams(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	// Alpha's clan name links it across sides; the clanless side is known
+	// by its players, who also scrim each other in the last match.
+	matches := []Match{
+		{
+			ID: "match-001", MapName: "de_dust2", Date: now.Add(-3 * time.Hour),
+			TeamA: "Alpha", TeamB: "Beta", ScoreA: 16, ScoreB: 12, DemoHash: "hash-001",
+			TeamAStartedAs: "CT", CreatedAt: now, TeamAID: "ta-1", TeamBID: "tb-1",
+			TeamAClanName: "Alpha", TeamBClanName: "Beta",
+			Players: []PlayerStats{
+				{PlayerID: "m1-p1", SteamID: "76561198001", Name: "Player One", Team: "CT"},
+				{PlayerID: "m1-p2", SteamID: "76561198002", Name: "Player Two", Team: "T"},
+			},
+		},
+		{
+			ID: "match-002", MapName: "de_inferno", Date: now.Add(-2 * time.Hour),
+			TeamA: "Team A", TeamB: "Alpha", ScoreA: 10, ScoreB: 13, DemoHash: "hash-002",
+			TeamAStartedAs: "CT", CreatedAt: now, TeamAID: "ta-2", TeamBID: "tb-2",
+			TeamBClanName: "Alpha",
+			Players: []PlayerStats{
+				{PlayerID: "m2-p3", SteamID: "76561198003", Name: "Player Three", Team: "CT"},
+				{PlayerID: "m2-p4", SteamID: "76561198004", Name: "Player Four", Team: "CT"},
+				{PlayerID: "m2-p1", SteamID: "76561198001", Name: "Player One", Team: "T"},
+			},
+		},
+		{
+			ID: "match-003", MapName: "de_mirage", Date: now.Add(-time.Hour),
+			TeamA: "Team A", TeamB: "Team B", ScoreA: 16, ScoreB: 5, DemoHash: "hash-003",
+			TeamAStartedAs: "T", CreatedAt: now, TeamAID: "ta-3", TeamBID: "tb-3",
+			Players: []PlayerStats{
+				{PlayerID: "m3-p3", SteamID: "76561198003", Name: "Player Three", Team: "T"},
+				{PlayerID: "m3-p4", SteamID: "76561198004", Name: "Player Four", Team: "T"},
+				{PlayerID: "m3-p5", SteamID: "76561198005", Name: "Player Five", Team: "T"},
+				{PlayerID: "m3-p6", SteamID: "76561198006", Name: "Player Six", Team: "CT"},
+			},
+		},
+		{
+			ID: "match-004", MapName: "de_mirage", Date: now,
+			TeamA: "Team A", TeamB: "Team B", ScoreA: 16, ScoreB: 14, DemoHash: "hash-004",
+			TeamAStartedAs: "CT", CreatedAt: now, TeamAID: "ta-4", TeamBID: "tb-4",
+			Players: []PlayerStats{
+				{PlayerID: "m4-p3", SteamID: "76561198003", Name: "Player Three", Team: "CT"},
+				{PlayerID: "m4-p4", SteamID: "76561198004", Name: "Player Four", Team: "T"},
+			},
+		},
+	}
+	for _, m := range matches {
+		if _, err := repo.StoreMatch(ctx, m); err != nil {
+			t.Fatalf("store match %s: %v", m.ID, err)
+		}
+	}
+
+	links := map[string][2]string{
+		"match-001": {"ta-1", "tb-1"},
+		"match-002": {"ta-2", "ta-1"},
+		"match-003": {"ta-2", "tb-3"},
+		"match-004": {"ta-2", "tb-4"},
+	}
+	for id, want := range links {
+		got, err := repo.GetMatch(ctx, id)
+		if err != nil {
+			t.Fatalf("get match %s: %v", id, err)
+		}
+		if got.TeamAID != want[0] || got.TeamBID != want[1] {
+			t.Errorf("%s teams: got %s and %s, want %s and %s", id, got.TeamAID, got.TeamBID, want[0], want[1])
+		}
+	}
+
+	alpha, err := repo.GetTeam(ctx, "ta-1")
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if alpha.Name != "Alpha" || alpha.ClanName != "Alpha" || alpha.Matches != 2 || alpha.Wins != 2 || alpha.Losses != 0 {
+		t.Errorf("alpha: got %+v", alpha)
+	}
+	if !alpha.FirstPlayed.Equal(matches[0].Date) || !alpha.LastPlayed.Equal(matches[1].Date) {
+		t.Errorf("alpha played: got %v to %v", alpha.FirstPlayed, alpha.LastPlayed)
+	}
+	if len(alpha.Roster) != 1 || alpha.Roster[0].SteamID != "76561198001" || alpha.Roster[0].Matches != 2 {
+		t.Errorf("alpha roster: got %+v", alpha.Roster)
+	}
+
+	mix, err := repo.GetTeam(ctx, "ta-2")
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if mix.Name != "Player Four, Player Three" || mix.ClanName != "" || mix.Matches != 3 || mix.Wins != 2 || mix.Losses != 1 {
+		t.Errorf("roster team: got %+v", mix)
+	}
+	var roster []string
+	for _, tm := range mix.Roster {
+		roster = append(roster, fmt.Sprintf("%s:%d", tm.SteamID, tm.Matches))
+	}
+	if want := []string{"76561198003:3", "76561198004:2", "76561198005:1"}; !slices.Equal(roster, want) {
+		t.Errorf("roster: got %v, want %v", roster, want)
+	}
+
+	if _, err := repo.GetTeam(ctx, "nonexistent"); err != ErrNotFound {
+		t.Errorf("get missing team: expected ErrNotFound, got %v", err)
+	}
+
+	teamIDs := func(filter TeamFilter) []string {
+		t.Helper()
+		ts, err := repo.ListTeams(ctx, filter)
+		if err != nil {
+			t.Fatalf("list teams: %v", err)
+		}
+		var ids []string
+		for _, tm := range ts {
+			ids = append(ids, tm.ID)
+		}
+		return ids
+	}
+	if got, want := teamIDs(TeamFilter{}), []string{"tb-4", "ta-2", "tb-3", "ta-1", "tb-1"}; !slices.Equal(got, want) {
+		t.Errorf("teams: got %v, want %v", got, want)
+	}
+	if got, want := teamIDs(TeamFilter{Limit: 2, CursorTime: now, CursorID: "ta-2"}), []string{"tb-3", "ta-1"}; !slices.Equal(got, want) {
+		t.Errorf("teams after cursor: got %v, want %v", got, want)
+	}
+	if got, want := teamIDs(TeamFilter{Name: "ALP"}), []string{"ta-1"}; !slices.Equal(got, want) {
+		t.Errorf("teams named alp: got %v, want %v", got, want)
+	}
+
+	ms, err := repo.ListMatches(ctx, MatchFilter{TeamID: "ta-1"})
+	if err != nil {
+		t.Fatalf("list matches: %v", err)
+	}
+	if len(ms) != 2 || ms[0].ID != "match-002" || ms[1].ID != "match-001" {
+		t.Errorf("alpha matches: got %+v", ms)
+	}
+	if ms[0].TeamAID != "ta-2" || ms[0].TeamBID != "ta-1" {
+		t.Errorf("alpha match teams: got %s and %s", ms[0].TeamAID, ms[0].TeamBID)
+	}
+
+	// a reparse mints new IDs but keeps the teams the match was linked to
+	reparsed := matches[2]
+	reparsed.TeamAID, reparsed.TeamBID = "ta-5", "tb-5"
+	if err := repo.ReplaceMatch(ctx, reparsed); err != nil {
+		t.Fatalf("replace match: %v", err)
+	}
+	got, err := repo.GetMatch(ctx, reparsed.ID)
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	if got.TeamAID != "ta-2" || got.TeamBID != "tb-3" {
+		t.Errorf("reparsed teams: got %s and %s, want ta-2 and tb-3", got.TeamAID, got.TeamBID)
+	}
+
+	if err := repo.DeleteMatch(ctx, "match-004"); err != nil {
+		t.Fatalf("delete match: %v", err)
+	}
+	if _, err := repo.GetTeam(ctx, "tb-4"); err != ErrNotFound {
+		t.Errorf("get team of deleted match: expected ErrNotFound, got %v", err)
+	}
+	if got, want := teamIDs(TeamFilter{}), []string{"tb-3", "ta-2", "ta-1", "tb-1"}; !slices.Equal(got, want) {
+		t.Errorf("teams after delete: got %v, want %v", got, want)
+	}
+}
+
+func testTeamHeadToHead(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	matches := []Match{
+		{ID: "match-001", TeamAClanName: "Alpha", TeamBClanName: "Beta", ScoreA: 16, ScoreB: 12, Date: now.Add(-2 * time.Hour)},
+		{ID: "match-002", TeamAClanName: "Beta", TeamBClanName: "Alpha", ScoreA: 13, ScoreB: 7, Date: now.Add(-time.Hour)},
+		{ID: "match-003", TeamAClanName: "Alpha", TeamBClanName: "Gamma", ScoreA: 16, ScoreB: 2, Date: now},
+	}
+	ids := make(map[string]string)
+	for _, m := range matches {
+		m.MapName, m.DemoHash, m.CreatedAt = "de_nuke", "hash-"+m.ID, now
+		m.TeamA, m.TeamB = m.TeamAClanName, m.TeamBClanName
+		m.TeamAID, m.TeamBID = m.ID+"-a", m.ID+"-b"
+		if _, err := repo.StoreMatch(ctx, m); err != nil {
+			t.Fatalf("store match %s: %v", m.ID, err)
+		}
+		got, err := repo.GetMatch(ctx, m.ID)
+		if err != nil {
+			t.Fatalf("get match %s: %v", m.ID, err)
+		}
+		ids[m.TeamAClanName], ids[m.TeamBClanName] = got.TeamAID, got.TeamBID
+	}
+
+	for _, pair := range [][2]string{{"Alpha", "Beta"}, {"Beta", "Alpha"}} {
+		ms, err := repo.ListTeamHeadToHead(ctx, ids[pair[0]], ids[pair[1]])
+		if err != nil {
+			t.Fatalf("list head to head: %v", err)
+		}
+		if len(ms) != 2 || ms[0].ID != "match-002" || ms[1].ID != "match-001" {
+			t.Errorf("%s against %s: got %+v", pair[0], pair[1], ms)
+		}
+	}
+
+	ms, err := repo.ListTeamHeadToHead(ctx, ids["Beta"], ids["Gamma"])
+	if err != nil {
+		t.Fatalf("list head to head: %v", err)
+	}
+	if len(ms) != 0 {
+		t.Errorf("Beta against Gamma: expected no matches, got %d", len(ms))
+	}
+}
+
+func testPlayerHeadToHead(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	seed := seedMatch(t, repo) // Player One's team beats Player Two's; each kills the other once
+
+	// teammates in the second match, where a team kill is no duel
+	m := Match{
+		ID: "match-002", MapName: "de_inferno", Date: seed.Date.Add(time.Hour), TeamA: "A", TeamB: "B",
+		ScoreA: 5, ScoreB: 16, DemoHash: "hash-002", TeamAStartedAs: "CT", CreatedAt: seed.CreatedAt,
+		Players: []PlayerStats{
+			{PlayerID: "m2-p1", SteamID: "76561198001", Name: "Player One", Team: "CT", Kills: 10},
+			{PlayerID: "m2-p2", SteamID: "76561198002", Name: "Player Two", Team: "CT", Kills: 5},
+		},
+		Rounds: []Round{{ID: "m2-r1", Number: 1, WinnerTeam: "T", WinMethod: "Elimination"}},
+		KillEvents: []KillEvent{
+			{ID: "m2-k1", RoundID: "m2-r1", Attacker: "m2-p1", Victim: "m2-p2",
+				AttackerSteamID: "76561198001", VictimSteamID: "76561198002", Weapon: "AK-47", Headshot: true},
+		},
+	}
+	if _, err := repo.StoreMatch(ctx, m); err != nil {
+		t.Fatalf("store match: %v", err)
+	}
+
+	h, err := repo.GetPlayerHeadToHead(ctx, "76561198001", "76561198002")
+	if err != nil {
+		t.Fatalf("get head to head: %v", err)
+	}
+	if h.Matches != 2 || h.Opposed != 1 || h.WinsA != 1 || h.WinsB != 0 {
+		t.Errorf("matches: got %d, opposed %d, wins %d-%d", h.Matches, h.Opposed, h.WinsA, h.WinsB)
+	}
+	if h.StatsA.Matches != 2 || h.StatsA.Kills != 35 || h.StatsB.Kills != 23 || h.StatsA.Wins != 1 || h.StatsB.Losses != 2 {
+		t.Errorf("stats: got %+v and %+v", h.StatsA, h.StatsB)
+	}
+	if h.KillsA != 1 || h.KillsB != 1 || h.HeadshotKillsA != 1 || h.HeadshotKillsB != 0 {
+		t.Errorf("duels: got %d-%d, headshots %d-%d", h.KillsA, h.KillsB, h.HeadshotKillsA, h.HeadshotKillsB)
+	}
+
+	h, err = repo.GetPlayerHeadToHead(ctx, "76561198002", "76561198001")
+	if err != nil {
+		t.Fatalf("get head to head: %v", err)
+	}
+	if h.WinsA != 0 || h.WinsB != 1 || h.StatsA.Kills != 23 || h.HeadshotKillsB != 1 {
+		t.Errorf("reversed: got %+v", h)
+	}
+
+	h, err = repo.GetPlayerHeadToHead(ctx, "76561198001", "76561198999")
+	if err != nil {
+		t.Fatalf("get head to head: %v", err)
+	}
+	if h != (PlayerHeadToHead{}) {
+		t.Errorf("players without a shared match: got %+v", h)
+	}
+}
+
+func testIngestJobLifecycle(t *testing.T, repo *sqlStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	for i, id := range []string{"job-1", "job-2"} {
+		job := IngestJob{
+			ID: id, FileName: id + ".dem", DemoHash: "hash-" + id, DemoPath: "/spool/" + id,
+			Status: JobQueued, CreatedAt: now.Add(time.Duration(i) * time.Second), UpdatedAt: now,
+		}
+		if err := repo.CreateIngestJob(ctx, job); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+
+	// the oldest queued job is claimed first
+	job, err := repo.ClaimIngestJob(ctx)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if job.ID != "job-1" || job.Status != JobParsing || job.DemoPath != "/spool/job-1" {
+		t.Errorf("claim: got %s/%s/%s, want job-1/parsing//spool/job-1", job.ID, job.Status, job.DemoPath)
+	}
+
+	// a restart puts the in-flight job back in the queue
+	n, err := repo.RequeueIngestJobs(ctx)
+	if err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("requeued: got %d, want 1", n)
+	}
+
+	job, err = repo.ClaimIngestJob(ctx)
+	if err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	job.Status = JobDone
+	job.MatchID = "match-1"
+	job.UpdatedAt = now
+	if err := repo.UpdateIngestJob(ctx, job); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, err := repo.GetIngestJob(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Status != JobDone || got.MatchID != "match-1" || got.FileName != "job-1.dem" {
+		t.Errorf("get: got %s/%s/%s, want done/match-1/job-1.dem", got.Status, got.MatchID, got.FileName)
+	}
+
+	if _, err := repo.ClaimIngestJob(ctx); err != nil {
+		t.Fatalf("claim job-2: %v", err)
+	}
+	if _, err := repo.ClaimIngestJob(ctx); err != ErrNotFound {
+		t.Errorf("claim on empty queue: got %v, want ErrNotFound", err)
+	}
+
+	// listing is newest first and filterable by status
+	all, err := repo.ListIngestJobs(ctx, IngestJobFilter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != "job-2" {
+		t.Fatalf("list: got %d jobs, want job-2 first of 2", len(all))
+	}
+	done, err := repo.ListIngestJobs(ctx, IngestJobFilter{Status: JobDone})
+	if err != nil {
+		t.Fatalf("list done: %v", err)
+	}
+	if len(done) != 1 || done[0].ID != "job-1" {
+		t.Errorf("list done: got %d jobs, want only job-1", len(done))
+	}
+	page, err := repo.ListIngestJobs(ctx, IngestJobFilter{CursorTime: all[0].CreatedAt, CursorID: all[0].ID})
+	if err != nil {
+		t.Fatalf("list page: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != "job-1" {
+		t.Errorf("list page: got %d jobs, want only job-1", len(page))
+	}
+}
+
+func testFindMatchByHash(t *testing.T, repo *sqlStore) {
+	m := seedMatch(t, repo)
+
+	id, err := repo.FindMatchByHash(context.Background(), m.DemoHash)
+	if err != nil {
+		t.Fatalf("find match: %v", err)
+	}
+	if id != m.ID {
+		t.Errorf("match ID: got %s, want %s", id, m.ID)
+	}
+
+	if _, err := repo.FindMatchByHash(context.Background(), "unknown"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// teamColumns sums the matches each team played, in the order scanTeam
+// reads them. Its %s is a WHERE clause on teams t and matches m.
+const teamColumns = `SELECT tm.id, tm.name, tm.clan_name, COUNT(*) AS matches,
+		        SUM(CASE WHEN tm.rounds_won > tm.rounds_lost THEN 1 ELSE 0 END) AS wins,
+		        SUM(CASE WHEN tm.rounds_won < tm.rounds_lost THEN 1 ELSE 0 END) AS losses,
+		        MIN(tm.date) AS first_played, MAX(tm.date) AS last_played
+		 FROM (
+		   SELECT t.id, t.name, t.clan_name, m.date,
+		          CASE WHEN m.team_a_id = t.id THEN m.score_a ELSE m.score_b END AS rounds_won,
+		          CASE WHEN m.team_a_id = t.id THEN m.score_b ELSE m.score_a END AS rounds_lost
+		   FROM teams t
+		   JOIN matches m ON m.team_a_id = t.id OR m.team_b_id = t.id
+		   %s
+		 ) tm
+		 GROUP BY tm.id, tm.name, tm.clan_name`
+
+// teamOfPlayer is the team a match_players row, aliased mp, played for in
+// its match, aliased m.
+const teamOfPlayer = `CASE WHEN mp.team = COALESCE(m.team_a_started_as, 'CT') THEN m.team_a_id ELSE m.team_b_id END`
+
+// GetTeam returns the team with the given ID and everyone who played for
+// it, most recent first, or ErrNotFound.
+func (s *sqlStore) GetTeam(ctx context.Context, id string) (Team, error) {
+	t, err := scanTeam(s.db.QueryRowContext(ctx, fmt.Sprintf(teamColumns, "WHERE t.id = ?"), id))
+	if err == sql.ErrNoRows {
+		return Team{}, ErrNotFound
+	}
+	if err != nil {
+		return Team{}, fmt.Errorf("query team %s: %w", id, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT p.steam_id, p.name, COUNT(*), MIN(m.date), MAX(m.date)
+		 FROM match_players mp
+		 JOIN players p ON p.id = mp.player_id
+		 JOIN matches m ON m.id = mp.match_id
+		 WHERE `+teamOfPlayer+` = ?
+		 GROUP BY p.steam_id, p.name
+		 ORDER BY MAX(m.date) DESC, COUNT(*) DESC, p.steam_id`, id,
+	)
+	if err != nil {
+		return Team{}, fmt.Errorf("query roster of team %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			tm            TeamMember
+			first, latest string
+		)
+		if err := rows.Scan(&tm.SteamID, &tm.Name, &tm.Matches, &first, &latest); err != nil {
+			return Team{}, fmt.Errorf("scan team member: %w", err)
+		}
+		tm.FirstPlayed, _ = time.Parse(time.RFC3339, first)
+		tm.LastPlayed, _ = time.Parse(time.RFC3339, latest)
+		t.Roster = append(t.Roster, tm)
+	}
+	return t, rows.Err()
+}
+
+// ListTeams returns a page of teams, most recently played first.
+func (s *sqlStore) ListTeams(ctx context.Context, filter TeamFilter) ([]Team, error) {
+	var (
+		clauses []string
+		args    []any
+	)
+	if filter.Name != "" {
+		clauses = append(clauses, "LOWER(ts.name) LIKE ?")
+		args = append(args, "%"+strings.ToLower(filter.Name)+"%")
+	}
+	if !filter.CursorTime.IsZero() && filter.CursorID != "" {
+		clauses = append(clauses, "(ts.last_played < ? OR (ts.last_played = ? AND ts.id < ?))")
+		ct := filter.CursorTime.Format(time.RFC3339)
+		args = append(args, ct, ct, filter.CursorID)
+	}
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(
+		`SELECT ts.* FROM (%s) ts
+		 %s
+		 ORDER BY ts.last_played DESC, ts.id DESC LIMIT ?`,
+		fmt.Sprintf(teamColumns, ""), where,
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Team
+	for rows.Next() {
+		t, err := scanTeam(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan team: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// ListTeamHeadToHead returns every match the two teams played against
+// each other, newest first.
+func (s *sqlStore) ListTeamHeadToHead(ctx context.Context, teamA, teamB string) ([]MatchSummary, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+matchSummaryColumns+`
+		 FROM matches m
+		 WHERE (m.team_a_id = ? AND m.team_b_id = ?) OR (m.team_a_id = ? AND m.team_b_id = ?)
+		 ORDER BY m.date DESC, m.id DESC`,
+		teamA, teamB, teamB, teamA,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list matches of %s against %s: %w", teamA, teamB, err)
+	}
+	defer rows.Close()
+
+	var out []MatchSummary
+	for rows.Next() {
+		ms, err := scanMatchSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan match summary: %w", err)
+		}
+		out = append(out, ms)
+	}
+	return out, rows.Err()
+}
+
+// scanTeam reads a row of teamColumns.
+func scanTeam(row interface{ Scan(...any) error }) (Team, error) {
+	var (
+		t             Team
+		first, latest string
+	)
+	if err := row.Scan(&t.ID, &t.Name, &t.ClanName, &t.Matches, &t.Wins, &t.Losses, &first, &latest); err != nil {
+		return Team{}, err
+	}
+	t.FirstPlayed, _ = time.Parse(time.RFC3339, first)
+	t.LastPlayed, _ = time.Parse(time.RFC3339, latest)
+	return t, nil
+}
+
+// resolveTeams returns the teams the sides of m are linked to, as
+// described on StoreMatch. A clan name both sides share identifies
+// neither.
+func resolveTeams(ctx context.Context, tx *boundTx, m Match) (teamA, teamB string, err error) {
+	clanA, clanB := m.TeamAClanName, m.TeamBClanName
+	if clanA == clanB {
+		clanA, clanB = "", ""
+	}
+
+	startA := cmp.Or(m.TeamAStartedAs, "CT")
+	var rosterA, rosterB []PlayerStats
+	for _, ps := range m.Players {
+		switch ps.Team {
+		case startA:
+			rosterA = append(rosterA, ps)
+		case "":
+		default:
+			rosterB = append(rosterB, ps)
+		}
+	}
+
+	teamA, err = resolveTeam(ctx, tx, m.TeamAID, clanA, rosterA, "")
+	if err != nil {
+		return "", "", err
+	}
+	teamB, err = resolveTeam(ctx, tx, m.TeamBID, clanB, rosterB, teamA)
+	if err != nil {
+		return "", "", err
+	}
+	return teamA, teamB, nil
+}
+
+// resolveTeam returns the team a match side is linked to, storing a new
+// team under id if the side matches none. A side without a clan name
+// matches the team, other than exclude, that most of its players have
+// played for. It returns "" for a side without id, or without a clan name
+// and players.
+func resolveTeam(ctx context.Context, tx *boundTx, id, clan string, roster []PlayerStats, exclude string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+
+	if clan != "" {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO teams (id, name, clan_name) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+			id, clan, clan,
+		)
+		if err != nil {
+			return "", fmt.Errorf("insert team %s: %w", clan, err)
+		}
+		var teamID string
+		err = tx.QueryRowContext(ctx, `SELECT id FROM teams WHERE clan_name = ?`, clan).Scan(&teamID)
+		if err != nil {
+			return "", fmt.Errorf("resolve team %s: %w", clan, err)
+		}
+		return teamID, nil
+	}
+
+	if len(roster) == 0 {
+		return "", nil
+	}
+
+	placeholders := make([]string, len(roster))
+	args := make([]any, 0, len(roster)+1)
+	names := make([]string, len(roster))
+	for i, ps := range roster {
+		placeholders[i] = "?"
+		args = append(args, ps.SteamID)
+		names[i] = ps.Name
+	}
+	args = append(args, exclude)
+
+	var (
+		teamID  string
+		overlap int
+	)
+	err := tx.QueryRowContext(ctx,
+		`SELECT pt.team_id, COUNT(DISTINCT pt.steam_id)
+		 FROM (
+		   SELECT `+teamOfPlayer+` AS team_id, p.steam_id, m.date
+		   FROM match_players mp
+		   JOIN players p ON p.id = mp.player_id
+		   JOIN matches m ON m.id = mp.match_id
+		   WHERE p.steam_id IN (`+strings.Join(placeholders, ", ")+`)
+		 ) pt
+		 WHERE pt.team_id IS NOT NULL AND pt.team_id <> ?
+		 GROUP BY pt.team_id
+		 ORDER BY COUNT(DISTINCT pt.steam_id) DESC, MAX(pt.date) DESC, pt.team_id
+		 LIMIT 1`, args...,
+	).Scan(&teamID, &overlap)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("match roster to a team: %w", err)
+	}
+	if err == nil && overlap*2 > len(roster) {
+		return teamID, nil
+	}
+
+	// a team without a clan name is known by its players
+	slices.Sort(names)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO teams (id, name, clan_name) VALUES (?, ?, '')`,
+		id, strings.Join(names, ", "),
+	)
+	if err != nil {
+		return "", fmt.Errorf("insert team %s: %w", id, err)
+	}
+	return id, nil
+}
+
+// pruneTeams deletes teams no match side is linked to.
+func pruneTeams(ctx context.Context, tx *boundTx) error {
+	_, err := tx.ExecContext(ctx,
+		`DELETE FROM teams WHERE id NOT IN (SELECT team_a_id FROM matches WHERE team_a_id IS NOT NULL)
+		 AND id NOT IN (SELECT team_b_id FROM matches WHERE team_b_id IS NOT NULL)`,
+	)
+	if err != nil {
+		return fmt.Errorf("prune teams: %w", err)
+	}
+	return nil
+}
@@ -14,11 +14,19 @@ type Match struct {
 	ScoreB          int
 	DemoHash        string
 	TeamAStartedAs  string
+	ParserVersion   int
 	CreatedAt       time.Time
+	TeamAID         string // see StoreMatch; empty for a side not linked to a team
+	TeamBID         string
+	TeamAClanName   string // empty when the demo has none
+	TeamBClanName   string
 	Players         []PlayerStats
 	Rounds          []Round
 	Economy         []EconomyRound
 	KillEvents      []KillEvent
+	DamageEvents    []DamageEvent
+	Replays         []RoundReplay
+	PlayerRounds    []PlayerRound
 }
 
 // MatchSummary is a lightweight match listing entry.
@@ -32,6 +40,8 @@ type MatchSummary struct {
 	ScoreA          int
 	ScoreB          int
 	TeamAStartedAs  string
+	TeamAID         string // empty for a side not linked to a team
+	TeamBID         string
 	CreatedAt       time.Time
 }
 
@@ -41,6 +51,7 @@ type MatchFilter struct {
 	DateFrom     time.Time
 	DateTo       time.Time
 	PlayerSteam  string
+	TeamID       string
 	Limit        int
 	CursorTime   time.Time
 	CursorID     string
@@ -69,6 +80,29 @@ type PlayerStats struct {
 	Rating        float64
 	FlashAssists  int
 	UtilityDamage int
+
+	KPR    float64
+	DPR    float64
+	Impact float64
+
+	Headshots   int
+	TotalDamage int
+	Survived    int // rounds survived
+	FirstKills  int
+	FirstDeaths int
+	TradeKills  int
+
+	// rounds with exactly 2, 3, 4 and 5 kills
+	TwoK   int
+	ThreeK int
+	FourK  int
+	FiveK  int
+
+	EnemiesFlashed        int
+	TeammatesFlashed      int
+	EnemyBlindDuration    float64 // seconds
+	AvgEnemyBlindDuration float64 // seconds per enemy flashed
+	FlashesLeadingToKill  int
 }
 
 // Round represents a single round in a match.
@@ -89,6 +123,9 @@ type Round struct {
 	BombPlantRoundTime float64
 	BombDefuseSteamID  string
 	BombDefuseRoundTime float64
+	TeamASide          string // side played by team A this round
+	TeamAWon           bool
+	DurationSeconds    float64 // from freeze-time end to round end
 	Clutch             *Clutch
 }
 
@@ -110,6 +147,7 @@ type EconomyRound struct {
 	Spend          int
 	EquipmentValue int
 	BuyType        string
+	TeamA          bool // the row belongs to team A; populated on reads only
 }
 
 // KillEvent records a single kill with positional data.
@@ -131,3 +169,326 @@ type KillEvent struct {
 	VictimY         float64
 	VictimZ         float64
 }
+
+// DamageEvent records a single hit with positional data. Damage derived
+// from CS2 entity counters is instead an attacker's total for the round,
+// marked Aggregate, with empty victim fields.
+type DamageEvent struct {
+	ID              string
+	RoundID         string
+	MatchID         string
+	RoundNum        int
+	Tick            int
+	Attacker        string // player ID
+	Victim          string // player ID
+	AttackerSteamID string
+	VictimSteamID   string
+	Weapon          string
+	HitGroup        string
+	HealthDamage    int
+	ArmorDamage     int
+	AttackerX       float64
+	AttackerY       float64
+	AttackerZ       float64
+	VictimX         float64
+	VictimY         float64
+	VictimZ         float64
+	Aggregate       bool
+}
+
+// PlayerRound holds one player's stats for a single round. RoundNumber,
+// Name and BuyType are populated on reads only; BuyType is the buy type of
+// the player's team that round.
+type PlayerRound struct {
+	RoundID        string
+	MatchID        string
+	RoundNumber    int
+	PlayerID       string
+	SteamID        string
+	Name           string
+	Side           string
+	Kills          int
+	Deaths         int
+	Assists        int
+	Damage         int
+	Survived       bool
+	Traded         bool
+	KAST           bool
+	EquipmentValue int
+	MoneySpent     int
+	BuyType        string
+}
+
+// RoundReplay holds the sampled player states for one round.
+type RoundReplay struct {
+	RoundID string
+	Frames  []ReplayFrame
+}
+
+// ReplayFrame holds every living player's state at one sampled tick.
+type ReplayFrame struct {
+	Tick    int
+	Players []ReplaySample
+}
+
+// ReplaySample is one player's state in a ReplayFrame. Stored values are
+// quantized: positions to 1/16 unit and view angles to 1/10 degree.
+type ReplaySample struct {
+	SteamID string
+	X       float64
+	Y       float64
+	Z       float64
+	Yaw     float64
+	Pitch   float64
+	Health  int
+	Armor   int
+	Weapon  string
+	Money   int
+}
+
+// Ingest job states, in the order a successful job moves through them.
+const (
+	JobQueued  = "queued"
+	JobParsing = "parsing"
+	JobStoring = "storing"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// IngestJob tracks an uploaded demo through background ingestion. DemoPath
+// is the spooled upload on disk. MatchID is set once the job is done and
+// Error once it has failed.
+type IngestJob struct {
+	ID        string
+	FileName  string
+	DemoHash  string
+	DemoPath  string
+	Status    string
+	Error     string
+	MatchID   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IngestJobFilter constrains ingest job listing queries.
+type IngestJobFilter struct {
+	Status     string
+	Limit      int
+	CursorTime time.Time
+	CursorID   string
+}
+
+// PlayerFilter constrains the matches a player's stats are read from.
+// Limit and the cursor apply to ListPlayerMatches only.
+type PlayerFilter struct {
+	MapName    string
+	DateFrom   time.Time
+	DateTo     time.Time
+	Limit      int
+	CursorTime time.Time
+	CursorID   string
+}
+
+// PlayerMatch is one match in a player's history. RoundsWon and
+// RoundsLost are the score from the player's team's point of view.
+type PlayerMatch struct {
+	Match        MatchSummary
+	Stats        PlayerStats
+	RoundsPlayed int
+	RoundsWon    int
+	RoundsLost   int
+}
+
+// PlayerMapStats holds a player's stats summed over the matches they
+// played on one map. Rate stats are averaged weighted by rounds played,
+// and HeadshotPct by kills.
+type PlayerMapStats struct {
+	MapName      string
+	Matches      int
+	Wins         int
+	Losses       int
+	RoundsPlayed int
+
+	Kills         int
+	Deaths        int
+	Assists       int
+	Headshots     int
+	TotalDamage   int
+	FirstKills    int
+	FirstDeaths   int
+	TradeKills    int
+	FlashAssists  int
+	UtilityDamage int
+	TwoK          int
+	ThreeK        int
+	FourK         int
+	FiveK         int
+
+	ADR         float64
+	KAST        float64
+	HeadshotPct float64
+	Rating      float64
+	KPR         float64
+	DPR         float64
+	Impact      float64
+}
+
+// PlayerSideStats holds a player's per-round stats summed over the rounds
+// they played on one side of one map.
+type PlayerSideStats struct {
+	MapName   string
+	Side      string
+	Rounds    int
+	RoundsWon int
+	Kills     int
+	Deaths    int
+	Assists   int
+	Damage    int
+	Survived  int
+	KAST      int // rounds with a kill, assist, survival or trade
+}
+
+// PlayerHeadToHead compares two players, A and B, over the matches both
+// played. WinsA and WinsB count the Opposed matches each player's team
+// won, and the kill counts are of one player by the other.
+type PlayerHeadToHead struct {
+	Matches int
+	Opposed int // matches the players played on opposing teams
+	WinsA   int
+	WinsB   int
+
+	StatsA PlayerMapStats // MapName is empty
+	StatsB PlayerMapStats
+
+	KillsA         int // of B by A
+	KillsB         int // of A by B
+	HeadshotKillsA int
+	HeadshotKillsB int
+}
+
+// Leaderboard metrics players can be ranked by.
+const (
+	MetricRating             = "rating"
+	MetricADR                = "adr"
+	MetricKAST               = "kast"
+	MetricHeadshotPct        = "hs_pct"
+	MetricOpeningKillSuccess = "opening_kill_success"
+	MetricClutchWinRate      = "clutch_win_rate"
+)
+
+// LeaderboardFilter constrains leaderboard queries. Only players with at
+// least MinRounds rounds and MinMatches matches among the matches picked
+// are ranked. The cursor is the metric value and steam ID of the last
+// entry of the previous page.
+type LeaderboardFilter struct {
+	Metric        string
+	MapName       string
+	DateFrom      time.Time
+	DateTo        time.Time
+	MinRounds     int
+	MinMatches    int
+	Limit         int
+	CursorValue   float64
+	CursorSteamID string
+}
+
+// LeaderboardEntry is one ranked player. Value is the player's value of
+// the metric ranked by; players with equal values share a rank. Rate stats
+// are averaged the same way as in PlayerMapStats; OpeningKillSuccess and
+// ClutchWinRate are percentages of opening duels and clutches.
+type LeaderboardEntry struct {
+	Rank         int
+	SteamID      string
+	Name         string
+	Value        float64
+	Matches      int
+	RoundsPlayed int
+	Kills        int
+	Deaths       int
+	FirstKills   int
+	FirstDeaths  int
+	Clutches     int
+	ClutchesWon  int
+
+	Rating             float64
+	ADR                float64
+	KAST               float64
+	HeadshotPct        float64
+	OpeningKillSuccess float64
+	ClutchWinRate      float64
+}
+
+// MapStatsFilter constrains map stats queries. With a SteamID only the
+// matches that player played are counted, and with a TeamName only those
+// that team played; either makes the stats their team's. Unfiltered, the
+// side and round stats cover both teams.
+type MapStatsFilter struct {
+	MapName  string
+	SteamID  string
+	TeamName string
+}
+
+// MapStats holds round outcomes summed over the matches on one map. Side,
+// win method and plant counts cover the rounds of the team the filter
+// picks; Wins and Losses are zero without a player or team filter.
+type MapStats struct {
+	MapName string
+	Matches int
+	Wins    int
+	Losses  int
+	Rounds  int // rounds stored with team A's side
+
+	CTRounds          int
+	CTRoundsWon       int
+	TRounds           int
+	TRoundsWon        int
+	CTPistolRounds    int
+	CTPistolRoundsWon int
+	TPistolRounds     int
+	TPistolRoundsWon  int
+
+	// rounds won by each method
+	Eliminations  int
+	BombsExploded int
+	BombsDefused  int
+	TimeExpired   int
+
+	AvgRoundDuration float64 // seconds, over rounds stored with a duration
+	BombPlants       int
+	PlantsA          int
+	PlantsB          int
+}
+
+// Team is a team tracked across matches. Matches, Wins and Losses count
+// the matches a side was linked to it.
+type Team struct {
+	ID          string
+	Name        string
+	ClanName    string // empty for a team identified by its roster
+	Matches     int
+	Wins        int
+	Losses      int
+	FirstPlayed time.Time
+	LastPlayed  time.Time
+	Roster      []TeamMember // only set by GetTeam
+}
+
+// TeamMember is a player who played for a team, with the span of their
+// matches for it.
+type TeamMember struct {
+	SteamID     string
+	Name        string
+	Matches     int
+	FirstPlayed time.Time
+	LastPlayed  time.Time
+}
+
+// TeamFilter constrains team listing queries. Name matches part of a team
+// name, ignoring case. The cursor is the LastPlayed and ID of the last
+// team of the previous page.
+type TeamFilter struct {
+	Name       string
+	Limit      int
+	CursorTime time.Time
+	CursorID   string
+}
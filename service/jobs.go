@@ -0,0 +1,409 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zarldev/cs2stats/parser"
+	"github.com/zarldev/cs2stats/repository"
+)
+
+// pollInterval bounds how long an idle worker sleeps before checking the
+// queue again, in case a wake-up was missed or a job was queued by another
+// process sharing the database.
+const pollInterval = 5 * time.Second
+
+// ErrEmptyDemo is returned when a submitted demo has no content.
+var ErrEmptyDemo = errors.New("empty demo")
+
+// Jobs ingests uploaded demos in the background. Submitted demos are
+// spooled to a directory on disk and recorded as queued jobs in the
+// repository, then picked up by a fixed pool of workers that parse them
+// straight from the file. Callers never wait on the parser, memory use
+// does not grow with upload size, and jobs interrupted by a restart are
+// resumed by the next Run.
+type Jobs struct {
+	svc     *Service
+	dir     string
+	workers int
+	wake    chan struct{}
+
+	mu       sync.Mutex
+	watchers map[string][]chan IngestJob
+	progress map[string]float64 // last reported progress of jobs being parsed
+}
+
+// NewJobs creates a job runner that spools uploads to dir and ingests
+// them through svc with the given number of workers. The directory is
+// created if needed. Call Run to start processing.
+func NewJobs(svc *Service, dir string, workers int) (*Jobs, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return &Jobs{
+		svc:      svc,
+		dir:      dir,
+		workers:  workers,
+		wake:     make(chan struct{}, workers),
+		watchers: make(map[string][]chan IngestJob),
+		progress: make(map[string]float64),
+	}, nil
+}
+
+// Run requeues jobs left unfinished by a previous run and processes the
+// queue until ctx is done. It returns once every worker has stopped. A job
+// in progress when ctx ends stays unfinished and is picked up again by the
+// next Run.
+func (j *Jobs) Run(ctx context.Context) error {
+	if _, err := j.svc.repo.RequeueIngestJobs(ctx); err != nil {
+		return fmt.Errorf("requeue ingest jobs: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for range j.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Submit spools the demos read from r and queues each for ingestion,
+// returning one job per demo. Demos compressed with gzip, bzip2 or zstd
+// are decompressed as they are spooled, and every .dem file in a zip
+// archive becomes its own job named after the file in the archive. Each
+// demo is hashed on its decompressed content. A demo that is already
+// stored is not queued; its entry carries only the demo hash and the
+// existing match ID. Submit fails with ErrEmptyDemo if r yields nothing
+// and with repository.ErrDuplicateDemo if every demo is already stored.
+// On any other error the jobs queued before it are returned with it.
+func (j *Jobs) Submit(ctx context.Context, fileName string, r io.Reader) ([]IngestJob, error) {
+	var (
+		jobs []IngestJob
+		dups int
+	)
+	err := parser.Demos(r, func(name string, demo io.Reader) error {
+		if name == "" {
+			name = fileName
+		}
+		job, err := j.submit(ctx, name, demo)
+		if errors.Is(err, repository.ErrDuplicateDemo) {
+			dups++
+		} else if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	if err != nil {
+		return jobs, err
+	}
+	if dups == len(jobs) {
+		return jobs, fmt.Errorf("demo already stored: %w", repository.ErrDuplicateDemo)
+	}
+	return jobs, nil
+}
+
+// submit spools and queues a single uncompressed demo.
+func (j *Jobs) submit(ctx context.Context, fileName string, r io.Reader) (IngestJob, error) {
+	path, hash, err := j.spool(r)
+	if err != nil {
+		return IngestJob{}, err
+	}
+	queued := false
+	defer func() {
+		if !queued {
+			os.Remove(path)
+		}
+	}()
+
+	matchID, err := j.svc.repo.FindMatchByHash(ctx, hash)
+	if err == nil {
+		existing := IngestJob{FileName: fileName, DemoHash: hash, MatchID: matchID}
+		return existing, fmt.Errorf("demo stored as match %s: %w", matchID, repository.ErrDuplicateDemo)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return IngestJob{}, fmt.Errorf("check demo hash: %w", err)
+	}
+
+	now := time.Now()
+	job := repository.IngestJob{
+		ID:        uuid.New().String(),
+		FileName:  fileName,
+		DemoHash:  hash,
+		DemoPath:  path,
+		Status:    repository.JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := j.svc.repo.CreateIngestJob(ctx, job); err != nil {
+		return IngestJob{}, fmt.Errorf("create ingest job: %w", err)
+	}
+	queued = true
+
+	// wake an idle worker without blocking if they are all busy
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+
+	return mapRepoJob(job), nil
+}
+
+// spool copies r to a new file in the upload directory and returns its
+// path and the hex sha256 of its content.
+func (j *Jobs) spool(r io.Reader) (path, hash string, err error) {
+	f, err := os.CreateTemp(j.dir, "upload-*.dem")
+	if err != nil {
+		return "", "", fmt.Errorf("create spool file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return "", "", fmt.Errorf("spool upload: %w", err)
+	}
+	if n == 0 {
+		return "", "", ErrEmptyDemo
+	}
+	if err = f.Close(); err != nil {
+		return "", "", fmt.Errorf("close spool file: %w", err)
+	}
+	return f.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GetIngestJob returns a job by ID, with its progress if it is being
+// parsed by this runner.
+func (j *Jobs) GetIngestJob(ctx context.Context, id string) (IngestJob, error) {
+	job, err := j.svc.repo.GetIngestJob(ctx, id)
+	if err != nil {
+		return IngestJob{}, fmt.Errorf("get ingest job %s: %w", id, err)
+	}
+	state := mapRepoJob(job)
+	if state.Status == JobParsing {
+		j.mu.Lock()
+		state.Progress = j.progress[id]
+		j.mu.Unlock()
+	}
+	return state, nil
+}
+
+// ListIngestJobs returns a paginated list of jobs, newest first.
+func (j *Jobs) ListIngestJobs(ctx context.Context, filter IngestJobFilter) ([]IngestJob, error) {
+	js, err := j.svc.repo.ListIngestJobs(ctx, repository.IngestJobFilter{
+		Status:     string(filter.Status),
+		Limit:      filter.Limit,
+		CursorTime: filter.CursorTime,
+		CursorID:   filter.CursorID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list ingest jobs: %w", err)
+	}
+	return mapRepoJobs(js), nil
+}
+
+// WatchIngestJob returns a channel that receives the job's current state
+// and then each change until the job finishes or ctx is done, after which
+// the channel is closed. Intermediate states may be skipped if the
+// receiver falls behind, but the final state is always delivered.
+func (j *Jobs) WatchIngestJob(ctx context.Context, id string) (<-chan IngestJob, error) {
+	// subscribe before reading the current state so no change is missed
+	sub := make(chan IngestJob, 1)
+	j.mu.Lock()
+	j.watchers[id] = append(j.watchers[id], sub)
+	j.mu.Unlock()
+
+	job, err := j.GetIngestJob(ctx, id)
+	if err != nil {
+		j.unwatch(id, sub)
+		return nil, err
+	}
+
+	out := make(chan IngestJob)
+	go func() {
+		defer close(out)
+		defer j.unwatch(id, sub)
+
+		cur := job
+		for {
+			select {
+			case out <- cur:
+			case <-ctx.Done():
+				return
+			}
+			if cur.Finished() {
+				return
+			}
+			select {
+			case cur = <-sub:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (j *Jobs) unwatch(id string, sub chan IngestJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	subs := j.watchers[id]
+	for i, s := range subs {
+		if s == sub {
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(j.watchers, id)
+		return
+	}
+	j.watchers[id] = subs
+}
+
+// publish hands the job's new state to its watchers, replacing any state
+// they have not received yet, and remembers its progress while it is
+// parsing.
+func (j *Jobs) publish(state IngestJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if state.Status == JobParsing {
+		j.progress[state.ID] = state.Progress
+	} else {
+		delete(j.progress, state.ID)
+	}
+
+	for _, sub := range j.watchers[state.ID] {
+		select {
+		case <-sub:
+		default:
+		}
+		sub <- state
+	}
+}
+
+func (j *Jobs) work(ctx context.Context) {
+	for {
+		job, err := j.svc.repo.ClaimIngestJob(ctx)
+		if err == nil {
+			j.process(ctx, job)
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		// queue empty or claim failed: wait for a submit or the next poll
+		select {
+		case <-ctx.Done():
+			return
+		case <-j.wake:
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// process parses and stores a claimed job, reading its demo from the
+// spool file.
+func (j *Jobs) process(ctx context.Context, job repository.IngestJob) {
+	j.publish(mapRepoJob(job))
+
+	f, err := os.Open(job.DemoPath)
+	if err != nil {
+		j.fail(ctx, job, fmt.Errorf("open spooled demo: %w", err))
+		return
+	}
+	defer f.Close()
+
+	parsed, err := j.svc.parse(ctx, f, func(p parser.Progress) {
+		state := mapRepoJob(job)
+		state.Progress = p.Percent
+		j.publish(state)
+	})
+	if err != nil {
+		j.fail(ctx, job, err)
+		return
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		j.fail(ctx, job, fmt.Errorf("rewind spooled demo: %w", err))
+		return
+	}
+	if err := j.svc.keep(ctx, job.DemoHash, f); err != nil {
+		j.fail(ctx, job, err)
+		return
+	}
+
+	if err := j.update(ctx, &job, repository.JobStoring); err != nil {
+		log.Printf("ingest job %s: %v", job.ID, err)
+		return
+	}
+
+	matchID, err := j.svc.store(ctx, parsed, job.DemoHash)
+	if err != nil {
+		j.fail(ctx, job, err)
+		return
+	}
+
+	job.MatchID = matchID
+	if err := j.update(ctx, &job, repository.JobDone); err != nil {
+		log.Printf("ingest job %s: %v", job.ID, err)
+	}
+}
+
+// fail marks the job failed with err, unless err came from ctx ending, in
+// which case the job is left for the next Run. A demo that turned out to
+// be stored already keeps a link to the existing match.
+func (j *Jobs) fail(ctx context.Context, job repository.IngestJob, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicateDemo) {
+		if id, ferr := j.svc.repo.FindMatchByHash(ctx, job.DemoHash); ferr == nil {
+			job.MatchID = id
+		}
+	}
+	job.Error = err.Error()
+	if uerr := j.update(ctx, &job, repository.JobFailed); uerr != nil {
+		log.Printf("ingest job %s: %v", job.ID, uerr)
+	}
+}
+
+// update records the job's new status and notifies watchers. The spooled
+// demo is removed once the job is done or failed.
+func (j *Jobs) update(ctx context.Context, job *repository.IngestJob, status string) error {
+	job.Status = status
+	job.UpdatedAt = time.Now()
+	if err := j.svc.repo.UpdateIngestJob(ctx, *job); err != nil {
+		return fmt.Errorf("update ingest job %s: %w", job.ID, err)
+	}
+	if status == repository.JobDone || status == repository.JobFailed {
+		os.Remove(job.DemoPath)
+	}
+	j.publish(mapRepoJob(*job))
+	return nil
+}
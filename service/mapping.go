@@ -9,10 +9,9 @@ import (
 	"github.com/zarldev/cs2stats/repository"
 )
 
-// mapParsedMatch converts a parsed demo into repository types for storage.
-// It generates all IDs and maps every nested structure.
-func mapParsedMatch(pm *parser.Match, demoHash string) repository.Match {
-	matchID := uuid.New().String()
+// mapParsedMatch converts a parsed demo into repository types for storage
+// under matchID. It generates all other IDs and maps every nested structure.
+func mapParsedMatch(pm *parser.Match, matchID, demoHash string) repository.Match {
 	now := time.Now()
 
 	// build player ID lookup: steam_id string -> generated player UUID
@@ -43,14 +42,39 @@ func mapParsedMatch(pm *parser.Match, demoHash string) repository.Match {
 			Rating:        p.Stats.Rating,
 			FlashAssists:  p.Stats.FlashAssists,
 			UtilityDamage: p.Stats.UtilityDamage,
+
+			KPR:    p.Stats.KPR,
+			DPR:    p.Stats.DPR,
+			Impact: p.Stats.Impact,
+
+			Headshots:   p.Stats.Headshots,
+			TotalDamage: p.Stats.TotalDamage,
+			Survived:    p.Stats.Survived,
+			FirstKills:  p.Stats.FirstKills,
+			FirstDeaths: p.Stats.FirstDeaths,
+			TradeKills:  p.Stats.TradeKills,
+
+			TwoK:   p.Stats.MultiKills[2],
+			ThreeK: p.Stats.MultiKills[3],
+			FourK:  p.Stats.MultiKills[4],
+			FiveK:  p.Stats.MultiKills[5],
+
+			EnemiesFlashed:        p.Stats.EnemiesFlashed,
+			TeammatesFlashed:      p.Stats.TeammatesFlashed,
+			EnemyBlindDuration:    p.Stats.EnemyBlindDuration,
+			AvgEnemyBlindDuration: p.Stats.AvgEnemyBlindDuration,
+			FlashesLeadingToKill:  p.Stats.FlashesLeadingToKill,
 		})
 	}
 
 	// map rounds, economy, kill events
 	var (
-		rounds []repository.Round
-		econ   []repository.EconomyRound
-		kills  []repository.KillEvent
+		rounds  []repository.Round
+		econ    []repository.EconomyRound
+		kills   []repository.KillEvent
+		damage  []repository.DamageEvent
+		replays []repository.RoundReplay
+		prounds []repository.PlayerRound
 	)
 
 	for _, r := range pm.Rounds {
@@ -83,6 +107,9 @@ func mapParsedMatch(pm *parser.Match, demoHash string) repository.Match {
 			FirstDeathSteamID:   firstDeathSteamID,
 			FirstKillWeapon:     firstKillWeapon,
 			FirstKillRoundTime:  firstKillRoundTime,
+			TeamASide:           r.TeamSides[0].String(),
+			TeamAWon:            r.WinnerTeam() == 0,
+			DurationSeconds:     r.Duration.Seconds(),
 		}
 
 		if r.BombPlant != nil {
@@ -146,26 +173,85 @@ func mapParsedMatch(pm *parser.Match, demoHash string) repository.Match {
 				VictimZ:         k.VictimPosition.Z,
 			})
 		}
+
+		// damage events
+		for _, d := range r.Damage {
+			de := repository.DamageEvent{
+				ID:           uuid.New().String(),
+				RoundID:      roundID,
+				Tick:         d.Tick,
+				Attacker:     playerIDs[steamIDStr(d.AttackerSteamID)],
+				Victim:       playerIDs[steamIDStr(d.VictimSteamID)],
+				Weapon:       d.Weapon,
+				HitGroup:     d.HitGroup,
+				HealthDamage: d.HealthDamage,
+				ArmorDamage:  d.ArmorDamage,
+				AttackerX:    d.AttackerPosition.X,
+				AttackerY:    d.AttackerPosition.Y,
+				AttackerZ:    d.AttackerPosition.Z,
+				VictimX:      d.VictimPosition.X,
+				VictimY:      d.VictimPosition.Y,
+				VictimZ:      d.VictimPosition.Z,
+				Aggregate:    d.Aggregate,
+			}
+			// world damage and entity-derived damage have no attacker/victim
+			if d.AttackerSteamID != 0 {
+				de.AttackerSteamID = steamIDStr(d.AttackerSteamID)
+			}
+			if d.VictimSteamID != 0 {
+				de.VictimSteamID = steamIDStr(d.VictimSteamID)
+			}
+			damage = append(damage, de)
+		}
+
+		// per-player round rows
+		for _, p := range r.Players {
+			sid := steamIDStr(p.SteamID)
+			prounds = append(prounds, repository.PlayerRound{
+				RoundID:        roundID,
+				MatchID:        matchID,
+				PlayerID:       playerIDs[sid],
+				SteamID:        sid,
+				Side:           p.Side.String(),
+				Kills:          p.Kills,
+				Deaths:         p.Deaths,
+				Assists:        p.Assists,
+				Damage:         p.Damage,
+				Survived:       p.Survived,
+				Traded:         p.Traded,
+				KAST:           p.KAST,
+				EquipmentValue: p.EquipmentValue,
+				MoneySpent:     p.MoneySpent,
+			})
+		}
+
+		// sampled player states for 2D replay
+		if len(r.Frames) > 0 {
+			replays = append(replays, repository.RoundReplay{
+				RoundID: roundID,
+				Frames:  mapReplayFrames(r.Frames),
+			})
+		}
 	}
 
 	// Bug 4: prefer per-round winner counts when they match total rounds
-	ctScore := pm.Teams[0].Score
-	tScore := pm.Teams[1].Score
+	scoreA := pm.Teams[0].Score
+	scoreB := pm.Teams[1].Score
 	if len(rounds) > 0 {
-		roundCT := 0
-		roundT := 0
-		for _, r := range rounds {
-			switch r.WinnerTeam {
-			case "CT":
-				roundCT++
-			case "T":
-				roundT++
+		roundA, roundB := 0, 0
+		for _, r := range pm.Rounds {
+			switch r.WinnerTeam() {
+			case 0:
+				roundA++
+			case 1:
+				roundB++
 			}
 		}
-		// use round counts when they cover all rounds (i.e. sum equals total rounds played)
-		if roundCT+roundT == len(rounds) && roundCT+roundT == ctScore+tScore {
-			ctScore = roundCT
-			tScore = roundT
+		// use round counts only when every round has a known winning team
+		// and they cover all rounds played
+		if roundA+roundB == len(rounds) && len(rounds) == scoreA+scoreB {
+			scoreA = roundA
+			scoreB = roundB
 		}
 	}
 
@@ -176,18 +262,49 @@ func mapParsedMatch(pm *parser.Match, demoHash string) repository.Match {
 		DurationSeconds: int(pm.Duration.Seconds()),
 		TeamA:           pm.Teams[0].Name,
 		TeamB:           pm.Teams[1].Name,
-		ScoreA:          ctScore,
-		ScoreB:          tScore,
+		ScoreA:          scoreA,
+		ScoreB:          scoreB,
 		DemoHash:        demoHash,
 		TeamAStartedAs:  pm.Teams[0].StartedAs.String(),
+		TeamAID:         uuid.New().String(),
+		TeamBID:         uuid.New().String(),
+		TeamAClanName:   pm.Teams[0].ClanName,
+		TeamBClanName:   pm.Teams[1].ClanName,
+		ParserVersion:   parser.Version,
 		CreatedAt:       now,
 		Players:         players,
 		Rounds:          rounds,
 		Economy:         econ,
 		KillEvents:      kills,
+		DamageEvents:    damage,
+		Replays:         replays,
+		PlayerRounds:    prounds,
 	}
 }
 
+func mapReplayFrames(fs []parser.ReplayFrame) []repository.ReplayFrame {
+	out := make([]repository.ReplayFrame, len(fs))
+	for i, f := range fs {
+		players := make([]repository.ReplaySample, len(f.Players))
+		for j, p := range f.Players {
+			players[j] = repository.ReplaySample{
+				SteamID: steamIDStr(p.SteamID),
+				X:       p.Position.X,
+				Y:       p.Position.Y,
+				Z:       p.Position.Z,
+				Yaw:     p.Yaw,
+				Pitch:   p.Pitch,
+				Health:  p.Health,
+				Armor:   p.Armor,
+				Weapon:  p.Weapon,
+				Money:   p.Money,
+			}
+		}
+		out[i] = repository.ReplayFrame{Tick: f.Tick, Players: players}
+	}
+	return out
+}
+
 func steamIDStr(id uint64) string {
 	return fmt.Sprintf("%d", id)
 }
@@ -205,6 +322,9 @@ func mapRepoMatchToDetail(m repository.Match) MatchDetail {
 		ScoreB:          m.ScoreB,
 		DemoHash:        m.DemoHash,
 		TeamAStartedAs:  m.TeamAStartedAs,
+		ParserVersion:   m.ParserVersion,
+		TeamAID:         m.TeamAID,
+		TeamBID:         m.TeamBID,
 	}
 }
 
@@ -222,6 +342,8 @@ func mapRepoSummaries(ms []repository.MatchSummary) []MatchSummary {
 			ScoreA:          m.ScoreA,
 			ScoreB:          m.ScoreB,
 			TeamAStartedAs:  m.TeamAStartedAs,
+			TeamAID:         m.TeamAID,
+			TeamBID:         m.TeamBID,
 			CreatedAt:       m.CreatedAt,
 		}
 	}
@@ -246,6 +368,28 @@ func mapRepoPlayerStats(ps []repository.PlayerStats) []PlayerStats {
 			Rating:        p.Rating,
 			FlashAssists:  p.FlashAssists,
 			UtilityDamage: p.UtilityDamage,
+
+			KPR:    p.KPR,
+			DPR:    p.DPR,
+			Impact: p.Impact,
+
+			Headshots:   p.Headshots,
+			TotalDamage: p.TotalDamage,
+			Survived:    p.Survived,
+			FirstKills:  p.FirstKills,
+			FirstDeaths: p.FirstDeaths,
+			TradeKills:  p.TradeKills,
+
+			TwoK:   p.TwoK,
+			ThreeK: p.ThreeK,
+			FourK:  p.FourK,
+			FiveK:  p.FiveK,
+
+			EnemiesFlashed:        p.EnemiesFlashed,
+			TeammatesFlashed:      p.TeammatesFlashed,
+			EnemyBlindDuration:    p.EnemyBlindDuration,
+			AvgEnemyBlindDuration: p.AvgEnemyBlindDuration,
+			FlashesLeadingToKill:  p.FlashesLeadingToKill,
 		}
 	}
 	return out
@@ -265,6 +409,8 @@ func mapRepoRounds(rs []repository.Round) []RoundEvent {
 			FirstDeathSteamID:  r.FirstDeathSteamID,
 			FirstKillWeapon:    r.FirstKillWeapon,
 			FirstKillRoundTime: r.FirstKillRoundTime,
+			TeamASide:          r.TeamASide,
+			TeamAWon:           r.TeamAWon,
 		}
 		if r.BombPlantSteamID != "" {
 			out[i].Plant = &PlantEvent{
@@ -298,6 +444,7 @@ func mapRepoEconomy(es []repository.EconomyRound) []EconomyData {
 		out[i] = EconomyData{
 			RoundNumber:    e.RoundNumber,
 			Team:           e.Team,
+			TeamA:          e.TeamA,
 			Spend:          e.Spend,
 			EquipmentValue: e.EquipmentValue,
 			BuyType:        e.BuyType,
@@ -328,3 +475,267 @@ func mapRepoKills(ks []repository.KillEvent) []KillPosition {
 	}
 	return out
 }
+
+// mapRepoDamage converts repository damage events to service damage events.
+func mapRepoDamage(ds []repository.DamageEvent) []DamageEvent {
+	out := make([]DamageEvent, len(ds))
+	for i, d := range ds {
+		out[i] = DamageEvent{
+			RoundNumber:     d.RoundNum,
+			Tick:            d.Tick,
+			AttackerSteamID: d.AttackerSteamID,
+			VictimSteamID:   d.VictimSteamID,
+			Weapon:          d.Weapon,
+			HitGroup:        d.HitGroup,
+			HealthDamage:    d.HealthDamage,
+			ArmorDamage:     d.ArmorDamage,
+			AttackerX:       d.AttackerX,
+			AttackerY:       d.AttackerY,
+			AttackerZ:       d.AttackerZ,
+			VictimX:         d.VictimX,
+			VictimY:         d.VictimY,
+			VictimZ:         d.VictimZ,
+			Aggregate:       d.Aggregate,
+		}
+	}
+	return out
+}
+
+// mapRepoReplay converts repository replay frames to service replay frames.
+func mapRepoReplay(fs []repository.ReplayFrame) []ReplayFrame {
+	out := make([]ReplayFrame, len(fs))
+	for i, f := range fs {
+		players := make([]ReplayPlayer, len(f.Players))
+		for j, p := range f.Players {
+			players[j] = ReplayPlayer{
+				SteamID: p.SteamID,
+				X:       p.X,
+				Y:       p.Y,
+				Z:       p.Z,
+				Yaw:     p.Yaw,
+				Pitch:   p.Pitch,
+				Health:  p.Health,
+				Armor:   p.Armor,
+				Weapon:  p.Weapon,
+				Money:   p.Money,
+			}
+		}
+		out[i] = ReplayFrame{Tick: f.Tick, Players: players}
+	}
+	return out
+}
+
+// mapRepoPlayerRounds converts repository player rounds to service types.
+func mapRepoPlayerRounds(prs []repository.PlayerRound) []PlayerRoundStats {
+	out := make([]PlayerRoundStats, len(prs))
+	for i, p := range prs {
+		out[i] = PlayerRoundStats{
+			RoundNumber:    p.RoundNumber,
+			SteamID:        p.SteamID,
+			Name:           p.Name,
+			Side:           p.Side,
+			Kills:          p.Kills,
+			Deaths:         p.Deaths,
+			Assists:        p.Assists,
+			Damage:         p.Damage,
+			Survived:       p.Survived,
+			Traded:         p.Traded,
+			KAST:           p.KAST,
+			EquipmentValue: p.EquipmentValue,
+			MoneySpent:     p.MoneySpent,
+			BuyType:        p.BuyType,
+		}
+	}
+	return out
+}
+
+// mapRepoJob converts a repository ingest job to the service type.
+func mapRepoJob(j repository.IngestJob) IngestJob {
+	return IngestJob{
+		ID:        j.ID,
+		FileName:  j.FileName,
+		DemoHash:  j.DemoHash,
+		Status:    JobStatus(j.Status),
+		Error:     j.Error,
+		MatchID:   j.MatchID,
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+	}
+}
+
+// mapRepoJobs converts repository ingest jobs to service types.
+func mapRepoJobs(js []repository.IngestJob) []IngestJob {
+	out := make([]IngestJob, len(js))
+	for i, j := range js {
+		out[i] = mapRepoJob(j)
+	}
+	return out
+}
+
+// mapRepoPlayerMatches converts a repository player history to service
+// player matches.
+func mapRepoPlayerMatches(pms []repository.PlayerMatch) []PlayerMatch {
+	out := make([]PlayerMatch, len(pms))
+	for i, pm := range pms {
+		out[i] = PlayerMatch{
+			Match:        mapRepoSummaries([]repository.MatchSummary{pm.Match})[0],
+			Stats:        mapRepoPlayerStats([]repository.PlayerStats{pm.Stats})[0],
+			RoundsPlayed: pm.RoundsPlayed,
+			RoundsWon:    pm.RoundsWon,
+			RoundsLost:   pm.RoundsLost,
+		}
+	}
+	return out
+}
+
+// mapRepoMapStats converts a player's repository stats on one map to
+// service career stats.
+func mapRepoMapStats(ms repository.PlayerMapStats) CareerStats {
+	return CareerStats{
+		MapName:       ms.MapName,
+		Matches:       ms.Matches,
+		Wins:          ms.Wins,
+		Losses:        ms.Losses,
+		RoundsPlayed:  ms.RoundsPlayed,
+		Kills:         ms.Kills,
+		Deaths:        ms.Deaths,
+		Assists:       ms.Assists,
+		Headshots:     ms.Headshots,
+		TotalDamage:   ms.TotalDamage,
+		FirstKills:    ms.FirstKills,
+		FirstDeaths:   ms.FirstDeaths,
+		TradeKills:    ms.TradeKills,
+		FlashAssists:  ms.FlashAssists,
+		UtilityDamage: ms.UtilityDamage,
+		TwoK:          ms.TwoK,
+		ThreeK:        ms.ThreeK,
+		FourK:         ms.FourK,
+		FiveK:         ms.FiveK,
+		ADR:           ms.ADR,
+		KAST:          ms.KAST,
+		HeadshotPct:   ms.HeadshotPct,
+		Rating:        ms.Rating,
+		KPR:           ms.KPR,
+		DPR:           ms.DPR,
+		Impact:        ms.Impact,
+	}
+}
+
+// mapRepoSideStats converts summed repository side stats to service side
+// stats, working out the per-round rates.
+func mapRepoSideStats(ss repository.PlayerSideStats) SideStats {
+	out := SideStats{
+		Side:      ss.Side,
+		Rounds:    ss.Rounds,
+		RoundsWon: ss.RoundsWon,
+		Kills:     ss.Kills,
+		Deaths:    ss.Deaths,
+		Assists:   ss.Assists,
+		Damage:    ss.Damage,
+		Survived:  ss.Survived,
+	}
+	if ss.Rounds > 0 {
+		rounds := float64(ss.Rounds)
+		out.ADR = float64(ss.Damage) / rounds
+		out.KAST = float64(ss.KAST) / rounds * 100
+		out.KPR = float64(ss.Kills) / rounds
+		out.DPR = float64(ss.Deaths) / rounds
+	}
+	return out
+}
+
+// mapRepoLeaderboard converts repository leaderboard entries to service
+// entries.
+func mapRepoLeaderboard(es []repository.LeaderboardEntry) []LeaderboardEntry {
+	out := make([]LeaderboardEntry, len(es))
+	for i, e := range es {
+		out[i] = LeaderboardEntry{
+			Rank:               e.Rank,
+			SteamID:            e.SteamID,
+			Name:               e.Name,
+			Value:              e.Value,
+			Matches:            e.Matches,
+			RoundsPlayed:       e.RoundsPlayed,
+			Kills:              e.Kills,
+			Deaths:             e.Deaths,
+			FirstKills:         e.FirstKills,
+			FirstDeaths:        e.FirstDeaths,
+			Clutches:           e.Clutches,
+			ClutchesWon:        e.ClutchesWon,
+			Rating:             e.Rating,
+			ADR:                e.ADR,
+			KAST:               e.KAST,
+			HeadshotPct:        e.HeadshotPct,
+			OpeningKillSuccess: e.OpeningKillSuccess,
+			ClutchWinRate:      e.ClutchWinRate,
+		}
+	}
+	return out
+}
+
+// mapRepoMapStatsSummary converts repository map stats to service map
+// stats, computing the side win rates.
+func mapRepoMapStatsSummary(ms repository.MapStats) MapStats {
+	return MapStats{
+		MapName:          ms.MapName,
+		Matches:          ms.Matches,
+		Wins:             ms.Wins,
+		Losses:           ms.Losses,
+		Rounds:           ms.Rounds,
+		CT:               mapSideStats(ms.CTRounds, ms.CTRoundsWon, ms.CTPistolRounds, ms.CTPistolRoundsWon),
+		T:                mapSideStats(ms.TRounds, ms.TRoundsWon, ms.TPistolRounds, ms.TPistolRoundsWon),
+		Eliminations:     ms.Eliminations,
+		BombsExploded:    ms.BombsExploded,
+		BombsDefused:     ms.BombsDefused,
+		TimeExpired:      ms.TimeExpired,
+		AvgRoundDuration: ms.AvgRoundDuration,
+		BombPlants:       ms.BombPlants,
+		PlantsA:          ms.PlantsA,
+		PlantsB:          ms.PlantsB,
+	}
+}
+
+func mapSideStats(rounds, won, pistols, pistolsWon int) MapSideStats {
+	out := MapSideStats{
+		Rounds:          rounds,
+		RoundsWon:       won,
+		PistolRounds:    pistols,
+		PistolRoundsWon: pistolsWon,
+	}
+	if rounds > 0 {
+		out.WinPct = float64(won) / float64(rounds) * 100
+	}
+	if pistols > 0 {
+		out.PistolWinPct = float64(pistolsWon) / float64(pistols) * 100
+	}
+	return out
+}
+
+// mapRepoTeams converts repository teams to service teams.
+func mapRepoTeams(ts []repository.Team) []Team {
+	out := make([]Team, len(ts))
+	for i, t := range ts {
+		roster := make([]TeamMember, len(t.Roster))
+		for j, tm := range t.Roster {
+			roster[j] = TeamMember{
+				SteamID:     tm.SteamID,
+				Name:        tm.Name,
+				Matches:     tm.Matches,
+				FirstPlayed: tm.FirstPlayed,
+				LastPlayed:  tm.LastPlayed,
+			}
+		}
+		out[i] = Team{
+			ID:          t.ID,
+			Name:        t.Name,
+			ClanName:    t.ClanName,
+			Matches:     t.Matches,
+			Wins:        t.Wins,
+			Losses:      t.Losses,
+			FirstPlayed: t.FirstPlayed,
+			LastPlayed:  t.LastPlayed,
+			Roster:      roster,
+		}
+	}
+	return out
+}
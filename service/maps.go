@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zarldev/cs2stats/repository"
+)
+
+// GetMapStats returns round outcomes per map over the matches picked by
+// filter, most played map first.
+func (s *Service) GetMapStats(ctx context.Context, filter MapStatsFilter) ([]MapStats, error) {
+	ms, err := s.repo.GetMapStats(ctx, repository.MapStatsFilter{
+		MapName:  filter.MapName,
+		SteamID:  filter.SteamID,
+		TeamName: filter.TeamName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get map stats: %w", err)
+	}
+	out := make([]MapStats, len(ms))
+	for i, m := range ms {
+		out[i] = mapRepoMapStatsSummary(m)
+	}
+	return out, nil
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zarldev/cs2stats/repository"
+)
+
+// GetPlayerProfile returns a player's stats aggregated over the matches
+// picked by filter, overall and per map, each split by side. It returns
+// repository.ErrNotFound for a player in no stored match.
+func (s *Service) GetPlayerProfile(ctx context.Context, steamID string, filter PlayerFilter) (PlayerProfile, error) {
+	p, err := s.repo.GetPlayer(ctx, steamID)
+	if err != nil {
+		return PlayerProfile{}, fmt.Errorf("get player %s: %w", steamID, err)
+	}
+
+	repoFilter := mapPlayerFilter(filter)
+	ms, err := s.repo.GetPlayerMapStats(ctx, steamID, repoFilter)
+	if err != nil {
+		return PlayerProfile{}, fmt.Errorf("get map stats of %s: %w", steamID, err)
+	}
+	ss, err := s.repo.GetPlayerSideStats(ctx, steamID, repoFilter)
+	if err != nil {
+		return PlayerProfile{}, fmt.Errorf("get side stats of %s: %w", steamID, err)
+	}
+
+	sidesByMap := make(map[string][]SideStats)
+	for _, side := range ss {
+		sidesByMap[side.MapName] = append(sidesByMap[side.MapName], mapRepoSideStats(side))
+	}
+	maps := make([]CareerStats, len(ms))
+	for i, m := range ms {
+		maps[i] = mapRepoMapStats(m)
+		maps[i].Sides = sidesByMap[m.MapName]
+	}
+
+	overall := mergeCareerStats(maps)
+	overall.Sides = mergeSideStats(ss)
+	return PlayerProfile{
+		SteamID: p.SteamID,
+		Name:    p.Name,
+		Overall: overall,
+		Maps:    maps,
+	}, nil
+}
+
+// GetPlayerHistory returns a page of the matches a player played, newest
+// first, with their stats in each. It returns repository.ErrNotFound for a
+// player in no stored match.
+func (s *Service) GetPlayerHistory(ctx context.Context, steamID string, filter PlayerFilter) ([]PlayerMatch, error) {
+	if _, err := s.repo.GetPlayer(ctx, steamID); err != nil {
+		return nil, fmt.Errorf("get player %s: %w", steamID, err)
+	}
+	pms, err := s.repo.ListPlayerMatches(ctx, steamID, mapPlayerFilter(filter))
+	if err != nil {
+		return nil, fmt.Errorf("list matches of %s: %w", steamID, err)
+	}
+	return mapRepoPlayerMatches(pms), nil
+}
+
+// GetLeaderboard ranks players across the stored matches picked by filter
+// by a metric, best first, returning one page. It returns ErrUnknownMetric
+// for a metric players cannot be ranked by.
+func (s *Service) GetLeaderboard(ctx context.Context, filter LeaderboardFilter) ([]LeaderboardEntry, error) {
+	metric := filter.Metric
+	if metric == "" {
+		metric = MetricRating
+	}
+	switch metric {
+	case MetricRating, MetricADR, MetricKAST, MetricHeadshotPct, MetricOpeningKillSuccess, MetricClutchWinRate:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMetric, metric)
+	}
+
+	es, err := s.repo.GetLeaderboard(ctx, repository.LeaderboardFilter{
+		Metric:        string(metric),
+		MapName:       filter.MapName,
+		DateFrom:      filter.DateFrom,
+		DateTo:        filter.DateTo,
+		MinRounds:     filter.MinRounds,
+		MinMatches:    filter.MinMatches,
+		Limit:         filter.Limit,
+		CursorValue:   filter.CursorValue,
+		CursorSteamID: filter.CursorSteamID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get %s leaderboard: %w", metric, err)
+	}
+	return mapRepoLeaderboard(es), nil
+}
+
+// comparedStats are the stats ComparePlayers reports deltas of, in order.
+var comparedStats = []struct {
+	name  string
+	value func(CareerStats) float64
+}{
+	{"rating", func(c CareerStats) float64 { return c.Rating }},
+	{"adr", func(c CareerStats) float64 { return c.ADR }},
+	{"kast", func(c CareerStats) float64 { return c.KAST }},
+	{"hs_pct", func(c CareerStats) float64 { return c.HeadshotPct }},
+	{"kpr", func(c CareerStats) float64 { return c.KPR }},
+	{"dpr", func(c CareerStats) float64 { return c.DPR }},
+	{"impact", func(c CareerStats) float64 { return c.Impact }},
+	{"kills", func(c CareerStats) float64 { return float64(c.Kills) }},
+	{"deaths", func(c CareerStats) float64 { return float64(c.Deaths) }},
+	{"assists", func(c CareerStats) float64 { return float64(c.Assists) }},
+	{"first_kills", func(c CareerStats) float64 { return float64(c.FirstKills) }},
+	{"first_deaths", func(c CareerStats) float64 { return float64(c.FirstDeaths) }},
+	{"trade_kills", func(c CareerStats) float64 { return float64(c.TradeKills) }},
+	{"utility_damage", func(c CareerStats) float64 { return float64(c.UtilityDamage) }},
+}
+
+// ComparePlayers compares two players over the matches both played, with
+// the difference in each stat and the record of their duels. It returns
+// repository.ErrNotFound if either player is in no stored match.
+func (s *Service) ComparePlayers(ctx context.Context, steamA, steamB string) (PlayerComparison, error) {
+	a, err := s.repo.GetPlayer(ctx, steamA)
+	if err != nil {
+		return PlayerComparison{}, fmt.Errorf("get player %s: %w", steamA, err)
+	}
+	b, err := s.repo.GetPlayer(ctx, steamB)
+	if err != nil {
+		return PlayerComparison{}, fmt.Errorf("get player %s: %w", steamB, err)
+	}
+
+	h, err := s.repo.GetPlayerHeadToHead(ctx, steamA, steamB)
+	if err != nil {
+		return PlayerComparison{}, fmt.Errorf("compare %s with %s: %w", steamA, steamB, err)
+	}
+
+	out := PlayerComparison{
+		A:       ComparedPlayer{SteamID: a.SteamID, Name: a.Name, Stats: mapRepoMapStats(h.StatsA)},
+		B:       ComparedPlayer{SteamID: b.SteamID, Name: b.Name, Stats: mapRepoMapStats(h.StatsB)},
+		Matches: h.Matches,
+		Opposed: h.Opposed,
+		WinsA:   h.WinsA,
+		WinsB:   h.WinsB,
+		Duels: DuelRecord{
+			KillsA:         h.KillsA,
+			KillsB:         h.KillsB,
+			HeadshotKillsA: h.HeadshotKillsA,
+			HeadshotKillsB: h.HeadshotKillsB,
+		},
+	}
+	for _, st := range comparedStats {
+		va, vb := st.value(out.A.Stats), st.value(out.B.Stats)
+		out.Deltas = append(out.Deltas, StatDelta{Stat: st.name, A: va, B: vb, Delta: va - vb})
+	}
+	return out, nil
+}
+
+// mapPlayerFilter converts a service player filter to a repository one.
+func mapPlayerFilter(f PlayerFilter) repository.PlayerFilter {
+	return repository.PlayerFilter{
+		MapName:    f.MapName,
+		DateFrom:   f.DateFrom,
+		DateTo:     f.DateTo,
+		Limit:      f.Limit,
+		CursorTime: f.CursorTime,
+		CursorID:   f.CursorID,
+	}
+}
+
+// mergeCareerStats combines per-map stats into stats over all the maps,
+// weighting rate stats the same way they were averaged per map.
+func mergeCareerStats(maps []CareerStats) CareerStats {
+	var out CareerStats
+	var rating, adr, kast, kpr, dpr, impact, hsPct float64
+	for _, m := range maps {
+		out.Matches += m.Matches
+		out.Wins += m.Wins
+		out.Losses += m.Losses
+		out.RoundsPlayed += m.RoundsPlayed
+		out.Kills += m.Kills
+		out.Deaths += m.Deaths
+		out.Assists += m.Assists
+		out.Headshots += m.Headshots
+		out.TotalDamage += m.TotalDamage
+		out.FirstKills += m.FirstKills
+		out.FirstDeaths += m.FirstDeaths
+		out.TradeKills += m.TradeKills
+		out.FlashAssists += m.FlashAssists
+		out.UtilityDamage += m.UtilityDamage
+		out.TwoK += m.TwoK
+		out.ThreeK += m.ThreeK
+		out.FourK += m.FourK
+		out.FiveK += m.FiveK
+
+		rounds := float64(m.RoundsPlayed)
+		rating += m.Rating * rounds
+		adr += m.ADR * rounds
+		kast += m.KAST * rounds
+		kpr += m.KPR * rounds
+		dpr += m.DPR * rounds
+		impact += m.Impact * rounds
+		hsPct += m.HeadshotPct * float64(m.Kills)
+	}
+	if out.RoundsPlayed > 0 {
+		rounds := float64(out.RoundsPlayed)
+		out.Rating = rating / rounds
+		out.ADR = adr / rounds
+		out.KAST = kast / rounds
+		out.KPR = kpr / rounds
+		out.DPR = dpr / rounds
+		out.Impact = impact / rounds
+	}
+	if out.Kills > 0 {
+		out.HeadshotPct = hsPct / float64(out.Kills)
+	}
+	return out
+}
+
+// mergeSideStats sums per-map side stats into one entry per side, CT
+// first.
+func mergeSideStats(ss []repository.PlayerSideStats) []SideStats {
+	var totals []repository.PlayerSideStats
+	for _, s := range ss {
+		i := 0
+		for i < len(totals) && totals[i].Side != s.Side {
+			i++
+		}
+		if i == len(totals) {
+			totals = append(totals, repository.PlayerSideStats{Side: s.Side})
+		}
+		t := &totals[i]
+		t.Rounds += s.Rounds
+		t.RoundsWon += s.RoundsWon
+		t.Kills += s.Kills
+		t.Deaths += s.Deaths
+		t.Assists += s.Assists
+		t.Damage += s.Damage
+		t.Survived += s.Survived
+		t.KAST += s.KAST
+	}
+
+	out := make([]SideStats, len(totals))
+	for i, t := range totals {
+		out[i] = mapRepoSideStats(t)
+	}
+	slices.SortFunc(out, func(a, b SideStats) int { return strings.Compare(a.Side, b.Side) })
+	return out
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zarldev/cs2stats/blobstore"
+	"github.com/zarldev/cs2stats/parser"
+)
+
+// ReprocessResult is the outcome of reprocessing one match. Err wraps
+// ErrDemoUnavailable if the original demo was not kept.
+type ReprocessResult struct {
+	MatchID string
+	Err     error
+}
+
+// ReprocessMatch parses the kept demo of a match again with the current
+// parser and replaces the match's players, rounds, kills and economy in a
+// single transaction. The match keeps its ID and date, since the parser
+// dates a match by when it was parsed. It returns
+// ErrDemoUnavailable if the original demo was not kept.
+func (s *Service) ReprocessMatch(ctx context.Context, matchID string) (MatchDetail, error) {
+	m, err := s.repo.GetMatch(ctx, matchID)
+	if err != nil {
+		return MatchDetail{}, fmt.Errorf("get match %s: %w", matchID, err)
+	}
+	if s.blobs == nil || m.DemoHash == "" {
+		return MatchDetail{}, ErrDemoUnavailable
+	}
+
+	rc, _, err := s.blobs.Get(ctx, m.DemoHash)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return MatchDetail{}, ErrDemoUnavailable
+	}
+	if err != nil {
+		return MatchDetail{}, fmt.Errorf("open demo for %s: %w", matchID, err)
+	}
+	parsed, err := s.parse(ctx, rc, nil)
+	rc.Close()
+	if err != nil {
+		return MatchDetail{}, err
+	}
+
+	repoMatch := mapParsedMatch(parsed, m.ID, m.DemoHash)
+	repoMatch.Date = m.Date
+	if err := s.repo.ReplaceMatch(ctx, repoMatch); err != nil {
+		return MatchDetail{}, fmt.Errorf("replace match %s: %w", matchID, err)
+	}
+	return s.GetMatch(ctx, matchID)
+}
+
+// ReprocessAll reprocesses stored matches one at a time, oldest first:
+// every match if all is set, otherwise only those produced by an older
+// parser version. report, if set, is called with the outcome of each
+// match. A match that fails is reported and the rest are still
+// reprocessed; an error is returned only if the matches cannot be listed
+// or ctx ends first.
+func (s *Service) ReprocessAll(ctx context.Context, all bool, report func(ReprocessResult)) error {
+	below := parser.Version
+	if all {
+		below = 0
+	}
+	ids, err := s.repo.ListMatchIDs(ctx, below)
+	if err != nil {
+		return fmt.Errorf("list matches: %w", err)
+	}
+
+	for _, id := range ids {
+		_, err := s.ReprocessMatch(ctx, id)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if report != nil {
+			report(ReprocessResult{MatchID: id, Err: err})
+		}
+	}
+	return nil
+}
@@ -5,56 +5,210 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 
+	"github.com/google/uuid"
+
+	"github.com/zarldev/cs2stats/blobstore"
 	"github.com/zarldev/cs2stats/parser"
 	"github.com/zarldev/cs2stats/repository"
 )
 
 // Parser defines the demo parsing interface the service depends on.
+// Implementations should stop and return ctx.Err() once ctx is done, and
+// report how far they have got through progress unless it is nil.
 type Parser interface {
-	Parse(io.Reader) (*parser.Match, error)
+	Parse(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error)
 }
 
 // ParserFunc adapts a plain function to the Parser interface.
-type ParserFunc func(io.Reader) (*parser.Match, error)
+type ParserFunc func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error)
 
-func (f ParserFunc) Parse(r io.Reader) (*parser.Match, error) {
-	return f(r)
+func (f ParserFunc) Parse(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+	return f(ctx, r, progress)
 }
 
+// ErrDemoUnavailable is returned when the original demo of a match was
+// not kept.
+var ErrDemoUnavailable = errors.New("original demo not stored")
+
+// ErrUnknownMetric is returned for a leaderboard metric that players
+// cannot be ranked by.
+var ErrUnknownMetric = errors.New("unknown leaderboard metric")
+
 // Service orchestrates demo ingestion and stat queries.
 type Service struct {
 	repo   repository.Repository
 	parser Parser
+	blobs  blobstore.BlobStore
 }
 
-// New creates a Service with the given repository and parser.
-func New(repo repository.Repository, p Parser) *Service {
-	return &Service{repo: repo, parser: p}
+// New creates a Service with the given repository and parser. Ingested
+// demos are kept in blobs, keyed by their hash; blobs may be nil to keep
+// only the parsed results.
+func New(repo repository.Repository, p Parser, blobs blobstore.BlobStore) *Service {
+	return &Service{repo: repo, parser: p, blobs: blobs}
 }
 
-// IngestDemo parses a demo file and stores the result.
-// Returns the match ID on success.
-func (s *Service) IngestDemo(ctx context.Context, demoBytes []byte) (string, error) {
-	hash := sha256sum(demoBytes)
+// IngestDemo parses a demo file and stores the result, returning the IDs
+// of the stored matches. Demos compressed with gzip, bzip2 or zstd are
+// decompressed first, and every .dem file in a zip archive is ingested in
+// turn. Each demo is deduplicated by the hash of its decompressed content:
+// one that is already stored yields the existing match ID, and
+// repository.ErrDuplicateDemo is returned only if every demo was. On any
+// other error the IDs of the demos stored before it are returned with it.
+func (s *Service) IngestDemo(ctx context.Context, demoBytes []byte) ([]string, error) {
+	return s.IngestDemoReader(ctx, bytes.NewReader(demoBytes))
+}
+
+// IngestDemoReader is like IngestDemo but reads the demos from r, so a
+// demo file need not be loaded whole before it is decompressed. Each demo
+// is spooled to a temporary file rather than held in memory, and zip
+// archives are read in place if r is an *os.File.
+func (s *Service) IngestDemoReader(ctx context.Context, r io.Reader) ([]string, error) {
+	var (
+		ids  []string
+		dups int
+	)
+	err := parser.Demos(r, func(name string, r io.Reader) error {
+		id, err := s.ingest(ctx, r)
+		if errors.Is(err, repository.ErrDuplicateDemo) {
+			dups++
+		} else if err != nil {
+			if name != "" {
+				err = fmt.Errorf("%s: %w", name, err)
+			}
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return ids, err
+	}
+	if dups == len(ids) {
+		return ids, fmt.Errorf("demo already stored: %w", repository.ErrDuplicateDemo)
+	}
+	return ids, nil
+}
+
+// ingest parses and stores a single uncompressed demo, keeping the demo
+// itself in the blob store. The demo is spooled to a temporary file while
+// it is hashed, and parsed and kept from there. A demo that is already
+// stored is not parsed again; its match ID is returned with
+// repository.ErrDuplicateDemo.
+func (s *Service) ingest(ctx context.Context, r io.Reader) (string, error) {
+	f, err := os.CreateTemp("", "ingest-*.dem")
+	if err != nil {
+		return "", fmt.Errorf("create spool file: %w", err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		return "", fmt.Errorf("spool demo: %w", err)
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	id, err := s.repo.FindMatchByHash(ctx, hash)
+	if err == nil {
+		return id, repository.ErrDuplicateDemo
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return "", fmt.Errorf("check demo hash: %w", err)
+	}
 
-	parsed, err := s.parser.Parse(bytes.NewReader(demoBytes))
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("rewind spool file: %w", err)
+	}
+	parsed, err := s.parse(ctx, f, nil)
 	if err != nil {
-		return "", fmt.Errorf("parse demo: %w", err)
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("rewind spool file: %w", err)
+	}
+	if err := s.keep(ctx, hash, f); err != nil {
+		return "", err
+	}
+	return s.store(ctx, parsed, hash)
+}
+
+// parse runs the parser over a demo, reporting progress to progress if it
+// is not nil.
+func (s *Service) parse(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+	parsed, err := s.parser.Parse(ctx, r, progress)
+	if err != nil {
+		return nil, fmt.Errorf("parse demo: %w", err)
+	}
+	return parsed, nil
+}
+
+// keep saves a demo in the blob store, if there is one.
+func (s *Service) keep(ctx context.Context, hash string, demo io.Reader) error {
+	if s.blobs == nil {
+		return nil
+	}
+	if err := s.blobs.Put(ctx, hash, demo); err != nil {
+		return fmt.Errorf("keep demo: %w", err)
 	}
+	return nil
+}
 
-	repoMatch := mapParsedMatch(parsed, hash)
+// store maps a parsed demo to repository types and stores it, returning
+// the new match ID.
+func (s *Service) store(ctx context.Context, parsed *parser.Match, hash string) (string, error) {
+	repoMatch := mapParsedMatch(parsed, uuid.New().String(), hash)
 
 	id, err := s.repo.StoreMatch(ctx, repoMatch)
 	if err != nil {
 		return "", fmt.Errorf("store match: %w", err)
 	}
-
 	return id, nil
 }
 
+// OpenDemo opens the original demo a match was parsed from. It returns
+// ErrDemoUnavailable if the demo was not kept. The caller must close the
+// returned file.
+func (s *Service) OpenDemo(ctx context.Context, matchID string) (DemoFile, error) {
+	m, err := s.repo.GetMatch(ctx, matchID)
+	if err != nil {
+		return DemoFile{}, fmt.Errorf("get match %s: %w", matchID, err)
+	}
+	if s.blobs == nil || m.DemoHash == "" {
+		return DemoFile{}, ErrDemoUnavailable
+	}
+
+	rc, size, err := s.blobs.Get(ctx, m.DemoHash)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return DemoFile{}, ErrDemoUnavailable
+	}
+	if err != nil {
+		return DemoFile{}, fmt.Errorf("open demo for %s: %w", matchID, err)
+	}
+	return DemoFile{
+		ReadCloser: rc,
+		Name:       fmt.Sprintf("%s-%s.dem", m.MapName, m.Date.UTC().Format("20060102-1504")),
+		Size:       size,
+	}, nil
+}
+
+// DeleteMatch deletes a match and everything parsed from it, along with
+// players who appear in no other match. The original demo stays in the
+// blob store, so the same demo can be uploaded again later.
+func (s *Service) DeleteMatch(ctx context.Context, id string) error {
+	if err := s.repo.DeleteMatch(ctx, id); err != nil {
+		return fmt.Errorf("delete match %s: %w", id, err)
+	}
+	return nil
+}
+
 // GetMatch returns match details by ID.
 func (s *Service) GetMatch(ctx context.Context, id string) (MatchDetail, error) {
 	m, err := s.repo.GetMatch(ctx, id)
@@ -119,7 +273,30 @@ func (s *Service) GetPositionalData(ctx context.Context, matchID string) ([]Kill
 	return mapRepoKills(ks), nil
 }
 
-func sha256sum(data []byte) string {
-	h := sha256.Sum256(data)
-	return hex.EncodeToString(h[:])
+// GetDamageEvents returns the per-hit damage log for a match.
+func (s *Service) GetDamageEvents(ctx context.Context, matchID string) ([]DamageEvent, error) {
+	ds, err := s.repo.GetDamageEvents(ctx, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("get damage events for %s: %w", matchID, err)
+	}
+	return mapRepoDamage(ds), nil
+}
+
+// GetRoundReplay returns the sampled player states for one round.
+func (s *Service) GetRoundReplay(ctx context.Context, matchID string, roundNumber int) ([]ReplayFrame, error) {
+	fs, err := s.repo.GetRoundReplay(ctx, matchID, roundNumber)
+	if err != nil {
+		return nil, fmt.Errorf("get replay for %s round %d: %w", matchID, roundNumber, err)
+	}
+	return mapRepoReplay(fs), nil
+}
+
+// GetPlayerRoundStats returns per-player round rows for a match. If steamID
+// is non-empty only that player's rounds are returned.
+func (s *Service) GetPlayerRoundStats(ctx context.Context, matchID, steamID string) ([]PlayerRoundStats, error) {
+	prs, err := s.repo.GetPlayerRounds(ctx, matchID, steamID)
+	if err != nil {
+		return nil, fmt.Errorf("get player rounds for %s: %w", matchID, err)
+	}
+	return mapRepoPlayerRounds(prs), nil
 }
@@ -1,11 +1,22 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/zarldev/cs2stats/blobstore"
 	"github.com/zarldev/cs2stats/parser"
 	"github.com/zarldev/cs2stats/repository"
 )
@@ -18,7 +29,18 @@ func newTestService(t *testing.T) (*Service, *repository.SQLite) {
 	}
 	t.Cleanup(func() { repo.Close() })
 
-	p := ParserFunc(func(r io.Reader) (*parser.Match, error) {
+	return New(repo, testParser(), nil), repo
+}
+
+// sha256sum returns the hex sha256 of data, as demos are hashed.
+func sha256sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// testParser returns a fixed two-round Match for any input.
+func testParser() ParserFunc {
+	return func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
 		return &parser.Match{
 			Map:      "de_dust2",
 			Date:     time.Date(2025, 1, 15, 14, 0, 0, 0, time.UTC),
@@ -34,6 +56,9 @@ func newTestService(t *testing.T) (*Service, *repository.SQLite) {
 						Kills: 30, Deaths: 15, Assists: 5,
 						ADR: 90.5, KAST: 78.0, HeadshotPct: 60.0, Rating: 1.45,
 						FlashAssists: 4, UtilityDamage: 150,
+						Headshots: 18, TotalDamage: 2534, Survived: 13,
+						FirstKills: 7, FirstDeaths: 3, TradeKills: 4,
+						MultiKills: map[int]int{2: 5, 3: 2, 5: 1},
 					},
 				},
 				{
@@ -99,10 +124,7 @@ func newTestService(t *testing.T) (*Service, *repository.SQLite) {
 				},
 			},
 		}, nil
-	})
-
-	svc := New(repo, p)
-	return svc, repo
+	}
 }
 
 // seedViaRepo stores a match directly in the repository,
@@ -169,13 +191,14 @@ func seedViaRepo(t *testing.T, repo *repository.SQLite) string {
 func TestIngestDemo(t *testing.T) {
 	svc, _ := newTestService(t)
 
-	id, err := svc.IngestDemo(context.Background(), []byte("fake demo data"))
+	ids, err := svc.IngestDemo(context.Background(), []byte("fake demo data"))
 	if err != nil {
 		t.Fatalf("ingest demo: %v", err)
 	}
-	if id == "" {
-		t.Fatal("expected non-empty match ID")
+	if len(ids) != 1 || ids[0] == "" {
+		t.Fatalf("expected one match ID, got %v", ids)
 	}
+	id := ids[0]
 
 	// verify we can retrieve the match
 	detail, err := svc.GetMatch(context.Background(), id)
@@ -196,6 +219,149 @@ func TestIngestDemo(t *testing.T) {
 	}
 }
 
+func TestIngestDemoPersistsPlayerCounts(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	ids, err := svc.IngestDemo(ctx, []byte("fake demo data"))
+	if err != nil {
+		t.Fatalf("ingest demo: %v", err)
+	}
+	id := ids[0]
+	stats, err := svc.GetPlayerStats(ctx, id)
+	if err != nil {
+		t.Fatalf("get player stats: %v", err)
+	}
+
+	var p PlayerStats
+	for _, ps := range stats {
+		if ps.Name == "s1mple" {
+			p = ps
+		}
+	}
+	if p.Headshots != 18 || p.TotalDamage != 2534 || p.Survived != 13 {
+		t.Errorf("headshots/damage/survived: got %d/%d/%d, want 18/2534/13", p.Headshots, p.TotalDamage, p.Survived)
+	}
+	if p.FirstKills != 7 || p.FirstDeaths != 3 || p.TradeKills != 4 {
+		t.Errorf("first kills/deaths/trades: got %d/%d/%d, want 7/3/4", p.FirstKills, p.FirstDeaths, p.TradeKills)
+	}
+	if p.TwoK != 5 || p.ThreeK != 2 || p.FourK != 0 || p.FiveK != 1 {
+		t.Errorf("multi-kills: got %d/%d/%d/%d, want 5/2/0/1", p.TwoK, p.ThreeK, p.FourK, p.FiveK)
+	}
+}
+
+func TestIngestDemoScoreFromRounds(t *testing.T) {
+	tests := []struct {
+		name           string
+		rounds         []parser.Round
+		scoreA, scoreB int
+	}{
+		{
+			name: "every round has a winning team",
+			rounds: []parser.Round{
+				{Number: 1, Winner: parser.SideCT, TeamSides: [2]parser.Side{parser.SideCT, parser.SideT}},
+				{Number: 2, Winner: parser.SideT, TeamSides: [2]parser.Side{parser.SideCT, parser.SideT}},
+				{Number: 3, Winner: parser.SideT, TeamSides: [2]parser.Side{parser.SideCT, parser.SideT}},
+			},
+			scoreA: 1, scoreB: 2,
+		},
+		{
+			// the sides of round 3 were never recorded
+			name: "a round without a winning team",
+			rounds: []parser.Round{
+				{Number: 1, Winner: parser.SideCT, TeamSides: [2]parser.Side{parser.SideCT, parser.SideT}},
+				{Number: 2, Winner: parser.SideT, TeamSides: [2]parser.Side{parser.SideCT, parser.SideT}},
+				{Number: 3, Winner: parser.SideT},
+			},
+			scoreA: 2, scoreB: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := newTestService(t)
+			svc := New(repo, ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+				return &parser.Match{
+					Map: "de_inferno",
+					Teams: [2]parser.Team{
+						{Name: "FaZe", Score: 2, StartedAs: parser.SideCT},
+						{Name: "NAVI", Score: 1, StartedAs: parser.SideT},
+					},
+					Rounds: tt.rounds,
+				}, nil
+			}), nil)
+			ctx := context.Background()
+
+			ids, err := svc.IngestDemo(ctx, []byte(tt.name))
+			if err != nil {
+				t.Fatalf("ingest demo: %v", err)
+			}
+			detail, err := svc.GetMatch(ctx, ids[0])
+			if err != nil {
+				t.Fatalf("get match: %v", err)
+			}
+			if detail.ScoreA != tt.scoreA || detail.ScoreB != tt.scoreB {
+				t.Errorf("score: got %d-%d, want %d-%d", detail.ScoreA, detail.ScoreB, tt.scoreA, tt.scoreB)
+			}
+		})
+	}
+}
+
+func TestIngestDemoTeamsAcrossHalftime(t *testing.T) {
+	repo, err := repository.New(":memory:")
+	if err != nil {
+		t.Fatalf("create test repo: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	// team A starts CT and wins round 1, then wins round 2 on T after the
+	// swap; team B wins round 3 on CT
+	p := ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		return &parser.Match{
+			Map: "de_mirage",
+			Teams: [2]parser.Team{
+				{Name: "Vitality", Score: 2, StartedAs: parser.SideCT},
+				{Name: "Spirit", Score: 1, StartedAs: parser.SideT},
+			},
+			Rounds: []parser.Round{
+				{Number: 1, Winner: parser.SideCT, TeamSides: [2]parser.Side{parser.SideCT, parser.SideT}},
+				{Number: 2, Winner: parser.SideT, TeamSides: [2]parser.Side{parser.SideT, parser.SideCT}},
+				{Number: 3, Winner: parser.SideCT, TeamSides: [2]parser.Side{parser.SideT, parser.SideCT}},
+			},
+		}, nil
+	})
+	svc := New(repo, p, nil)
+	ctx := context.Background()
+
+	ids, err := svc.IngestDemo(ctx, []byte("halftime demo"))
+	if err != nil {
+		t.Fatalf("ingest demo: %v", err)
+	}
+	id := ids[0]
+
+	detail, err := svc.GetMatch(ctx, id)
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	if detail.TeamA != "Vitality" || detail.ScoreA != 2 || detail.ScoreB != 1 {
+		t.Errorf("match: got %s %d-%d, want Vitality 2-1", detail.TeamA, detail.ScoreA, detail.ScoreB)
+	}
+
+	rounds, err := svc.GetRoundTimeline(ctx, id)
+	if err != nil {
+		t.Fatalf("get round timeline: %v", err)
+	}
+	want := []struct {
+		side string
+		won  bool
+	}{{"CT", true}, {"T", true}, {"T", false}}
+	for i, w := range want {
+		if rounds[i].TeamASide != w.side || rounds[i].TeamAWon != w.won {
+			t.Errorf("round %d: got side %s won %v, want %s/%v",
+				i+1, rounds[i].TeamASide, rounds[i].TeamAWon, w.side, w.won)
+		}
+	}
+}
+
 func TestIngestDemoDuplicate(t *testing.T) {
 	svc, _ := newTestService(t)
 	ctx := context.Background()
@@ -212,11 +378,592 @@ func TestIngestDemoDuplicate(t *testing.T) {
 	}
 }
 
+func TestIngestDemoCompressed(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	// demos are spooled to the temporary directory while they are ingested
+	spool := t.TempDir()
+	t.Setenv("TMPDIR", spool)
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("first demo"))
+	gw.Close()
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, f := range []struct{ name, body string }{
+		{"first.dem", "first demo"},
+		{"notes.txt", "ignored"},
+		{"second.dem", "second demo"},
+	} {
+		w, _ := zw.Create(f.name)
+		w.Write([]byte(f.body))
+	}
+	zw.Close()
+
+	ids, err := svc.IngestDemo(ctx, gz.Bytes())
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("ingest gzip demo: got %v, %v", ids, err)
+	}
+	detail, err := svc.GetMatch(ctx, ids[0])
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	if detail.DemoHash != sha256sum([]byte("first demo")) {
+		t.Errorf("demo hash: got %s, want hash of decompressed demo", detail.DemoHash)
+	}
+
+	// the archive's first demo matches the gzip upload, the second is new;
+	// it is streamed from a file as the import command does
+	path := filepath.Join(t.TempDir(), "demos.zip")
+	if err := os.WriteFile(path, archive.Bytes(), 0o644); err != nil {
+		t.Fatalf("write zip archive: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open zip archive: %v", err)
+	}
+	defer f.Close()
+	zipped, err := svc.IngestDemoReader(ctx, f)
+	if err != nil {
+		t.Fatalf("ingest zip archive: %v", err)
+	}
+	if len(zipped) != 2 || zipped[0] != ids[0] || zipped[1] == ids[0] {
+		t.Errorf("zip match IDs: got %v, want [%s <new>]", zipped, ids[0])
+	}
+
+	if _, err := svc.IngestDemo(ctx, archive.Bytes()); !errors.Is(err, repository.ErrDuplicateDemo) {
+		t.Errorf("reingest zip archive: expected ErrDuplicateDemo, got %v", err)
+	}
+
+	if left, _ := os.ReadDir(spool); len(left) != 0 {
+		t.Errorf("spool files left behind: %d", len(left))
+	}
+}
+
+func TestOpenDemo(t *testing.T) {
+	_, repo := newTestService(t)
+	blobs, err := blobstore.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+	svc := New(repo, testParser(), blobs)
+	ctx := context.Background()
+
+	// a compressed upload is kept decompressed, under the hash it is
+	// deduplicated by
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("kept demo"))
+	gw.Close()
+	ids, err := svc.IngestDemo(ctx, gz.Bytes())
+	if err != nil {
+		t.Fatalf("ingest demo: %v", err)
+	}
+
+	// demos ingested as jobs are kept too
+	jobs := runJobs(t, svc, t.TempDir(), 1)
+	submitted, err := jobs.Submit(ctx, "job.dem", strings.NewReader("queued kept demo"))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	seen := finalState(t, jobs, submitted[0].ID)
+
+	for id, want := range map[string]string{ids[0]: "kept demo", seen[len(seen)-1].MatchID: "queued kept demo"} {
+		demo, err := svc.OpenDemo(ctx, id)
+		if err != nil {
+			t.Fatalf("open demo %s: %v", id, err)
+		}
+		got, err := io.ReadAll(demo)
+		demo.Close()
+		if err != nil {
+			t.Fatalf("read demo: %v", err)
+		}
+		if string(got) != want || demo.Size != int64(len(want)) {
+			t.Errorf("demo %s: got %q (%d bytes), want %q", id, got, demo.Size, want)
+		}
+		if demo.Name != "de_dust2-20250115-1400.dem" {
+			t.Errorf("demo name: got %s", demo.Name)
+		}
+	}
+
+	if _, err := New(repo, nil, nil).OpenDemo(ctx, ids[0]); !errors.Is(err, ErrDemoUnavailable) {
+		t.Errorf("without blob store: expected ErrDemoUnavailable, got %v", err)
+	}
+	if _, err := svc.OpenDemo(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("missing match: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestReprocessMatch(t *testing.T) {
+	_, repo := newTestService(t)
+	blobs, err := blobstore.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+	ctx := context.Background()
+
+	ids, err := New(repo, testParser(), blobs).IngestDemo(ctx, []byte("reprocessed demo"))
+	if err != nil {
+		t.Fatalf("ingest demo: %v", err)
+	}
+	id := ids[0]
+	// a match stored without its demo is skipped
+	keptless, err := New(repo, testParser(), nil).IngestDemo(ctx, []byte("demo not kept"))
+	if err != nil {
+		t.Fatalf("ingest demo: %v", err)
+	}
+
+	// a fixed parser drops round 2 and sees the demo it was given; like the
+	// real parser it dates the match by when it was parsed
+	var parsed string
+	fixed := ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		b, _ := io.ReadAll(r)
+		parsed = string(b)
+		m, _ := testParser()(ctx, r, progress)
+		m.Date = time.Now()
+		m.Rounds = m.Rounds[:1]
+		m.Teams[0].Score, m.Teams[1].Score = 1, 0
+		return m, nil
+	})
+	svc := New(repo, fixed, blobs)
+
+	detail, err := svc.ReprocessMatch(ctx, id)
+	if err != nil {
+		t.Fatalf("reprocess: %v", err)
+	}
+	if parsed != "reprocessed demo" {
+		t.Errorf("parsed demo: got %q", parsed)
+	}
+	if detail.ID != id || detail.ParserVersion != parser.Version {
+		t.Errorf("detail: got id %s version %d, want %s version %d", detail.ID, detail.ParserVersion, id, parser.Version)
+	}
+	if want := time.Date(2025, 1, 15, 14, 0, 0, 0, time.UTC); !detail.Date.Equal(want) {
+		t.Errorf("date: got %v, want %v kept from the first parse", detail.Date, want)
+	}
+	rounds, err := svc.GetRoundTimeline(ctx, id)
+	if err != nil {
+		t.Fatalf("get rounds: %v", err)
+	}
+	if len(rounds) != 1 {
+		t.Errorf("rounds: got %d, want 1", len(rounds))
+	}
+
+	if _, err := svc.ReprocessMatch(ctx, keptless[0]); !errors.Is(err, ErrDemoUnavailable) {
+		t.Errorf("demo not kept: expected ErrDemoUnavailable, got %v", err)
+	}
+	if _, err := svc.ReprocessMatch(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("missing match: expected ErrNotFound, got %v", err)
+	}
+
+	// both matches are at the current version, so only all reprocesses
+	var results []ReprocessResult
+	report := func(res ReprocessResult) { results = append(results, res) }
+	if err := svc.ReprocessAll(ctx, false, report); err != nil {
+		t.Fatalf("reprocess outdated: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("reprocess outdated: got %d results, want 0", len(results))
+	}
+	if err := svc.ReprocessAll(ctx, true, report); err != nil {
+		t.Fatalf("reprocess all: %v", err)
+	}
+	if len(results) != 2 || results[0].MatchID != id || results[0].Err != nil ||
+		!errors.Is(results[1].Err, ErrDemoUnavailable) {
+		t.Errorf("reprocess all: got %+v", results)
+	}
+}
+
+func TestDeleteMatch(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	ids, err := svc.IngestDemo(ctx, []byte("deleted demo"))
+	if err != nil {
+		t.Fatalf("ingest demo: %v", err)
+	}
+	if err := svc.DeleteMatch(ctx, ids[0]); err != nil {
+		t.Fatalf("delete match: %v", err)
+	}
+	if _, err := svc.GetMatch(ctx, ids[0]); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("get deleted match: expected ErrNotFound, got %v", err)
+	}
+	if err := svc.DeleteMatch(ctx, ids[0]); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("delete again: expected ErrNotFound, got %v", err)
+	}
+
+	// the demo is no longer a duplicate once its match is gone
+	if _, err := svc.IngestDemo(ctx, []byte("deleted demo")); err != nil {
+		t.Errorf("ingest deleted demo again: %v", err)
+	}
+}
+
+func TestIngestDemoCancelled(t *testing.T) {
+	_, repo := newTestService(t)
+
+	// the parser must receive the caller's context and nothing is stored
+	// when it gives up
+	p := ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	svc := New(repo, p, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := svc.IngestDemo(ctx, []byte("demo"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	ms, err := svc.ListMatches(context.Background(), MatchFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("list matches: %v", err)
+	}
+	if len(ms) != 0 {
+		t.Errorf("expected no stored matches, got %d", len(ms))
+	}
+}
+
+// runJobs runs a job runner spooling to dir for the duration of the test.
+func runJobs(t *testing.T, svc *Service, dir string, workers int) *Jobs {
+	t.Helper()
+	jobs, err := NewJobs(svc, dir, workers)
+	if err != nil {
+		t.Fatalf("create jobs: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		jobs.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return jobs
+}
+
+// finalState watches a job until it finishes and returns every state seen.
+func finalState(t *testing.T, jobs *Jobs, id string) []IngestJob {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	updates, err := jobs.WatchIngestJob(ctx, id)
+	if err != nil {
+		t.Fatalf("watch job: %v", err)
+	}
+	var seen []IngestJob
+	for j := range updates {
+		seen = append(seen, j)
+	}
+	if len(seen) == 0 || !seen[len(seen)-1].Finished() {
+		t.Fatalf("job %s did not finish: %v", id, seen)
+	}
+	return seen
+}
+
+func TestJobsIngest(t *testing.T) {
+	svc, _ := newTestService(t)
+	jobs := runJobs(t, svc, t.TempDir(), 2)
+	ctx := context.Background()
+
+	submitted, err := jobs.Submit(ctx, "match.dem", strings.NewReader("queued demo"))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	job := submitted[0]
+	if job.Status != JobQueued || job.DemoHash == "" {
+		t.Errorf("submitted job: got status %s hash %q", job.Status, job.DemoHash)
+	}
+
+	seen := finalState(t, jobs, job.ID)
+	last := seen[len(seen)-1]
+	if last.Status != JobDone || last.MatchID == "" || last.Error != "" {
+		t.Fatalf("final state: got %s match %q error %q", last.Status, last.MatchID, last.Error)
+	}
+
+	detail, err := svc.GetMatch(ctx, last.MatchID)
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	if detail.DemoHash != job.DemoHash {
+		t.Errorf("demo hash: got %s, want %s", detail.DemoHash, job.DemoHash)
+	}
+
+	// the stored demo is now rejected up front, pointing at the match
+	dup, err := jobs.Submit(ctx, "again.dem", strings.NewReader("queued demo"))
+	if !errors.Is(err, repository.ErrDuplicateDemo) {
+		t.Errorf("resubmit: expected ErrDuplicateDemo, got %v", err)
+	}
+	if len(dup) != 1 || dup[0].MatchID != last.MatchID {
+		t.Errorf("resubmit: got %v, want match ID %q", dup, last.MatchID)
+	}
+
+	// nothing is left behind in the spool directory
+	spooled, err := os.ReadDir(jobs.dir)
+	if err != nil {
+		t.Fatalf("read spool dir: %v", err)
+	}
+	if len(spooled) != 0 {
+		t.Errorf("expected empty spool dir, got %d files", len(spooled))
+	}
+
+	if _, err := jobs.Submit(ctx, "empty.dem", strings.NewReader("")); !errors.Is(err, ErrEmptyDemo) {
+		t.Errorf("empty submit: expected ErrEmptyDemo, got %v", err)
+	}
+}
+
+func TestJobsIngestArchive(t *testing.T) {
+	svc, _ := newTestService(t)
+	jobs := runJobs(t, svc, t.TempDir(), 2)
+	ctx := context.Background()
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, name := range []string{"a.dem", "b.dem"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte("demo " + name))
+	}
+	zw.Close()
+
+	submitted, err := jobs.Submit(ctx, "series.zip", io.MultiReader(&archive))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if len(submitted) != 2 {
+		t.Fatalf("jobs: got %d, want 2", len(submitted))
+	}
+	for i, name := range []string{"a.dem", "b.dem"} {
+		job := submitted[i]
+		if job.FileName != name || job.DemoHash != sha256sum([]byte("demo "+name)) {
+			t.Errorf("job %d: got name %s hash %s", i, job.FileName, job.DemoHash)
+		}
+		seen := finalState(t, jobs, job.ID)
+		if last := seen[len(seen)-1]; last.Status != JobDone {
+			t.Errorf("job %s: got %s (%s), want done", name, last.Status, last.Error)
+		}
+	}
+}
+
+func TestJobsProgress(t *testing.T) {
+	_, repo := newTestService(t)
+
+	// the parser holds at half way until the test has seen it reported
+	release := make(chan struct{})
+	svc := New(repo, ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		progress(parser.Progress{Percent: 50})
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return testParser()(ctx, r, progress)
+	}), nil)
+	jobs := runJobs(t, svc, t.TempDir(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	submitted, err := jobs.Submit(ctx, "match.dem", strings.NewReader("demo"))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	updates, err := jobs.WatchIngestJob(ctx, submitted[0].ID)
+	if err != nil {
+		t.Fatalf("watch job: %v", err)
+	}
+
+	var last IngestJob
+	for job := range updates {
+		if job.Status == JobParsing && job.Progress == 50 {
+			close(release)
+		}
+		last = job
+	}
+	if last.Status != JobDone || last.Progress != 0 {
+		t.Errorf("final state: got %s progress %v, want done progress 0", last.Status, last.Progress)
+	}
+}
+
+func TestJobsParseFailure(t *testing.T) {
+	_, repo := newTestService(t)
+	svc := New(repo, ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		return nil, errors.New("corrupt demo")
+	}), nil)
+	jobs := runJobs(t, svc, t.TempDir(), 1)
+	ctx := context.Background()
+
+	submitted, err := jobs.Submit(ctx, "bad.dem", strings.NewReader("bad demo"))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	job := submitted[0]
+
+	seen := finalState(t, jobs, job.ID)
+	last := seen[len(seen)-1]
+	if last.Status != JobFailed || last.Error != "parse demo: corrupt demo" {
+		t.Errorf("final state: got %s error %q", last.Status, last.Error)
+	}
+
+	// a failed job can be looked up and listed afterwards
+	got, err := jobs.GetIngestJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if got.Status != JobFailed {
+		t.Errorf("stored status: got %s, want failed", got.Status)
+	}
+	failed, err := jobs.ListIngestJobs(ctx, IngestJobFilter{Status: JobFailed})
+	if err != nil {
+		t.Fatalf("list jobs: %v", err)
+	}
+	if len(failed) != 1 {
+		t.Errorf("failed jobs: got %d, want 1", len(failed))
+	}
+}
+
+func TestJobsResumeAfterShutdown(t *testing.T) {
+	_, repo := newTestService(t)
+
+	// the first run is stopped while the demo is being parsed
+	started := make(chan struct{})
+	blocking := New(repo, ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}), nil)
+	dir := t.TempDir()
+	jobs, err := NewJobs(blocking, dir, 1)
+	if err != nil {
+		t.Fatalf("create jobs: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		jobs.Run(ctx)
+	}()
+
+	submitted, err := jobs.Submit(context.Background(), "slow.dem", strings.NewReader("slow demo"))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	job := submitted[0]
+	<-started
+	cancel()
+	<-done
+
+	got, err := jobs.GetIngestJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if got.Status != JobParsing {
+		t.Fatalf("interrupted job: got %s, want parsing", got.Status)
+	}
+
+	// the next run picks it up again from the spooled file
+	resumed := runJobs(t, New(repo, testParser(), nil), dir, 1)
+	seen := finalState(t, resumed, job.ID)
+	if last := seen[len(seen)-1]; last.Status != JobDone {
+		t.Errorf("resumed job: got %s (%s), want done", last.Status, last.Error)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	_, repo := newTestService(t)
+	// a corrupt demo fails to parse
+	svc := New(repo, ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		if b, _ := io.ReadAll(r); string(b) == "corrupt demo" {
+			return nil, errors.New("corrupt demo")
+		}
+		return testParser()(ctx, r, progress)
+	}), nil)
+	dir := t.TempDir()
+
+	results := make(chan WatchResult)
+	w, err := NewWatcher(svc, dir, WatchOptions{
+		Interval: 10 * time.Millisecond,
+		Move:     true,
+		Report:   func(res WatchResult) { results <- res },
+	})
+	if err != nil {
+		t.Fatalf("create watcher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	var empty bytes.Buffer
+	zip.NewWriter(&empty).Close()
+
+	files := []struct {
+		name    string
+		content []byte
+		dest    string
+		err     error
+	}{
+		{"scrim.dem", []byte("watched demo"), WatchDoneDir, nil},
+		{"again.dem", []byte("watched demo"), WatchDoneDir, repository.ErrDuplicateDemo},
+		{"empty.zip", empty.Bytes(), WatchFailedDir, parser.ErrNoDemos},
+		{"corrupt.dem", []byte("corrupt demo"), WatchFailedDir, nil},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.content, 0o644); err != nil {
+			t.Fatalf("write %s: %v", f.name, err)
+		}
+
+		var res WatchResult
+		select {
+		case res = <-results:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s was not ingested", f.name)
+		}
+		if res.Path != filepath.Join(dir, f.name) {
+			t.Errorf("result path: got %s, want %s", res.Path, f.name)
+		}
+		if f.dest == WatchDoneDir && f.err == nil && (res.Err != nil || len(res.MatchIDs) != 1) {
+			t.Errorf("%s: got %v, %v", f.name, res.MatchIDs, res.Err)
+		}
+		if f.dest == WatchFailedDir && res.Err == nil {
+			t.Errorf("%s: expected an error", f.name)
+		}
+		if f.err != nil && !errors.Is(res.Err, f.err) {
+			t.Errorf("%s: expected %v, got %v", f.name, f.err, res.Err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, f.dest, f.name)); err != nil {
+			t.Errorf("%s not moved to %s: %v", f.name, f.dest, err)
+		}
+	}
+
+	// other files are ignored
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a demo"), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+	select {
+	case res := <-results:
+		t.Errorf("unexpected result for %s", res.Path)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestGetMatch(t *testing.T) {
 	_, repo := newTestService(t)
 	matchID := seedViaRepo(t, repo)
 
-	svc := New(repo, nil) // parser not needed for queries
+	svc := New(repo, nil, nil) // parser not needed for queries
 
 	detail, err := svc.GetMatch(context.Background(), matchID)
 	if err != nil {
@@ -247,7 +994,7 @@ func TestListMatches(t *testing.T) {
 	_, repo := newTestService(t)
 	seedViaRepo(t, repo)
 
-	svc := New(repo, nil)
+	svc := New(repo, nil, nil)
 
 	results, err := svc.ListMatches(context.Background(), MatchFilter{})
 	if err != nil {
@@ -265,7 +1012,7 @@ func TestGetPlayerStats(t *testing.T) {
 	_, repo := newTestService(t)
 	matchID := seedViaRepo(t, repo)
 
-	svc := New(repo, nil)
+	svc := New(repo, nil, nil)
 
 	stats, err := svc.GetPlayerStats(context.Background(), matchID)
 	if err != nil {
@@ -284,11 +1031,401 @@ func TestGetPlayerStats(t *testing.T) {
 	}
 }
 
+// seedSecondMap stores a de_nuke match for device, after seedViaRepo's,
+// that their team lost and that has per-round stats for two rounds.
+func seedSecondMap(t *testing.T, repo *repository.SQLite) {
+	t.Helper()
+	now := time.Now().Truncate(time.Second)
+	m := repository.Match{
+		ID: "nuke-match-id", MapName: "de_nuke",
+		Date: now.Add(-time.Hour), DurationSeconds: 1500,
+		TeamA: "Vitality", TeamB: "Astralis",
+		ScoreA: 16, ScoreB: 4,
+		DemoHash: "seed-hash-nuke", TeamAStartedAs: "T", CreatedAt: now.Add(-time.Hour),
+		Players: []repository.PlayerStats{
+			{
+				PlayerID: "pid1-nuke", SteamID: "76561198001", Name: "device",
+				Team: "CT", Kills: 10, Deaths: 18, HeadshotPct: 20.0, Rating: 0.80,
+			},
+		},
+		Rounds: []repository.Round{
+			{ID: "nuke-rd1", Number: 1, WinnerTeam: "CT", WinMethod: "Elimination"},
+			{ID: "nuke-rd2", Number: 2, WinnerTeam: "CT", WinMethod: "Elimination"},
+		},
+		PlayerRounds: []repository.PlayerRound{
+			{RoundID: "nuke-rd1", PlayerID: "pid1-nuke", SteamID: "76561198001", Side: "CT", Kills: 1, Damage: 90, KAST: true},
+			{RoundID: "nuke-rd2", PlayerID: "pid1-nuke", SteamID: "76561198001", Side: "T", Deaths: 1},
+		},
+	}
+	if _, err := repo.StoreMatch(context.Background(), m); err != nil {
+		t.Fatalf("seed repo: %v", err)
+	}
+}
+
+func TestGetPlayerProfile(t *testing.T) {
+	_, repo := newTestService(t)
+	seedViaRepo(t, repo)
+	seedSecondMap(t, repo)
+
+	svc := New(repo, nil, nil)
+	ctx := context.Background()
+
+	profile, err := svc.GetPlayerProfile(ctx, "76561198001", PlayerFilter{})
+	if err != nil {
+		t.Fatalf("get player profile: %v", err)
+	}
+	if profile.Name != "device" {
+		t.Errorf("name: got %s, want device", profile.Name)
+	}
+
+	// de_mirage has no round stats, so its 30-round score counts
+	o := profile.Overall
+	if o.Matches != 2 || o.Wins != 1 || o.Losses != 1 || o.RoundsPlayed != 32 || o.Kills != 32 {
+		t.Errorf("overall totals: got %+v", o)
+	}
+	if want := (1.20*30 + 0.80*2) / 32; math.Abs(o.Rating-want) > 1e-9 {
+		t.Errorf("overall rating: got %f, want %f", o.Rating, want)
+	}
+	if want := (50.0*22 + 20*10) / 32; math.Abs(o.HeadshotPct-want) > 1e-9 {
+		t.Errorf("overall HS%%: got %f, want %f", o.HeadshotPct, want)
+	}
+	if len(o.Sides) != 2 || o.Sides[0].Side != "CT" || o.Sides[1].Side != "T" {
+		t.Fatalf("overall sides: got %+v", o.Sides)
+	}
+	if ct := o.Sides[0]; ct.Rounds != 1 || ct.RoundsWon != 1 || ct.ADR != 90 || ct.KAST != 100 || ct.KPR != 1 {
+		t.Errorf("CT side: got %+v", ct)
+	}
+	if tSide := o.Sides[1]; tSide.Rounds != 1 || tSide.RoundsWon != 0 || tSide.DPR != 1 || tSide.KAST != 0 {
+		t.Errorf("T side: got %+v", tSide)
+	}
+
+	if len(profile.Maps) != 2 || profile.Maps[0].MapName != "de_mirage" || profile.Maps[1].MapName != "de_nuke" {
+		t.Fatalf("maps: got %+v", profile.Maps)
+	}
+	if mirage := profile.Maps[0]; mirage.Rating != 1.20 || len(mirage.Sides) != 0 {
+		t.Errorf("de_mirage: got %+v", mirage)
+	}
+	if nuke := profile.Maps[1]; nuke.Losses != 1 || len(nuke.Sides) != 2 {
+		t.Errorf("de_nuke: got %+v", nuke)
+	}
+
+	onMap, err := svc.GetPlayerProfile(ctx, "76561198001", PlayerFilter{MapName: "de_nuke"})
+	if err != nil {
+		t.Fatalf("get player profile on map: %v", err)
+	}
+	if onMap.Overall.Matches != 1 || onMap.Overall.Rating != 0.80 || len(onMap.Maps) != 1 {
+		t.Errorf("on de_nuke: got %+v", onMap)
+	}
+
+	if _, err := svc.GetPlayerProfile(ctx, "unknown", PlayerFilter{}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("unknown player: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetPlayerHistory(t *testing.T) {
+	_, repo := newTestService(t)
+	seedViaRepo(t, repo)
+	seedSecondMap(t, repo)
+
+	svc := New(repo, nil, nil)
+	ctx := context.Background()
+
+	history, err := svc.GetPlayerHistory(ctx, "76561198001", PlayerFilter{})
+	if err != nil {
+		t.Fatalf("get player history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(history))
+	}
+	if h := history[0]; h.Match.MapName != "de_mirage" || h.Stats.Kills != 22 || h.RoundsWon != 16 || h.RoundsLost != 14 {
+		t.Errorf("newest match: got %+v", h)
+	}
+	if h := history[1]; h.Match.ID != "nuke-match-id" || h.RoundsPlayed != 2 || h.RoundsWon != 4 || h.RoundsLost != 16 {
+		t.Errorf("oldest match: got %+v", h)
+	}
+
+	if _, err := svc.GetPlayerHistory(ctx, "unknown", PlayerFilter{}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("unknown player: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	_, repo := newTestService(t)
+	seedViaRepo(t, repo)
+	seedSecondMap(t, repo)
+
+	svc := New(repo, nil, nil)
+	ctx := context.Background()
+
+	// rating is the default metric
+	entries, err := svc.GetLeaderboard(ctx, LeaderboardFilter{})
+	if err != nil {
+		t.Fatalf("get leaderboard: %v", err)
+	}
+	if len(entries) != 2 || entries[0].SteamID != "76561198001" || entries[1].SteamID != "76561198002" {
+		t.Fatalf("rating order: got %+v", entries)
+	}
+	if e := entries[0]; e.Rank != 1 || e.Matches != 2 || e.RoundsPlayed != 32 || math.Abs(e.Value-(1.20*30+0.80*2)/32) > 1e-9 {
+		t.Errorf("top entry: got %+v", e)
+	}
+
+	entries, err = svc.GetLeaderboard(ctx, LeaderboardFilter{Metric: MetricHeadshotPct})
+	if err != nil {
+		t.Fatalf("get HS%% leaderboard: %v", err)
+	}
+	if len(entries) != 2 || entries[0].SteamID != "76561198002" || entries[0].Value != 42 {
+		t.Errorf("HS%% order: got %+v", entries)
+	}
+
+	entries, err = svc.GetLeaderboard(ctx, LeaderboardFilter{MinMatches: 2})
+	if err != nil {
+		t.Fatalf("get leaderboard with min matches: %v", err)
+	}
+	if len(entries) != 1 || entries[0].SteamID != "76561198001" {
+		t.Errorf("min matches: got %+v", entries)
+	}
+
+	if _, err := svc.GetLeaderboard(ctx, LeaderboardFilter{Metric: "kills"}); !errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("unknown metric: expected ErrUnknownMetric, got %v", err)
+	}
+}
+
+func TestGetMapStats(t *testing.T) {
+	_, repo := newTestService(t)
+	seedViaRepo(t, repo) // de_mirage, Astralis vs Liquid
+
+	now := time.Now().Truncate(time.Second)
+	m := repository.Match{
+		ID: "nuke-match-id", MapName: "de_nuke",
+		Date: now.Add(-time.Hour), DurationSeconds: 300,
+		TeamA: "Vitality", TeamB: "Astralis",
+		ScoreA: 2, ScoreB: 1,
+		DemoHash: "seed-hash-nuke", TeamAStartedAs: "CT", CreatedAt: now.Add(-time.Hour),
+		Rounds: []repository.Round{
+			{ID: "nuke-rd1", Number: 1, WinnerTeam: "CT", WinMethod: "Elimination",
+				TeamASide: "CT", TeamAWon: true, DurationSeconds: 90},
+			{ID: "nuke-rd2", Number: 2, WinnerTeam: "T", WinMethod: "BombExploded", BombPlantSite: "B",
+				TeamASide: "CT", DurationSeconds: 110},
+			{ID: "nuke-rd3", Number: 3, WinnerTeam: "T", WinMethod: "BombExploded", BombPlantSite: "A",
+				TeamASide: "T", TeamAWon: true, DurationSeconds: 100},
+		},
+	}
+	if _, err := repo.StoreMatch(context.Background(), m); err != nil {
+		t.Fatalf("seed repo: %v", err)
+	}
+
+	svc := New(repo, nil, nil)
+	ctx := context.Background()
+
+	maps, err := svc.GetMapStats(ctx, MapStatsFilter{TeamName: "Vitality"})
+	if err != nil {
+		t.Fatalf("get map stats: %v", err)
+	}
+	if len(maps) != 1 {
+		t.Fatalf("expected 1 map, got %d", len(maps))
+	}
+	nuke := maps[0]
+	if nuke.MapName != "de_nuke" || nuke.Matches != 1 || nuke.Wins != 1 || nuke.Rounds != 3 {
+		t.Errorf("totals: got %+v", nuke)
+	}
+	if want := (MapSideStats{Rounds: 2, RoundsWon: 1, WinPct: 50, PistolRounds: 1, PistolRoundsWon: 1, PistolWinPct: 100}); nuke.CT != want {
+		t.Errorf("CT side: got %+v, want %+v", nuke.CT, want)
+	}
+	if want := (MapSideStats{Rounds: 1, RoundsWon: 1, WinPct: 100, PistolRounds: 1, PistolRoundsWon: 1, PistolWinPct: 100}); nuke.T != want {
+		t.Errorf("T side: got %+v, want %+v", nuke.T, want)
+	}
+	if nuke.Eliminations != 1 || nuke.BombsExploded != 1 || nuke.BombPlants != 1 || nuke.PlantsA != 1 || nuke.AvgRoundDuration != 100 {
+		t.Errorf("rounds: got %+v", nuke)
+	}
+
+	maps, err = svc.GetMapStats(ctx, MapStatsFilter{TeamName: "Astralis"})
+	if err != nil {
+		t.Fatalf("get map stats: %v", err)
+	}
+	if len(maps) != 2 || maps[0].MapName != "de_mirage" || maps[0].Wins != 1 || maps[1].Losses != 1 {
+		t.Fatalf("Astralis maps: got %+v", maps)
+	}
+	if ct := maps[1].CT; ct.Rounds != 1 || ct.RoundsWon != 0 || ct.WinPct != 0 {
+		t.Errorf("Astralis CT side on de_nuke: got %+v", ct)
+	}
+}
+
+func TestTeams(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	// the same clanless rosters play twice, so each side keeps its team
+	var matches []MatchDetail
+	for _, demo := range []string{"first demo", "second demo"} {
+		ids, err := svc.IngestDemo(ctx, []byte(demo))
+		if err != nil {
+			t.Fatalf("ingest demo: %v", err)
+		}
+		detail, err := svc.GetMatch(ctx, ids[0])
+		if err != nil {
+			t.Fatalf("get match: %v", err)
+		}
+		matches = append(matches, detail)
+	}
+	navi, faze := matches[0].TeamAID, matches[0].TeamBID
+	if navi == "" || faze == "" || navi == faze {
+		t.Fatalf("teams: got %q and %q", navi, faze)
+	}
+	if matches[1].TeamAID != navi || matches[1].TeamBID != faze {
+		t.Errorf("rematch teams: got %s and %s, want %s and %s", matches[1].TeamAID, matches[1].TeamBID, navi, faze)
+	}
+
+	team, err := svc.GetTeam(ctx, navi)
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if team.Name != "s1mple" || team.Matches != 2 || team.Wins != 2 || team.Losses != 0 {
+		t.Errorf("team: got %+v", team)
+	}
+	if len(team.Roster) != 1 || team.Roster[0].SteamID != "76561198001" || team.Roster[0].Matches != 2 {
+		t.Errorf("roster: got %+v", team.Roster)
+	}
+
+	teams, err := svc.ListTeams(ctx, TeamFilter{Name: "RAIN"})
+	if err != nil {
+		t.Fatalf("list teams: %v", err)
+	}
+	if len(teams) != 1 || teams[0].ID != faze || teams[0].Losses != 2 {
+		t.Errorf("teams named rain: got %+v", teams)
+	}
+
+	ms, err := svc.GetTeamMatches(ctx, faze, MatchFilter{})
+	if err != nil {
+		t.Fatalf("get team matches: %v", err)
+	}
+	if len(ms) != 2 || ms[0].TeamBID != faze {
+		t.Errorf("team matches: got %+v", ms)
+	}
+	if _, err := svc.GetTeamMatches(ctx, "nonexistent", MatchFilter{}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("unknown team: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTeamsByClanName(t *testing.T) {
+	repo, err := repository.New(":memory:")
+	if err != nil {
+		t.Fatalf("create test repo: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	// the clan name follows the team to the other side, whoever plays
+	swapped := false
+	p := ParserFunc(func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
+		m, err := testParser()(ctx, r, progress)
+		if err != nil {
+			return nil, err
+		}
+		m.Teams[0].ClanName, m.Teams[1].ClanName = "Natus Vincere", "FaZe Clan"
+		if swapped {
+			m.Teams[0], m.Teams[1] = m.Teams[1], m.Teams[0]
+		}
+		swapped = true
+		return m, nil
+	})
+	svc := New(repo, p, nil)
+	ctx := context.Background()
+
+	var matches []MatchDetail
+	for _, demo := range []string{"first demo", "second demo"} {
+		ids, err := svc.IngestDemo(ctx, []byte(demo))
+		if err != nil {
+			t.Fatalf("ingest demo: %v", err)
+		}
+		detail, err := svc.GetMatch(ctx, ids[0])
+		if err != nil {
+			t.Fatalf("get match: %v", err)
+		}
+		matches = append(matches, detail)
+	}
+	if matches[1].TeamAID != matches[0].TeamBID || matches[1].TeamBID != matches[0].TeamAID {
+		t.Errorf("swapped teams: got %+v and %+v", matches[0], matches[1])
+	}
+
+	team, err := svc.GetTeam(ctx, matches[0].TeamAID)
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if team.Name != "Natus Vincere" || team.ClanName != "Natus Vincere" || team.Matches != 2 {
+		t.Errorf("team: got %+v", team)
+	}
+}
+
+func TestCompareTeams(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	for _, m := range []repository.Match{
+		{ID: "mirage-id", MapName: "de_mirage", Date: now.Add(-time.Hour), TeamA: "Astralis", TeamB: "Liquid", ScoreA: 16, ScoreB: 14},
+		{ID: "nuke-id", MapName: "de_nuke", Date: now, TeamA: "Liquid", TeamB: "Astralis", ScoreA: 13, ScoreB: 10},
+	} {
+		m.DemoHash, m.CreatedAt = "hash-"+m.ID, now
+		m.TeamAClanName, m.TeamBClanName = m.TeamA, m.TeamB
+		m.TeamAID, m.TeamBID = m.ID+"-a", m.ID+"-b"
+		if _, err := repo.StoreMatch(ctx, m); err != nil {
+			t.Fatalf("seed repo: %v", err)
+		}
+	}
+
+	c, err := svc.CompareTeams(ctx, "mirage-id-a", "mirage-id-b")
+	if err != nil {
+		t.Fatalf("compare teams: %v", err)
+	}
+	if c.A.Name != "Astralis" || c.B.Name != "Liquid" {
+		t.Errorf("teams: got %s and %s", c.A.Name, c.B.Name)
+	}
+	if c.WinsA != 1 || c.WinsB != 1 || c.Draws != 0 || c.RoundsA != 26 || c.RoundsB != 27 || c.RoundDiff != -1 {
+		t.Errorf("record: got %+v", c)
+	}
+	if len(c.Matches) != 2 || c.Matches[0].Match.ID != "nuke-id" || c.Matches[0].ScoreA != 10 || c.Matches[0].RoundDiff != -3 {
+		t.Errorf("matches: got %+v", c.Matches)
+	}
+	if len(c.Maps) != 2 || c.Maps[1].MapName != "de_mirage" || c.Maps[1].WinsA != 1 || c.Maps[1].RoundDiff != 2 {
+		t.Errorf("maps: got %+v", c.Maps)
+	}
+
+	if _, err := svc.CompareTeams(ctx, "mirage-id-a", "nonexistent"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("unknown team: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestComparePlayers(t *testing.T) {
+	svc, repo := newTestService(t)
+	seedViaRepo(t, repo) // device's team beats NAF's, and device kills NAF once
+	ctx := context.Background()
+
+	c, err := svc.ComparePlayers(ctx, "76561198001", "76561198002")
+	if err != nil {
+		t.Fatalf("compare players: %v", err)
+	}
+	if c.A.Name != "device" || c.B.Name != "NAF" || c.Matches != 1 || c.Opposed != 1 || c.WinsA != 1 || c.WinsB != 0 {
+		t.Errorf("comparison: got %+v", c)
+	}
+	if c.A.Stats.Kills != 22 || c.B.Stats.Kills != 19 {
+		t.Errorf("kills: got %d and %d", c.A.Stats.Kills, c.B.Stats.Kills)
+	}
+	if want := (DuelRecord{KillsA: 1, HeadshotKillsA: 1}); c.Duels != want {
+		t.Errorf("duels: got %+v, want %+v", c.Duels, want)
+	}
+	if len(c.Deltas) == 0 || c.Deltas[0].Stat != "rating" || math.Abs(c.Deltas[0].Delta-0.2) > 1e-9 {
+		t.Errorf("deltas: got %+v", c.Deltas)
+	}
+
+	if _, err := svc.ComparePlayers(ctx, "76561198001", "76561198999"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("unknown player: expected ErrNotFound, got %v", err)
+	}
+}
+
 func TestGetRoundTimeline(t *testing.T) {
 	_, repo := newTestService(t)
 	matchID := seedViaRepo(t, repo)
 
-	svc := New(repo, nil)
+	svc := New(repo, nil, nil)
 
 	rounds, err := svc.GetRoundTimeline(context.Background(), matchID)
 	if err != nil {
@@ -316,7 +1453,7 @@ func TestGetEconomyStats(t *testing.T) {
 	_, repo := newTestService(t)
 	matchID := seedViaRepo(t, repo)
 
-	svc := New(repo, nil)
+	svc := New(repo, nil, nil)
 
 	econ, err := svc.GetEconomyStats(context.Background(), matchID)
 	if err != nil {
@@ -339,7 +1476,7 @@ func TestGetPositionalData(t *testing.T) {
 	_, repo := newTestService(t)
 	matchID := seedViaRepo(t, repo)
 
-	svc := New(repo, nil)
+	svc := New(repo, nil, nil)
 
 	kills, err := svc.GetPositionalData(context.Background(), matchID)
 	if err != nil {
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/zarldev/cs2stats/repository"
+)
+
+// GetTeam returns a team with everyone who played for it, most recent
+// first. It returns repository.ErrNotFound for an unknown team.
+func (s *Service) GetTeam(ctx context.Context, id string) (Team, error) {
+	t, err := s.repo.GetTeam(ctx, id)
+	if err != nil {
+		return Team{}, fmt.Errorf("get team %s: %w", id, err)
+	}
+	return mapRepoTeams([]repository.Team{t})[0], nil
+}
+
+// ListTeams returns a page of teams, most recently played first.
+func (s *Service) ListTeams(ctx context.Context, filter TeamFilter) ([]Team, error) {
+	ts, err := s.repo.ListTeams(ctx, repository.TeamFilter{
+		Name:       filter.Name,
+		Limit:      filter.Limit,
+		CursorTime: filter.CursorTime,
+		CursorID:   filter.CursorID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	return mapRepoTeams(ts), nil
+}
+
+// GetTeamMatches returns a page of the matches a team played, newest
+// first. It returns repository.ErrNotFound for an unknown team.
+func (s *Service) GetTeamMatches(ctx context.Context, teamID string, filter MatchFilter) ([]MatchSummary, error) {
+	if _, err := s.repo.GetTeam(ctx, teamID); err != nil {
+		return nil, fmt.Errorf("get team %s: %w", teamID, err)
+	}
+	ms, err := s.repo.ListMatches(ctx, repository.MatchFilter{
+		MapName:    filter.MapName,
+		DateFrom:   filter.DateFrom,
+		DateTo:     filter.DateTo,
+		TeamID:     teamID,
+		Limit:      filter.Limit,
+		CursorTime: filter.CursorTime,
+		CursorID:   filter.CursorID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list matches of team %s: %w", teamID, err)
+	}
+	return mapRepoSummaries(ms), nil
+}
+
+// CompareTeams returns the record of two teams against each other, from
+// team A's point of view. It returns repository.ErrNotFound if either team
+// is unknown.
+func (s *Service) CompareTeams(ctx context.Context, teamA, teamB string) (TeamComparison, error) {
+	var out TeamComparison
+	for _, t := range []struct {
+		id   string
+		dest *Team
+	}{{teamA, &out.A}, {teamB, &out.B}} {
+		team, err := s.repo.GetTeam(ctx, t.id)
+		if err != nil {
+			return TeamComparison{}, fmt.Errorf("get team %s: %w", t.id, err)
+		}
+		team.Roster = nil
+		*t.dest = mapRepoTeams([]repository.Team{team})[0]
+	}
+
+	ms, err := s.repo.ListTeamHeadToHead(ctx, teamA, teamB)
+	if err != nil {
+		return TeamComparison{}, fmt.Errorf("list matches of %s against %s: %w", teamA, teamB, err)
+	}
+
+	byMap := make(map[string]int)
+	for _, m := range mapRepoSummaries(ms) {
+		h := HeadToHeadMatch{Match: m, ScoreA: m.ScoreA, ScoreB: m.ScoreB}
+		if m.TeamAID != teamA {
+			h.ScoreA, h.ScoreB = m.ScoreB, m.ScoreA
+		}
+		h.RoundDiff = h.ScoreA - h.ScoreB
+		out.Matches = append(out.Matches, h)
+
+		i, ok := byMap[m.MapName]
+		if !ok {
+			i = len(out.Maps)
+			byMap[m.MapName] = i
+			out.Maps = append(out.Maps, HeadToHeadMap{MapName: m.MapName})
+		}
+		hm := &out.Maps[i]
+		hm.Matches++
+		hm.RoundDiff += h.RoundDiff
+		switch {
+		case h.RoundDiff > 0:
+			out.WinsA++
+			hm.WinsA++
+		case h.RoundDiff < 0:
+			out.WinsB++
+			hm.WinsB++
+		default:
+			out.Draws++
+		}
+		out.RoundsA += h.ScoreA
+		out.RoundsB += h.ScoreB
+	}
+	out.RoundDiff = out.RoundsA - out.RoundsB
+
+	slices.SortStableFunc(out.Maps, func(a, b HeadToHeadMap) int { return b.Matches - a.Matches })
+	return out, nil
+}
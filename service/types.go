@@ -1,6 +1,11 @@
 package service
 
-import "time"
+import (
+	"io"
+	"time"
+
+	"github.com/zarldev/cs2stats/repository"
+)
 
 // MatchDetail holds full match information returned by GetMatch.
 type MatchDetail struct {
@@ -14,6 +19,9 @@ type MatchDetail struct {
 	ScoreB          int
 	DemoHash        string
 	TeamAStartedAs  string
+	ParserVersion   int
+	TeamAID         string // empty for a side not linked to a team
+	TeamBID         string
 }
 
 // MatchSummary is a lightweight listing entry.
@@ -28,6 +36,8 @@ type MatchSummary struct {
 	ScoreB          int
 	DemoHash        string
 	TeamAStartedAs  string
+	TeamAID         string // empty for a side not linked to a team
+	TeamBID         string
 	CreatedAt       time.Time
 }
 
@@ -57,6 +67,29 @@ type PlayerStats struct {
 	Rating        float64
 	FlashAssists  int
 	UtilityDamage int
+
+	KPR    float64
+	DPR    float64
+	Impact float64
+
+	Headshots   int
+	TotalDamage int
+	Survived    int // rounds survived
+	FirstKills  int
+	FirstDeaths int
+	TradeKills  int
+
+	// rounds with exactly 2, 3, 4 and 5 kills
+	TwoK   int
+	ThreeK int
+	FourK  int
+	FiveK  int
+
+	EnemiesFlashed        int
+	TeammatesFlashed      int
+	EnemyBlindDuration    float64 // seconds
+	AvgEnemyBlindDuration float64 // seconds per enemy flashed
+	FlashesLeadingToKill  int
 }
 
 // RoundEvent describes a single round in the timeline.
@@ -70,6 +103,8 @@ type RoundEvent struct {
 	FirstDeathSteamID  string
 	FirstKillWeapon    string
 	FirstKillRoundTime float64
+	TeamASide          string // side played by team A this round
+	TeamAWon           bool
 	Plant              *PlantEvent
 	Defuse             *DefuseEvent
 	Clutch             *ClutchEvent
@@ -99,7 +134,8 @@ type ClutchEvent struct {
 // EconomyData holds economy info for one team in one round.
 type EconomyData struct {
 	RoundNumber    int
-	Team           string
+	Team           string // side, CT or T
+	TeamA          bool   // the row belongs to team A
 	Spend          int
 	EquipmentValue int
 	BuyType        string
@@ -121,3 +157,398 @@ type KillPosition struct {
 	VictimY         float64
 	VictimZ         float64
 }
+
+// DamageEvent holds a single hit with positional data, or with Aggregate
+// set an attacker's damage total for a round whose hits are unknown.
+type DamageEvent struct {
+	RoundNumber     int
+	Tick            int
+	AttackerSteamID string
+	VictimSteamID   string
+	Weapon          string
+	HitGroup        string
+	HealthDamage    int
+	ArmorDamage     int
+	AttackerX       float64
+	AttackerY       float64
+	AttackerZ       float64
+	VictimX         float64
+	VictimY         float64
+	VictimZ         float64
+	Aggregate       bool
+}
+
+// PlayerRoundStats holds one player's stats for a single round. BuyType is
+// the buy type of the player's team that round.
+type PlayerRoundStats struct {
+	RoundNumber    int
+	SteamID        string
+	Name           string
+	Side           string
+	Kills          int
+	Deaths         int
+	Assists        int
+	Damage         int
+	Survived       bool
+	Traded         bool
+	KAST           bool
+	EquipmentValue int
+	MoneySpent     int
+	BuyType        string
+}
+
+// ReplayFrame holds every living player's state at one sampled tick.
+type ReplayFrame struct {
+	Tick    int
+	Players []ReplayPlayer
+}
+
+// ReplayPlayer is one player's state in a ReplayFrame.
+type ReplayPlayer struct {
+	SteamID string
+	X       float64
+	Y       float64
+	Z       float64
+	Yaw     float64
+	Pitch   float64
+	Health  int
+	Armor   int
+	Weapon  string
+	Money   int
+}
+
+// DemoFile is an open original demo returned by OpenDemo. Name is a file
+// name suggested for downloads.
+type DemoFile struct {
+	io.ReadCloser
+	Name string
+	Size int64
+}
+
+// JobStatus is the state of an ingest job.
+type JobStatus string
+
+// Ingest job states, in the order a successful job moves through them.
+const (
+	JobQueued  JobStatus = repository.JobQueued
+	JobParsing JobStatus = repository.JobParsing
+	JobStoring JobStatus = repository.JobStoring
+	JobDone    JobStatus = repository.JobDone
+	JobFailed  JobStatus = repository.JobFailed
+)
+
+// IngestJob tracks an uploaded demo through background ingestion. MatchID
+// is set once the job is done, or when it failed because the demo was
+// already stored. Progress is the percentage of the demo parsed so far; it
+// is only known while the job is parsing and is 0 otherwise.
+type IngestJob struct {
+	ID        string
+	FileName  string
+	DemoHash  string
+	Status    JobStatus
+	Error     string
+	MatchID   string
+	Progress  float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Finished reports whether the job has reached a final state.
+func (j IngestJob) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+// IngestJobFilter constrains ingest job listing.
+type IngestJobFilter struct {
+	Status     JobStatus
+	Limit      int
+	CursorTime time.Time
+	CursorID   string
+}
+
+// PlayerFilter constrains the matches a player's stats are taken from.
+// Limit and the cursor apply to GetPlayerHistory only.
+type PlayerFilter struct {
+	MapName    string
+	DateFrom   time.Time
+	DateTo     time.Time
+	Limit      int
+	CursorTime time.Time
+	CursorID   string
+}
+
+// PlayerProfile holds a player's stats aggregated across matches.
+type PlayerProfile struct {
+	SteamID string
+	Name    string
+	Overall CareerStats
+	Maps    []CareerStats // most played first
+}
+
+// CareerStats holds a player's stats over several matches, on one map or
+// on all of them. Rate stats are averaged weighted by rounds played, and
+// HeadshotPct by kills.
+type CareerStats struct {
+	MapName      string // empty for stats over all maps
+	Matches      int
+	Wins         int
+	Losses       int
+	RoundsPlayed int
+
+	Kills         int
+	Deaths        int
+	Assists       int
+	Headshots     int
+	TotalDamage   int
+	FirstKills    int
+	FirstDeaths   int
+	TradeKills    int
+	FlashAssists  int
+	UtilityDamage int
+	TwoK          int
+	ThreeK        int
+	FourK         int
+	FiveK         int
+
+	ADR         float64
+	KAST        float64
+	HeadshotPct float64
+	Rating      float64
+	KPR         float64
+	DPR         float64
+	Impact      float64
+
+	Sides []SideStats // CT then T; empty for matches without round stats
+}
+
+// SideStats holds a player's stats over the rounds they played on one
+// side. KAST is a percentage of rounds, like PlayerStats.KAST.
+type SideStats struct {
+	Side      string
+	Rounds    int
+	RoundsWon int
+	Kills     int
+	Deaths    int
+	Assists   int
+	Damage    int
+	Survived  int
+	ADR       float64
+	KAST      float64
+	KPR       float64
+	DPR       float64
+}
+
+// PlayerMatch is one match in a player's history. RoundsWon and
+// RoundsLost are the score from the player's team's point of view.
+type PlayerMatch struct {
+	Match        MatchSummary
+	Stats        PlayerStats
+	RoundsPlayed int
+	RoundsWon    int
+	RoundsLost   int
+}
+
+// LeaderboardMetric is a stat players can be ranked by.
+type LeaderboardMetric string
+
+// Leaderboard metrics. Opening kill success is the percentage of opening
+// duels won and clutch win rate the percentage of clutches won.
+const (
+	MetricRating             LeaderboardMetric = repository.MetricRating
+	MetricADR                LeaderboardMetric = repository.MetricADR
+	MetricKAST               LeaderboardMetric = repository.MetricKAST
+	MetricHeadshotPct        LeaderboardMetric = repository.MetricHeadshotPct
+	MetricOpeningKillSuccess LeaderboardMetric = repository.MetricOpeningKillSuccess
+	MetricClutchWinRate      LeaderboardMetric = repository.MetricClutchWinRate
+)
+
+// LeaderboardFilter constrains a leaderboard. Metric defaults to
+// MetricRating. Only players with at least MinRounds rounds and MinMatches
+// matches among the matches picked are ranked. The cursor is the Value
+// and SteamID of the last entry of the previous page.
+type LeaderboardFilter struct {
+	Metric        LeaderboardMetric
+	MapName       string
+	DateFrom      time.Time
+	DateTo        time.Time
+	MinRounds     int
+	MinMatches    int
+	Limit         int
+	CursorValue   float64
+	CursorSteamID string
+}
+
+// LeaderboardEntry is one ranked player. Value is the player's value of
+// the metric ranked by; players with equal values share a rank.
+type LeaderboardEntry struct {
+	Rank         int
+	SteamID      string
+	Name         string
+	Value        float64
+	Matches      int
+	RoundsPlayed int
+	Kills        int
+	Deaths       int
+	FirstKills   int
+	FirstDeaths  int
+	Clutches     int
+	ClutchesWon  int
+
+	Rating             float64
+	ADR                float64
+	KAST               float64
+	HeadshotPct        float64
+	OpeningKillSuccess float64
+	ClutchWinRate      float64
+}
+
+// MapStatsFilter constrains map stats. With a SteamID only the matches that
+// player played are counted, and with a TeamName only those that team
+// played; either makes the stats their team's.
+type MapStatsFilter struct {
+	MapName  string
+	SteamID  string
+	TeamName string
+}
+
+// MapStats holds round outcomes over the matches on one map. Side, win
+// method and plant stats cover the rounds of the team the filter picks, or
+// of both teams without one; Wins and Losses are zero without a filter.
+type MapStats struct {
+	MapName string
+	Matches int
+	Wins    int
+	Losses  int
+	Rounds  int
+	CT      MapSideStats
+	T       MapSideStats
+
+	// rounds won by each method
+	Eliminations  int
+	BombsExploded int
+	BombsDefused  int
+	TimeExpired   int
+
+	AvgRoundDuration float64 // seconds
+	BombPlants       int
+	PlantsA          int
+	PlantsB          int
+}
+
+// MapSideStats holds the rounds played on one side of a map. The pistol
+// rounds are the first round of each half.
+type MapSideStats struct {
+	Rounds          int
+	RoundsWon       int
+	WinPct          float64
+	PistolRounds    int
+	PistolRoundsWon int
+	PistolWinPct    float64
+}
+
+// Team is a team tracked across matches, by its clan name or else by its
+// players. Matches, Wins and Losses count the matches it played.
+type Team struct {
+	ID          string
+	Name        string
+	ClanName    string // empty for a team identified by its roster
+	Matches     int
+	Wins        int
+	Losses      int
+	FirstPlayed time.Time
+	LastPlayed  time.Time
+	Roster      []TeamMember // only set by GetTeam
+}
+
+// TeamMember is a player who played for a team.
+type TeamMember struct {
+	SteamID     string
+	Name        string
+	Matches     int
+	FirstPlayed time.Time
+	LastPlayed  time.Time
+}
+
+// TeamFilter constrains team listing. Name matches part of a team name,
+// ignoring case. The cursor is the LastPlayed and ID of the last team of
+// the previous page.
+type TeamFilter struct {
+	Name       string
+	Limit      int
+	CursorTime time.Time
+	CursorID   string
+}
+
+// TeamComparison is the record of two teams, A and B, against each other.
+// Scores and rounds are from team A's point of view.
+type TeamComparison struct {
+	A         Team // without Roster
+	B         Team
+	WinsA     int
+	WinsB     int
+	Draws     int
+	RoundsA   int
+	RoundsB   int
+	RoundDiff int // RoundsA - RoundsB
+
+	Maps    []HeadToHeadMap   // most played, then most recently played, first
+	Matches []HeadToHeadMatch // newest first
+}
+
+// HeadToHeadMap sums the matches two teams played against each other on
+// one map.
+type HeadToHeadMap struct {
+	MapName   string
+	Matches   int
+	WinsA     int
+	WinsB     int
+	RoundDiff int
+}
+
+// HeadToHeadMatch is one match two teams played against each other.
+type HeadToHeadMatch struct {
+	Match     MatchSummary
+	ScoreA    int // rounds won by team A
+	ScoreB    int
+	RoundDiff int
+}
+
+// PlayerComparison compares two players, A and B, over the matches both
+// played. WinsA and WinsB count the Opposed matches each player's team
+// won.
+type PlayerComparison struct {
+	A       ComparedPlayer
+	B       ComparedPlayer
+	Matches int
+	Opposed int // matches the players played on opposing teams
+	WinsA   int
+	WinsB   int
+	Deltas  []StatDelta
+	Duels   DuelRecord
+}
+
+// ComparedPlayer is one side of a PlayerComparison, with their stats over
+// the matches both players played.
+type ComparedPlayer struct {
+	SteamID string
+	Name    string
+	Stats   CareerStats
+}
+
+// StatDelta is the difference between the two players of a
+// PlayerComparison in one stat.
+type StatDelta struct {
+	Stat  string // e.g. rating or adr
+	A     float64
+	B     float64
+	Delta float64 // A - B
+}
+
+// DuelRecord counts the kills of one player by the other, team kills
+// aside.
+type DuelRecord struct {
+	KillsA         int // of B by A
+	KillsB         int // of A by B
+	HeadshotKillsA int
+	HeadshotKillsB int
+}
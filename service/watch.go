@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zarldev/cs2stats/repository"
+)
+
+// Subdirectories of a watched directory that ingested files are moved to
+// when WatchOptions.Move is set.
+const (
+	WatchDoneDir   = "done"
+	WatchFailedDir = "failed"
+)
+
+// WatchOptions configures a Watcher.
+type WatchOptions struct {
+	// Interval is the time between scans of the directory. A file is
+	// ingested once its size and modification time are unchanged across a
+	// whole interval, so it is not read while still being written.
+	Interval time.Duration
+
+	// Move moves each ingested file to the done subdirectory, or to failed
+	// if it could not be ingested. Otherwise files are left in place and
+	// ingested again only if they change.
+	Move bool
+
+	// Report, if set, is called with the outcome of each file ingested,
+	// and with the directory path when it cannot be scanned.
+	Report func(WatchResult)
+}
+
+// WatchResult is the outcome of ingesting one file found by a Watcher.
+// Err wraps repository.ErrDuplicateDemo if every demo in the file was
+// already stored.
+type WatchResult struct {
+	Path     string
+	MatchIDs []string
+	Err      error
+}
+
+// fileState identifies a version of a file between scans.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher polls a directory for new demo files and ingests them through
+// the service. It needs no filesystem notification support, which makes it
+// suitable for network shares.
+type Watcher struct {
+	svc  *Service
+	dir  string
+	opts WatchOptions
+
+	pending   map[string]fileState // seen but not yet stable
+	processed map[string]fileState // ingested and still in the directory
+}
+
+// NewWatcher creates a watcher for dir. The directory, and with
+// opts.Move the done and failed subdirectories, are created if needed.
+// Call Run to start polling.
+func NewWatcher(svc *Service, dir string, opts WatchOptions) (*Watcher, error) {
+	dirs := []string{dir}
+	if opts.Move {
+		dirs = append(dirs, filepath.Join(dir, WatchDoneDir), filepath.Join(dir, WatchFailedDir))
+	}
+	for _, d := range dirs {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("create watch dir: %w", err)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	return &Watcher{
+		svc:       svc,
+		dir:       dir,
+		opts:      opts,
+		pending:   make(map[string]fileState),
+		processed: make(map[string]fileState),
+	}, nil
+}
+
+// Run scans the directory every interval until ctx is done. Files are
+// ingested one at a time. A failed scan is retried on the next interval.
+func (w *Watcher) Run(ctx context.Context) error {
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+	for {
+		w.scan(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// scan ingests every demo file that is unchanged since the previous scan.
+func (w *Watcher) scan(ctx context.Context) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		w.report(WatchResult{Path: w.dir, Err: fmt.Errorf("scan watch dir: %w", err)})
+		return
+	}
+
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !isDemoFile(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		present[name] = true
+
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if w.processed[name] == state {
+			continue
+		}
+		if prev, ok := w.pending[name]; !ok || prev != state || state.size == 0 {
+			w.pending[name] = state
+			continue
+		}
+
+		delete(w.pending, name)
+		if !w.ingest(ctx, name) {
+			return
+		}
+		// also covers a file that could not be moved away
+		w.processed[name] = state
+	}
+
+	// forget files that were removed
+	for name := range w.pending {
+		if !present[name] {
+			delete(w.pending, name)
+		}
+	}
+	for name := range w.processed {
+		if !present[name] {
+			delete(w.processed, name)
+		}
+	}
+}
+
+// ingest ingests one file and moves it if configured. It returns false if
+// ctx ended first, leaving the file to be picked up again.
+func (w *Watcher) ingest(ctx context.Context, name string) bool {
+	path := filepath.Join(w.dir, name)
+
+	ids, err := w.ingestFile(ctx, path)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if w.opts.Move {
+		dest := WatchDoneDir
+		if err != nil && !errors.Is(err, repository.ErrDuplicateDemo) {
+			dest = WatchFailedDir
+		}
+		if merr := moveFile(path, filepath.Join(w.dir, dest)); merr != nil && err == nil {
+			err = merr
+		}
+	}
+
+	w.report(WatchResult{Path: path, MatchIDs: ids, Err: err})
+	return true
+}
+
+// ingestFile ingests the demos in the file at path, streaming it from
+// disk.
+func (w *Watcher) ingestFile(ctx context.Context, path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return w.svc.IngestDemoReader(ctx, f)
+}
+
+func (w *Watcher) report(res WatchResult) {
+	if w.opts.Report != nil {
+		w.opts.Report(res)
+	}
+}
+
+// moveFile moves the file at path into dir, adding a timestamp to its name
+// if a file with the same name is already there.
+func moveFile(path, dir string) error {
+	name := filepath.Base(path)
+	dest := filepath.Join(dir, name)
+	if _, err := os.Stat(dest); err == nil {
+		dest = filepath.Join(dir, time.Now().Format("20060102-150405.000000")+"-"+name)
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return fmt.Errorf("move demo: %w", err)
+	}
+	return nil
+}
+
+// isDemoFile reports whether name looks like a demo, plain or compressed,
+// or a zip archive. Hidden files are skipped since copy tools often write
+// to them before renaming.
+func isDemoFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	name = strings.ToLower(name)
+	for _, ext := range []string{".dem", ".dem.gz", ".dem.bz2", ".dem.zst", ".zip"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,27 +1,38 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 
 	"connectrpc.com/connect"
 
+	"github.com/zarldev/cs2stats/parser"
 	"github.com/zarldev/cs2stats/repository"
 	"github.com/zarldev/cs2stats/service"
 	demov1 "github.com/zarldev/cs2stats/transport/grpc/gen/demo/v1"
 	"github.com/zarldev/cs2stats/transport/grpc/gen/demo/v1/demov1connect"
 )
 
+// errUploadTooLarge is returned by a chunkReader once a streamed upload
+// passes its size limit.
+var errUploadTooLarge = errors.New("upload too large")
+
 // DemoHandler implements the DemoService ConnectRPC handler.
 type DemoHandler struct {
 	demov1connect.UnimplementedDemoServiceHandler
-	svc *service.Service
+	svc       *service.Service
+	jobs      *service.Jobs
+	maxUpload int64
 }
 
 // NewDemoHandler creates a DemoHandler backed by the given service.
-func NewDemoHandler(svc *service.Service) *DemoHandler {
-	return &DemoHandler{svc: svc}
+// Uploads are queued on jobs for background ingestion, and a streamed
+// upload is rejected once it sends more than maxUpload bytes.
+func NewDemoHandler(svc *service.Service, jobs *service.Jobs, maxUpload int64) *DemoHandler {
+	return &DemoHandler{svc: svc, jobs: jobs, maxUpload: maxUpload}
 }
 
 func (h *DemoHandler) UploadDemo(
@@ -33,19 +44,254 @@ func (h *DemoHandler) UploadDemo(
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("demo_file is required"))
 	}
 
-	matchID, err := h.svc.IngestDemo(ctx, data)
+	jobs, err := h.jobs.Submit(ctx, req.Msg.GetFileName(), bytes.NewReader(data))
+	if err != nil {
+		return nil, submitError(err)
+	}
+
+	return connect.NewResponse(uploadResponse(jobs)), nil
+}
+
+func (h *DemoHandler) UploadDemoStream(
+	ctx context.Context,
+	stream *connect.ClientStream[demov1.UploadDemoStreamRequest],
+) (*connect.Response[demov1.UploadDemoResponse], error) {
+	// the file name comes with the first message
+	if !stream.Receive() {
+		if err := stream.Err(); err != nil {
+			return nil, submitError(err)
+		}
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("demo is required"))
+	}
+	first := stream.Msg()
+
+	r := &chunkReader{stream: stream, buf: first.GetChunk(), remaining: h.maxUpload}
+	jobs, err := h.jobs.Submit(ctx, first.GetFileName(), r)
+	if errors.Is(err, errUploadTooLarge) {
+		return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("upload exceeds %d bytes", h.maxUpload))
+	}
 	if err != nil {
-		if errors.Is(err, repository.ErrDuplicateDemo) {
-			return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("demo already uploaded"))
+		return nil, submitError(err)
+	}
+
+	return connect.NewResponse(uploadResponse(jobs)), nil
+}
+
+// uploadResponse lists the jobs queued by an upload, skipping demos that
+// were already stored.
+func uploadResponse(jobs []service.IngestJob) *demov1.UploadDemoResponse {
+	resp := &demov1.UploadDemoResponse{}
+	for _, job := range jobs {
+		if job.ID != "" {
+			resp.JobIds = append(resp.JobIds, job.ID)
 		}
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("ingest demo: %w", err))
+	}
+	if len(resp.JobIds) > 0 {
+		resp.JobId = resp.JobIds[0]
+	}
+	return resp
+}
+
+// chunkReader reads the chunks of an UploadDemoStream as one byte stream.
+// It fails with errUploadTooLarge once the chunks add up to more than
+// remaining bytes.
+type chunkReader struct {
+	stream    *connect.ClientStream[demov1.UploadDemoStreamRequest]
+	buf       []byte
+	remaining int64
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if !r.stream.Receive() {
+			if err := r.stream.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		r.buf = r.stream.Msg().GetChunk()
+	}
+	if int64(len(r.buf)) > r.remaining {
+		return 0, errUploadTooLarge
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.remaining -= int64(n)
+	return n, nil
+}
+
+// submitError maps a failure to queue an upload to a connect error.
+func submitError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateDemo):
+		return connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("demo already uploaded"))
+	case errors.Is(err, service.ErrEmptyDemo):
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("demo is empty"))
+	case errors.Is(err, parser.ErrNoDemos):
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("archive contains no demos"))
+	case errors.Is(err, parser.ErrDemoTooLarge):
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("demo exceeds %d bytes decompressed", parser.MaxDemoSize))
+	case errors.Is(err, context.Canceled):
+		return connect.NewError(connect.CodeCanceled, fmt.Errorf("queue demo: %w", err))
+	case errors.Is(err, context.DeadlineExceeded):
+		return connect.NewError(connect.CodeDeadlineExceeded, fmt.Errorf("queue demo: %w", err))
+	default:
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("queue demo: %w", err))
+	}
+}
+
+func (h *DemoHandler) GetIngestJob(
+	ctx context.Context,
+	req *connect.Request[demov1.GetIngestJobRequest],
+) (*connect.Response[demov1.GetIngestJobResponse], error) {
+	id := req.Msg.GetJobId()
+	if id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("job_id is required"))
 	}
 
-	return connect.NewResponse(&demov1.UploadDemoResponse{
-		MatchId: matchID,
+	job, err := h.jobs.GetIngestJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("job %s not found", id))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get ingest job %s: %w", id, err))
+	}
+
+	return connect.NewResponse(&demov1.GetIngestJobResponse{
+		Job: ingestJobToProto(job),
 	}), nil
 }
 
+func (h *DemoHandler) ListIngestJobs(
+	ctx context.Context,
+	req *connect.Request[demov1.ListIngestJobsRequest],
+) (*connect.Response[demov1.ListIngestJobsResponse], error) {
+	filter := listIngestJobsFilter(req.Msg)
+
+	// default page size
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	// fetch one extra to detect next page
+	filter.Limit++
+
+	jobs, err := h.jobs.ListIngestJobs(ctx, filter)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("list ingest jobs: %w", err))
+	}
+
+	hasMore := len(jobs) >= filter.Limit
+	if hasMore {
+		jobs = jobs[:filter.Limit-1]
+	}
+
+	pbJobs := make([]*demov1.IngestJob, len(jobs))
+	for i, j := range jobs {
+		pbJobs[i] = ingestJobToProto(j)
+	}
+
+	resp := &demov1.ListIngestJobsResponse{
+		Jobs: pbJobs,
+	}
+	if hasMore {
+		last := jobs[len(jobs)-1]
+		resp.NextPageToken = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+func (h *DemoHandler) WatchIngestJob(
+	ctx context.Context,
+	req *connect.Request[demov1.WatchIngestJobRequest],
+	stream *connect.ServerStream[demov1.WatchIngestJobResponse],
+) error {
+	id := req.Msg.GetJobId()
+	if id == "" {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("job_id is required"))
+	}
+
+	updates, err := h.jobs.WatchIngestJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("job %s not found", id))
+		}
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("watch ingest job %s: %w", id, err))
+	}
+
+	for job := range updates {
+		if err := stream.Send(&demov1.WatchIngestJobResponse{Job: ingestJobToProto(job)}); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (h *DemoHandler) DeleteMatch(
+	ctx context.Context,
+	req *connect.Request[demov1.DeleteMatchRequest],
+) (*connect.Response[demov1.DeleteMatchResponse], error) {
+	id := req.Msg.GetMatchId()
+	if id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("match_id is required"))
+	}
+
+	if err := h.svc.DeleteMatch(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("match %s not found", id))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("delete match %s: %w", id, err))
+	}
+
+	return connect.NewResponse(&demov1.DeleteMatchResponse{}), nil
+}
+
+// downloadChunkSize is the size of each DownloadDemo message.
+const downloadChunkSize = 256 << 10
+
+func (h *DemoHandler) DownloadDemo(
+	ctx context.Context,
+	req *connect.Request[demov1.DownloadDemoRequest],
+	stream *connect.ServerStream[demov1.DownloadDemoResponse],
+) error {
+	id := req.Msg.GetMatchId()
+	if id == "" {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("match_id is required"))
+	}
+
+	demo, err := h.svc.OpenDemo(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("match %s not found", id))
+		case errors.Is(err, service.ErrDemoUnavailable):
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("demo for match %s was not kept", id))
+		default:
+			return connect.NewError(connect.CodeInternal, fmt.Errorf("open demo for %s: %w", id, err))
+		}
+	}
+	defer demo.Close()
+
+	msg := &demov1.DownloadDemoResponse{FileName: demo.Name, Size: demo.Size}
+	buf := make([]byte, downloadChunkSize)
+	for {
+		n, err := io.ReadFull(demo, buf)
+		if n > 0 || msg.FileName != "" {
+			msg.Chunk = buf[:n]
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			msg = &demov1.DownloadDemoResponse{}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return connect.NewError(connect.CodeInternal, fmt.Errorf("read demo for %s: %w", id, err))
+		}
+	}
+}
+
 func (h *DemoHandler) ListMatches(
 	ctx context.Context,
 	req *connect.Request[demov1.ListMatchesRequest],
@@ -118,3 +364,54 @@ func (h *DemoHandler) GetMatch(
 		Players: players,
 	}), nil
 }
+
+func (h *DemoHandler) ReprocessMatch(
+	ctx context.Context,
+	req *connect.Request[demov1.ReprocessMatchRequest],
+) (*connect.Response[demov1.ReprocessMatchResponse], error) {
+	id := req.Msg.GetMatchId()
+	if id == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("match_id is required"))
+	}
+
+	detail, err := h.svc.ReprocessMatch(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("match %s not found", id))
+		case errors.Is(err, service.ErrDemoUnavailable):
+			return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("demo for match %s was not kept", id))
+		default:
+			return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("reprocess match %s: %w", id, err))
+		}
+	}
+
+	return connect.NewResponse(&demov1.ReprocessMatchResponse{
+		Match: matchDetailToProto(detail),
+	}), nil
+}
+
+func (h *DemoHandler) ReprocessAll(
+	ctx context.Context,
+	req *connect.Request[demov1.ReprocessAllRequest],
+) (*connect.Response[demov1.ReprocessAllResponse], error) {
+	resp := &demov1.ReprocessAllResponse{}
+	err := h.svc.ReprocessAll(ctx, req.Msg.GetAll(), func(res service.ReprocessResult) {
+		switch {
+		case res.Err == nil:
+			resp.Reprocessed++
+		case errors.Is(res.Err, service.ErrDemoUnavailable):
+			resp.Skipped++
+		default:
+			resp.Failures = append(resp.Failures, &demov1.ReprocessFailure{
+				MatchId: res.MatchID,
+				Error:   res.Err.Error(),
+			})
+		}
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("reprocess matches: %w", err))
+	}
+
+	return connect.NewResponse(resp), nil
+}
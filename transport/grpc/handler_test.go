@@ -1,6 +1,8 @@
 package grpc_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"io"
 	"net/http"
@@ -10,26 +12,31 @@ import (
 
 	"connectrpc.com/connect"
 
+	"github.com/zarldev/cs2stats/blobstore"
 	"github.com/zarldev/cs2stats/parser"
 	"github.com/zarldev/cs2stats/repository"
 	"github.com/zarldev/cs2stats/service"
 	transportgrpc "github.com/zarldev/cs2stats/transport/grpc"
 	demov1 "github.com/zarldev/cs2stats/transport/grpc/gen/demo/v1"
 	"github.com/zarldev/cs2stats/transport/grpc/gen/demo/v1/demov1connect"
+	playerv1 "github.com/zarldev/cs2stats/transport/grpc/gen/player/v1"
+	"github.com/zarldev/cs2stats/transport/grpc/gen/player/v1/playerv1connect"
 	statsv1 "github.com/zarldev/cs2stats/transport/grpc/gen/stats/v1"
 	"github.com/zarldev/cs2stats/transport/grpc/gen/stats/v1/statsv1connect"
+	teamv1 "github.com/zarldev/cs2stats/transport/grpc/gen/team/v1"
+	"github.com/zarldev/cs2stats/transport/grpc/gen/team/v1/teamv1connect"
 )
 
 // stubParser returns a fixed Match for any input.
 func stubParser() service.ParserFunc {
-	return func(r io.Reader) (*parser.Match, error) {
+	return func(ctx context.Context, r io.Reader, progress func(parser.Progress)) (*parser.Match, error) {
 		return &parser.Match{
 			Map:      "de_dust2",
 			Date:     time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC),
 			Duration: 45 * time.Minute,
 			Teams: [2]parser.Team{
-				{Name: "Team Alpha", Score: 16},
-				{Name: "Team Beta", Score: 12},
+				{Name: "Team Alpha", ClanName: "Team Alpha", Score: 16},
+				{Name: "Team Beta", ClanName: "Team Beta", Score: 12},
 			},
 			Players: []parser.Player{
 				{
@@ -90,12 +97,45 @@ func stubParser() service.ParserFunc {
 							VictimPosition:   parser.Position{X: 150.1, Y: 180.7, Z: 10.0},
 						},
 					},
+					Damage: []parser.DamageEvent{
+						{
+							AttackerSteamID: 76561198000000002,
+							VictimSteamID:   76561198000000001,
+							Weapon:          "glock",
+							HitGroup:        "Chest",
+							HealthDamage:    24,
+						},
+						{
+							AttackerSteamID: 76561198000000001,
+							VictimSteamID:   76561198000000002,
+							Weapon:          "ak47",
+							HitGroup:        "Head",
+							HealthDamage:    100,
+							ArmorDamage:     10,
+						},
+					},
+					Players: []parser.PlayerRound{
+						{SteamID: 76561198000000001, Side: parser.SideCT, Kills: 1, Damage: 100, Survived: true, KAST: true, EquipmentValue: 950, MoneySpent: 750},
+						{SteamID: 76561198000000002, Side: parser.SideT, Deaths: 1, Damage: 24, EquipmentValue: 800, MoneySpent: 600},
+					},
+					Frames: []parser.ReplayFrame{
+						{Tick: 100, Players: []parser.PlayerSample{
+							{SteamID: 76561198000000001, Position: parser.Position{X: 10, Y: 20}, Health: 100, Weapon: "ak47"},
+							{SteamID: 76561198000000002, Position: parser.Position{X: 30, Y: 40}, Health: 100, Weapon: "glock"},
+						}},
+						{Tick: 116, Players: []parser.PlayerSample{
+							{SteamID: 76561198000000001, Position: parser.Position{X: 12, Y: 22}, Health: 76, Weapon: "ak47"},
+						}},
+					},
 				},
 			},
 		}, nil
 	}
 }
 
+// maxTestUpload caps streamed uploads to the test server.
+const maxTestUpload = 1 << 20
+
 func setupTestServer(t *testing.T) (*httptest.Server, demov1connect.DemoServiceClient, statsv1connect.StatsServiceClient) {
 	t.Helper()
 
@@ -105,16 +145,41 @@ func setupTestServer(t *testing.T) (*httptest.Server, demov1connect.DemoServiceC
 	}
 	t.Cleanup(func() { repo.Close() })
 
-	svc := service.New(repo, stubParser())
+	blobs, err := blobstore.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+	svc := service.New(repo, stubParser(), blobs)
+	jobs, err := service.NewJobs(svc, t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("create jobs: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		jobs.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
 
-	demoHandler := transportgrpc.NewDemoHandler(svc)
+	demoHandler := transportgrpc.NewDemoHandler(svc, jobs, maxTestUpload)
 	statsHandler := transportgrpc.NewStatsHandler(svc)
+	playerHandler := transportgrpc.NewPlayerHandler(svc)
+	teamHandler := transportgrpc.NewTeamHandler(svc)
 
 	mux := http.NewServeMux()
 	demoPath, demoHTTP := demov1connect.NewDemoServiceHandler(demoHandler)
 	statsPath, statsHTTP := statsv1connect.NewStatsServiceHandler(statsHandler)
+	playerPath, playerHTTP := playerv1connect.NewPlayerServiceHandler(playerHandler)
+	teamPath, teamHTTP := teamv1connect.NewTeamServiceHandler(teamHandler)
 	mux.Handle(demoPath, demoHTTP)
 	mux.Handle(statsPath, statsHTTP)
+	mux.Handle(playerPath, playerHTTP)
+	mux.Handle(teamPath, teamHTTP)
 
 	srv := httptest.NewServer(mux)
 	t.Cleanup(srv.Close)
@@ -125,6 +190,20 @@ func setupTestServer(t *testing.T) (*httptest.Server, demov1connect.DemoServiceC
 	return srv, demoClient, statsClient
 }
 
+// newPlayerClient returns a PlayerService client for a server started by
+// setupTestServer.
+func newPlayerClient(srv *httptest.Server) playerv1connect.PlayerServiceClient {
+	return playerv1connect.NewPlayerServiceClient(srv.Client(), srv.URL)
+}
+
+// newTeamClient returns a TeamService client for a server started by
+// setupTestServer.
+func newTeamClient(srv *httptest.Server) teamv1connect.TeamServiceClient {
+	return teamv1connect.NewTeamServiceClient(srv.Client(), srv.URL)
+}
+
+// uploadDemo uploads a demo, waits for its ingest job to finish and
+// returns the match ID.
 func uploadDemo(t *testing.T, client demov1connect.DemoServiceClient) string {
 	t.Helper()
 
@@ -135,10 +214,45 @@ func uploadDemo(t *testing.T, client demov1connect.DemoServiceClient) string {
 	if err != nil {
 		t.Fatalf("upload demo: %v", err)
 	}
-	if resp.Msg.MatchId == "" {
+	if resp.Msg.JobId == "" {
+		t.Fatal("expected non-empty job ID")
+	}
+
+	job := waitForJob(t, client, resp.Msg.JobId)
+	if job.Status != demov1.IngestJobStatus_INGEST_JOB_STATUS_DONE {
+		t.Fatalf("job finished as %v: %s", job.Status, job.Error)
+	}
+	if job.MatchId == "" {
 		t.Fatal("expected non-empty match ID")
 	}
-	return resp.Msg.MatchId
+	return job.MatchId
+}
+
+// waitForJob watches an ingest job until it finishes and returns its final
+// state.
+func waitForJob(t *testing.T, client demov1connect.DemoServiceClient, jobID string) *demov1.IngestJob {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchIngestJob(ctx, connect.NewRequest(&demov1.WatchIngestJobRequest{JobId: jobID}))
+	if err != nil {
+		t.Fatalf("watch ingest job: %v", err)
+	}
+	defer stream.Close()
+
+	var last *demov1.IngestJob
+	for stream.Receive() {
+		last = stream.Msg().GetJob()
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("watch ingest job: %v", err)
+	}
+	if last == nil {
+		t.Fatal("expected at least one job state")
+	}
+	return last
 }
 
 func TestUploadDemo(t *testing.T) {
@@ -169,6 +283,256 @@ func TestUploadDemoDuplicate(t *testing.T) {
 	}
 }
 
+func TestUploadDemoStream(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+
+	stream := demoClient.UploadDemoStream(context.Background())
+	chunks := []string{"fake-", "demo-", "data"}
+	for i, c := range chunks {
+		req := &demov1.UploadDemoStreamRequest{Chunk: []byte(c)}
+		if i == 0 {
+			req.FileName = "streamed.dem"
+		}
+		if err := stream.Send(req); err != nil {
+			t.Fatalf("send chunk %d: %v", i, err)
+		}
+	}
+	resp, err := stream.CloseAndReceive()
+	if err != nil {
+		t.Fatalf("close stream: %v", err)
+	}
+
+	job := waitForJob(t, demoClient, resp.Msg.JobId)
+	if job.Status != demov1.IngestJobStatus_INGEST_JOB_STATUS_DONE || job.FileName != "streamed.dem" {
+		t.Fatalf("job: got %v file %q, want done streamed.dem", job.Status, job.FileName)
+	}
+
+	// the chunks hash to the same demo as a single upload of the same bytes
+	_, err = demoClient.UploadDemo(context.Background(), connect.NewRequest(&demov1.UploadDemoRequest{
+		DemoFile: []byte("fake-demo-data"),
+		FileName: "test.dem",
+	}))
+	if connect.CodeOf(err) != connect.CodeAlreadyExists {
+		t.Fatalf("expected AlreadyExists, got %v", connect.CodeOf(err))
+	}
+}
+
+func TestUploadDemoStreamEmpty(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+
+	stream := demoClient.UploadDemoStream(context.Background())
+	if err := stream.Send(&demov1.UploadDemoStreamRequest{FileName: "empty.dem"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	_, err := stream.CloseAndReceive()
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", connect.CodeOf(err))
+	}
+}
+
+func TestUploadDemoStreamTooLarge(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+
+	stream := demoClient.UploadDemoStream(context.Background())
+	chunk := bytes.Repeat([]byte("x"), 64<<10)
+	for i := 0; i <= maxTestUpload/len(chunk); i++ {
+		req := &demov1.UploadDemoStreamRequest{Chunk: chunk}
+		if i == 0 {
+			req.FileName = "huge.dem"
+		}
+		// the server may reject the upload before it has read every chunk
+		if err := stream.Send(req); err != nil {
+			break
+		}
+	}
+	_, err := stream.CloseAndReceive()
+	if connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	jobs, err := demoClient.ListIngestJobs(context.Background(), connect.NewRequest(&demov1.ListIngestJobsRequest{}))
+	if err != nil {
+		t.Fatalf("list ingest jobs: %v", err)
+	}
+	if len(jobs.Msg.Jobs) != 0 {
+		t.Fatalf("expected no jobs, got %d", len(jobs.Msg.Jobs))
+	}
+}
+
+func TestUploadDemoArchive(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+	ctx := context.Background()
+
+	matchID := uploadDemo(t, demoClient)
+
+	// the archive holds the demo already uploaded and a new one
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, body := range map[string]string{"old.dem": "fake-demo-data", "new.dem": "other-demo-data"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+
+	resp, err := demoClient.UploadDemo(ctx, connect.NewRequest(&demov1.UploadDemoRequest{
+		DemoFile: archive.Bytes(),
+		FileName: "series.zip",
+	}))
+	if err != nil {
+		t.Fatalf("upload archive: %v", err)
+	}
+	if len(resp.Msg.JobIds) != 1 || resp.Msg.JobId != resp.Msg.JobIds[0] {
+		t.Fatalf("expected one queued job, got %v", resp.Msg.JobIds)
+	}
+	job := waitForJob(t, demoClient, resp.Msg.JobId)
+	if job.FileName != "new.dem" || job.MatchId == "" || job.MatchId == matchID {
+		t.Errorf("archive job: got file %s match %s", job.FileName, job.MatchId)
+	}
+
+	_, err = demoClient.UploadDemo(ctx, connect.NewRequest(&demov1.UploadDemoRequest{
+		DemoFile: archive.Bytes(),
+		FileName: "series.zip",
+	}))
+	if connect.CodeOf(err) != connect.CodeAlreadyExists {
+		t.Errorf("reupload archive: expected AlreadyExists, got %v", connect.CodeOf(err))
+	}
+}
+
+func TestDownloadDemo(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+	ctx := context.Background()
+
+	matchID := uploadDemo(t, demoClient)
+
+	stream, err := demoClient.DownloadDemo(ctx, connect.NewRequest(&demov1.DownloadDemoRequest{MatchId: matchID}))
+	if err != nil {
+		t.Fatalf("download demo: %v", err)
+	}
+	defer stream.Close()
+
+	var (
+		demo  []byte
+		first *demov1.DownloadDemoResponse
+	)
+	for stream.Receive() {
+		if first == nil {
+			first = stream.Msg()
+		}
+		demo = append(demo, stream.Msg().GetChunk()...)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("download demo: %v", err)
+	}
+	if string(demo) != "fake-demo-data" {
+		t.Errorf("demo: got %q, want the uploaded content", demo)
+	}
+	if first.GetFileName() == "" || first.GetSize() != int64(len(demo)) {
+		t.Errorf("first message: got name %q size %d", first.GetFileName(), first.GetSize())
+	}
+
+	missing, err := demoClient.DownloadDemo(ctx, connect.NewRequest(&demov1.DownloadDemoRequest{MatchId: "missing"}))
+	if err != nil {
+		t.Fatalf("download missing demo: %v", err)
+	}
+	defer missing.Close()
+	for missing.Receive() {
+	}
+	if connect.CodeOf(missing.Err()) != connect.CodeNotFound {
+		t.Errorf("missing match: expected NotFound, got %v", missing.Err())
+	}
+}
+
+func TestDeleteMatch(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+	ctx := context.Background()
+
+	matchID := uploadDemo(t, demoClient)
+
+	if _, err := demoClient.DeleteMatch(ctx, connect.NewRequest(&demov1.DeleteMatchRequest{MatchId: matchID})); err != nil {
+		t.Fatalf("delete match: %v", err)
+	}
+	_, err := demoClient.GetMatch(ctx, connect.NewRequest(&demov1.GetMatchRequest{MatchId: matchID}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("get deleted match: expected NotFound, got %v", err)
+	}
+	_, err = demoClient.DeleteMatch(ctx, connect.NewRequest(&demov1.DeleteMatchRequest{MatchId: matchID}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("delete again: expected NotFound, got %v", err)
+	}
+	_, err = demoClient.DeleteMatch(ctx, connect.NewRequest(&demov1.DeleteMatchRequest{}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("missing match_id: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestReprocessMatch(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+	ctx := context.Background()
+
+	matchID := uploadDemo(t, demoClient)
+
+	resp, err := demoClient.ReprocessMatch(ctx, connect.NewRequest(&demov1.ReprocessMatchRequest{MatchId: matchID}))
+	if err != nil {
+		t.Fatalf("reprocess match: %v", err)
+	}
+	m := resp.Msg.GetMatch()
+	if m.GetId() != matchID || m.GetParserVersion() != parser.Version {
+		t.Errorf("match: got id %s version %d", m.GetId(), m.GetParserVersion())
+	}
+
+	_, err = demoClient.ReprocessMatch(ctx, connect.NewRequest(&demov1.ReprocessMatchRequest{MatchId: "missing"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("missing match: expected NotFound, got %v", err)
+	}
+
+	all, err := demoClient.ReprocessAll(ctx, connect.NewRequest(&demov1.ReprocessAllRequest{All: true}))
+	if err != nil {
+		t.Fatalf("reprocess all: %v", err)
+	}
+	if all.Msg.GetReprocessed() != 1 || all.Msg.GetSkipped() != 0 || len(all.Msg.GetFailures()) != 0 {
+		t.Errorf("reprocess all: got %+v", all.Msg)
+	}
+}
+
+func TestGetIngestJob(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+
+	matchID := uploadDemo(t, demoClient)
+
+	list, err := demoClient.ListIngestJobs(context.Background(), connect.NewRequest(&demov1.ListIngestJobsRequest{
+		Status: demov1.IngestJobStatus_INGEST_JOB_STATUS_DONE,
+	}))
+	if err != nil {
+		t.Fatalf("list ingest jobs: %v", err)
+	}
+	if len(list.Msg.Jobs) != 1 {
+		t.Fatalf("expected 1 done job, got %d", len(list.Msg.Jobs))
+	}
+
+	resp, err := demoClient.GetIngestJob(context.Background(), connect.NewRequest(&demov1.GetIngestJobRequest{
+		JobId: list.Msg.Jobs[0].Id,
+	}))
+	if err != nil {
+		t.Fatalf("get ingest job: %v", err)
+	}
+	job := resp.Msg.Job
+	if job.MatchId != matchID || job.FileName != "test.dem" || job.DemoFileHash == "" {
+		t.Errorf("job: got match %q file %q hash %q, want match %q file test.dem",
+			job.MatchId, job.FileName, job.DemoFileHash, matchID)
+	}
+}
+
+func TestGetIngestJobNotFound(t *testing.T) {
+	_, demoClient, _ := setupTestServer(t)
+
+	_, err := demoClient.GetIngestJob(context.Background(), connect.NewRequest(&demov1.GetIngestJobRequest{
+		JobId: "nonexistent",
+	}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("expected NotFound, got %v", connect.CodeOf(err))
+	}
+}
+
 func TestUploadDemoEmptyFile(t *testing.T) {
 	_, demoClient, _ := setupTestServer(t)
 
@@ -292,6 +656,127 @@ func TestGetPlayerStatsFilterBySteamID(t *testing.T) {
 	}
 }
 
+func TestGetPlayerProfile(t *testing.T) {
+	srv, demoClient, _ := setupTestServer(t)
+	playerClient := newPlayerClient(srv)
+	ctx := context.Background()
+
+	uploadDemo(t, demoClient)
+
+	resp, err := playerClient.GetPlayerProfile(ctx, connect.NewRequest(&playerv1.GetPlayerProfileRequest{
+		SteamId: "76561198000000001",
+	}))
+	if err != nil {
+		t.Fatalf("get player profile: %v", err)
+	}
+	if resp.Msg.Name != "player1" {
+		t.Errorf("name: got %s, want player1", resp.Msg.Name)
+	}
+	o := resp.Msg.Overall
+	if o.Matches != 1 || o.RoundsPlayed != 1 || o.Kills != 25 || o.Rating != 1.25 {
+		t.Errorf("overall: got %+v", o)
+	}
+	if len(o.Sides) != 1 || o.Sides[0].Side != "CT" || o.Sides[0].Adr != 100 {
+		t.Errorf("sides: got %+v", o.Sides)
+	}
+	if len(resp.Msg.Maps) != 1 || resp.Msg.Maps[0].MapName != "de_dust2" {
+		t.Errorf("maps: got %+v", resp.Msg.Maps)
+	}
+
+	_, err = playerClient.GetPlayerProfile(ctx, connect.NewRequest(&playerv1.GetPlayerProfileRequest{SteamId: "unknown"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("unknown player: expected NotFound, got %v", err)
+	}
+	_, err = playerClient.GetPlayerProfile(ctx, connect.NewRequest(&playerv1.GetPlayerProfileRequest{}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("missing steam_id: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestGetPlayerHistory(t *testing.T) {
+	srv, demoClient, _ := setupTestServer(t)
+	playerClient := newPlayerClient(srv)
+	ctx := context.Background()
+
+	matchID := uploadDemo(t, demoClient)
+
+	resp, err := playerClient.GetPlayerHistory(ctx, connect.NewRequest(&playerv1.GetPlayerHistoryRequest{
+		SteamId:  "76561198000000002",
+		PageSize: 10,
+	}))
+	if err != nil {
+		t.Fatalf("get player history: %v", err)
+	}
+	if len(resp.Msg.Matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(resp.Msg.Matches))
+	}
+	pm := resp.Msg.Matches[0]
+	if pm.Match.Id != matchID || pm.Stats.Kills != 20 || pm.RoundsPlayed != 1 {
+		t.Errorf("match: got %+v", pm)
+	}
+	if resp.Msg.NextPageToken != "" {
+		t.Errorf("expected no next page, got %q", resp.Msg.NextPageToken)
+	}
+
+	_, err = playerClient.GetPlayerHistory(ctx, connect.NewRequest(&playerv1.GetPlayerHistoryRequest{SteamId: "unknown"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("unknown player: expected NotFound, got %v", err)
+	}
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	srv, demoClient, _ := setupTestServer(t)
+	playerClient := newPlayerClient(srv)
+	ctx := context.Background()
+
+	uploadDemo(t, demoClient)
+
+	resp, err := playerClient.GetLeaderboard(ctx, connect.NewRequest(&playerv1.GetLeaderboardRequest{
+		Metric:   playerv1.LeaderboardMetric_LEADERBOARD_METRIC_RATING,
+		PageSize: 1,
+	}))
+	if err != nil {
+		t.Fatalf("get leaderboard: %v", err)
+	}
+	if len(resp.Msg.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(resp.Msg.Entries))
+	}
+	if e := resp.Msg.Entries[0]; e.Rank != 1 || e.SteamId != "76561198000000001" || e.Value != 1.25 || e.Matches != 1 {
+		t.Errorf("first entry: got %+v", e)
+	}
+	if resp.Msg.NextPageToken == "" {
+		t.Fatal("expected next page token")
+	}
+
+	resp, err = playerClient.GetLeaderboard(ctx, connect.NewRequest(&playerv1.GetLeaderboardRequest{
+		Metric:    playerv1.LeaderboardMetric_LEADERBOARD_METRIC_RATING,
+		PageSize:  1,
+		PageToken: resp.Msg.NextPageToken,
+	}))
+	if err != nil {
+		t.Fatalf("get leaderboard page 2: %v", err)
+	}
+	if len(resp.Msg.Entries) != 1 || resp.Msg.Entries[0].SteamId != "76561198000000002" || resp.Msg.Entries[0].Rank != 2 {
+		t.Errorf("page 2: got %+v", resp.Msg.Entries)
+	}
+	if resp.Msg.NextPageToken != "" {
+		t.Errorf("expected no next page, got %q", resp.Msg.NextPageToken)
+	}
+
+	resp, err = playerClient.GetLeaderboard(ctx, connect.NewRequest(&playerv1.GetLeaderboardRequest{MinMatches: 2}))
+	if err != nil {
+		t.Fatalf("get leaderboard with min matches: %v", err)
+	}
+	if len(resp.Msg.Entries) != 0 {
+		t.Errorf("min matches: expected no entries, got %d", len(resp.Msg.Entries))
+	}
+
+	_, err = playerClient.GetLeaderboard(ctx, connect.NewRequest(&playerv1.GetLeaderboardRequest{Metric: 99}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("unknown metric: expected InvalidArgument, got %v", err)
+	}
+}
+
 func TestGetEconomyStats(t *testing.T) {
 	_, demoClient, statsClient := setupTestServer(t)
 
@@ -315,6 +800,180 @@ func TestGetEconomyStats(t *testing.T) {
 	}
 }
 
+func TestGetMapStats(t *testing.T) {
+	_, demoClient, statsClient := setupTestServer(t)
+	ctx := context.Background()
+
+	uploadDemo(t, demoClient)
+
+	resp, err := statsClient.GetMapStats(ctx, connect.NewRequest(&statsv1.GetMapStatsRequest{
+		TeamName: "Team Alpha",
+	}))
+	if err != nil {
+		t.Fatalf("get map stats: %v", err)
+	}
+	if len(resp.Msg.Maps) != 1 {
+		t.Fatalf("expected 1 map, got %d", len(resp.Msg.Maps))
+	}
+	m := resp.Msg.Maps[0]
+	if m.MapName != "de_dust2" || m.Matches != 1 || m.Wins != 1 || m.Rounds != 1 || m.Eliminations != 1 {
+		t.Errorf("map: got %+v", m)
+	}
+	if m.Ct.Rounds != 1 || m.Ct.WinPct != 100 || m.Ct.PistolRounds != 1 || m.Ct.PistolRoundsWon != 1 {
+		t.Errorf("CT side: got %+v", m.Ct)
+	}
+
+	resp, err = statsClient.GetMapStats(ctx, connect.NewRequest(&statsv1.GetMapStatsRequest{
+		MapName: "de_nuke",
+	}))
+	if err != nil {
+		t.Fatalf("get map stats for de_nuke: %v", err)
+	}
+	if len(resp.Msg.Maps) != 0 {
+		t.Errorf("de_nuke: expected no maps, got %d", len(resp.Msg.Maps))
+	}
+}
+
+func TestTeams(t *testing.T) {
+	srv, demoClient, _ := setupTestServer(t)
+	teamClient := newTeamClient(srv)
+	ctx := context.Background()
+
+	matchID := uploadDemo(t, demoClient)
+	match, err := demoClient.GetMatch(ctx, connect.NewRequest(&demov1.GetMatchRequest{MatchId: matchID}))
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	alphaID := match.Msg.Match.TeamAId
+	if alphaID == "" || match.Msg.Match.TeamBId == "" {
+		t.Fatalf("expected both sides linked to teams, got %+v", match.Msg.Match)
+	}
+
+	team, err := teamClient.GetTeam(ctx, connect.NewRequest(&teamv1.GetTeamRequest{TeamId: alphaID}))
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if tm := team.Msg.Team; tm.Name != "Team Alpha" || tm.ClanName != "Team Alpha" || tm.Matches != 1 || tm.Wins != 1 {
+		t.Errorf("team: got %+v", tm)
+	}
+
+	list, err := teamClient.ListTeams(ctx, connect.NewRequest(&teamv1.ListTeamsRequest{PageSize: 1}))
+	if err != nil {
+		t.Fatalf("list teams: %v", err)
+	}
+	if len(list.Msg.Teams) != 1 || list.Msg.NextPageToken == "" {
+		t.Fatalf("first page: got %d teams, token %q", len(list.Msg.Teams), list.Msg.NextPageToken)
+	}
+	next, err := teamClient.ListTeams(ctx, connect.NewRequest(&teamv1.ListTeamsRequest{
+		PageSize:  1,
+		PageToken: list.Msg.NextPageToken,
+	}))
+	if err != nil {
+		t.Fatalf("list teams page 2: %v", err)
+	}
+	if len(next.Msg.Teams) != 1 || next.Msg.Teams[0].Id == list.Msg.Teams[0].Id || next.Msg.NextPageToken != "" {
+		t.Errorf("second page: got %+v, token %q", next.Msg.Teams, next.Msg.NextPageToken)
+	}
+
+	matches, err := teamClient.GetTeamMatches(ctx, connect.NewRequest(&teamv1.GetTeamMatchesRequest{TeamId: alphaID}))
+	if err != nil {
+		t.Fatalf("get team matches: %v", err)
+	}
+	if len(matches.Msg.Matches) != 1 || matches.Msg.Matches[0].Id != matchID {
+		t.Errorf("team matches: got %+v", matches.Msg.Matches)
+	}
+
+	_, err = teamClient.GetTeam(ctx, connect.NewRequest(&teamv1.GetTeamRequest{TeamId: "nonexistent"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("unknown team: expected NotFound, got %v", err)
+	}
+	_, err = teamClient.GetTeamMatches(ctx, connect.NewRequest(&teamv1.GetTeamMatchesRequest{}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("missing team_id: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestCompareTeams(t *testing.T) {
+	srv, demoClient, _ := setupTestServer(t)
+	teamClient := newTeamClient(srv)
+	ctx := context.Background()
+
+	matchID := uploadDemo(t, demoClient)
+	match, err := demoClient.GetMatch(ctx, connect.NewRequest(&demov1.GetMatchRequest{MatchId: matchID}))
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	alphaID, betaID := match.Msg.Match.TeamAId, match.Msg.Match.TeamBId
+
+	resp, err := teamClient.CompareTeams(ctx, connect.NewRequest(&teamv1.CompareTeamsRequest{
+		TeamAId: betaID,
+		TeamBId: alphaID,
+	}))
+	if err != nil {
+		t.Fatalf("compare teams: %v", err)
+	}
+	c := resp.Msg
+	if c.TeamA.Name != "Team Beta" || c.WinsA != 0 || c.WinsB != 1 || c.RoundsA != 12 || c.RoundsB != 16 || c.RoundDiff != -4 {
+		t.Errorf("record: got %+v", c)
+	}
+	if len(c.Maps) != 1 || c.Maps[0].MapName != "de_dust2" || c.Maps[0].WinsB != 1 {
+		t.Errorf("maps: got %+v", c.Maps)
+	}
+	if len(c.Matches) != 1 || c.Matches[0].Match.Id != matchID || c.Matches[0].ScoreA != 12 {
+		t.Errorf("matches: got %+v", c.Matches)
+	}
+
+	_, err = teamClient.CompareTeams(ctx, connect.NewRequest(&teamv1.CompareTeamsRequest{TeamAId: alphaID, TeamBId: alphaID}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("same team: expected InvalidArgument, got %v", err)
+	}
+	_, err = teamClient.CompareTeams(ctx, connect.NewRequest(&teamv1.CompareTeamsRequest{TeamAId: alphaID, TeamBId: "nonexistent"}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("unknown team: expected NotFound, got %v", err)
+	}
+}
+
+func TestComparePlayers(t *testing.T) {
+	srv, demoClient, _ := setupTestServer(t)
+	playerClient := newPlayerClient(srv)
+	ctx := context.Background()
+
+	uploadDemo(t, demoClient)
+
+	resp, err := playerClient.ComparePlayers(ctx, connect.NewRequest(&playerv1.ComparePlayersRequest{
+		SteamIdA: "76561198000000001",
+		SteamIdB: "76561198000000002",
+	}))
+	if err != nil {
+		t.Fatalf("compare players: %v", err)
+	}
+	c := resp.Msg
+	if c.PlayerA.Name != "player1" || c.PlayerB.Name != "player2" || c.Matches != 1 || c.Opposed != 1 {
+		t.Errorf("comparison: got %+v", c)
+	}
+	if c.PlayerA.Stats.Kills != 25 || c.PlayerB.Stats.Kills != 20 {
+		t.Errorf("kills: got %d and %d", c.PlayerA.Stats.Kills, c.PlayerB.Stats.Kills)
+	}
+	if d := c.Duels; d.KillsA != 1 || d.KillsB != 0 || d.HeadshotKillsA != 1 {
+		t.Errorf("duels: got %+v", d)
+	}
+	if len(c.Deltas) == 0 || c.Deltas[0].Stat != "rating" || c.Deltas[0].Delta != 0.3 {
+		t.Errorf("deltas: got %+v", c.Deltas)
+	}
+
+	_, err = playerClient.ComparePlayers(ctx, connect.NewRequest(&playerv1.ComparePlayersRequest{SteamIdA: "76561198000000001"}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("missing steam_id_b: expected InvalidArgument, got %v", err)
+	}
+	_, err = playerClient.ComparePlayers(ctx, connect.NewRequest(&playerv1.ComparePlayersRequest{
+		SteamIdA: "76561198000000001",
+		SteamIdB: "76561198999999999",
+	}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("unknown player: expected NotFound, got %v", err)
+	}
+}
+
 func TestGetRoundTimeline(t *testing.T) {
 	_, demoClient, statsClient := setupTestServer(t)
 
@@ -392,3 +1051,138 @@ func TestGetPositionalDataFilterByRound(t *testing.T) {
 		t.Fatalf("expected 0 kills for round 99, got %d", len(resp.Msg.Kills))
 	}
 }
+
+func TestGetDamageEvents(t *testing.T) {
+	_, demoClient, statsClient := setupTestServer(t)
+
+	matchID := uploadDemo(t, demoClient)
+
+	resp, err := statsClient.GetDamageEvents(context.Background(), connect.NewRequest(&statsv1.GetDamageEventsRequest{
+		MatchId: matchID,
+	}))
+	if err != nil {
+		t.Fatalf("get damage events: %v", err)
+	}
+	if len(resp.Msg.Events) != 2 {
+		t.Fatalf("expected 2 damage events, got %d", len(resp.Msg.Events))
+	}
+
+	// filter by round
+	resp, err = statsClient.GetDamageEvents(context.Background(), connect.NewRequest(&statsv1.GetDamageEventsRequest{
+		MatchId:     matchID,
+		RoundNumber: 99,
+	}))
+	if err != nil {
+		t.Fatalf("get damage events: %v", err)
+	}
+	if len(resp.Msg.Events) != 0 {
+		t.Fatalf("expected 0 damage events for round 99, got %d", len(resp.Msg.Events))
+	}
+}
+
+func TestGetDamageEventsMissingMatchID(t *testing.T) {
+	_, _, statsClient := setupTestServer(t)
+
+	_, err := statsClient.GetDamageEvents(context.Background(), connect.NewRequest(&statsv1.GetDamageEventsRequest{}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", connect.CodeOf(err))
+	}
+}
+
+func TestGetPlayerRoundStats(t *testing.T) {
+	_, demoClient, statsClient := setupTestServer(t)
+
+	matchID := uploadDemo(t, demoClient)
+
+	resp, err := statsClient.GetPlayerRoundStats(context.Background(), connect.NewRequest(&statsv1.GetPlayerRoundStatsRequest{
+		MatchId: matchID,
+		SteamId: "76561198000000001",
+	}))
+	if err != nil {
+		t.Fatalf("get player round stats: %v", err)
+	}
+	if len(resp.Msg.Rounds) != 1 {
+		t.Fatalf("expected 1 round, got %d", len(resp.Msg.Rounds))
+	}
+	r := resp.Msg.Rounds[0]
+	if r.RoundNumber != 1 || r.Side != "CT" || r.Kills != 1 || r.Damage != 100 || !r.Kast {
+		t.Errorf("unexpected round stats: %+v", r)
+	}
+	if r.BuyType != "Eco" {
+		t.Errorf("buy type: got %s, want Eco", r.BuyType)
+	}
+
+	// filter by buy type
+	resp, err = statsClient.GetPlayerRoundStats(context.Background(), connect.NewRequest(&statsv1.GetPlayerRoundStatsRequest{
+		MatchId: matchID,
+		BuyType: "Full",
+	}))
+	if err != nil {
+		t.Fatalf("get player round stats: %v", err)
+	}
+	if len(resp.Msg.Rounds) != 0 {
+		t.Fatalf("expected 0 full-buy rounds, got %d", len(resp.Msg.Rounds))
+	}
+}
+
+func TestGetPlayerRoundStatsMissingMatchID(t *testing.T) {
+	_, _, statsClient := setupTestServer(t)
+
+	_, err := statsClient.GetPlayerRoundStats(context.Background(), connect.NewRequest(&statsv1.GetPlayerRoundStatsRequest{}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", connect.CodeOf(err))
+	}
+}
+
+func TestStreamRoundReplay(t *testing.T) {
+	_, demoClient, statsClient := setupTestServer(t)
+
+	matchID := uploadDemo(t, demoClient)
+
+	stream, err := statsClient.StreamRoundReplay(context.Background(), connect.NewRequest(&statsv1.StreamRoundReplayRequest{
+		MatchId:     matchID,
+		RoundNumber: 1,
+	}))
+	if err != nil {
+		t.Fatalf("stream round replay: %v", err)
+	}
+	defer stream.Close()
+
+	var frames []*statsv1.ReplayFrame
+	for stream.Receive() {
+		frames = append(frames, stream.Msg().Frame)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("receive replay frames: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(frames))
+	}
+	if len(frames[0].Players) != 2 || len(frames[1].Players) != 1 {
+		t.Errorf("expected 2 then 1 players, got %d then %d", len(frames[0].Players), len(frames[1].Players))
+	}
+	if frames[1].Players[0].Health != 76 {
+		t.Errorf("expected health 76, got %d", frames[1].Players[0].Health)
+	}
+}
+
+func TestStreamRoundReplayNotFound(t *testing.T) {
+	_, demoClient, statsClient := setupTestServer(t)
+
+	matchID := uploadDemo(t, demoClient)
+
+	stream, err := statsClient.StreamRoundReplay(context.Background(), connect.NewRequest(&statsv1.StreamRoundReplayRequest{
+		MatchId:     matchID,
+		RoundNumber: 99,
+	}))
+	if err != nil {
+		t.Fatalf("stream round replay: %v", err)
+	}
+	defer stream.Close()
+
+	for stream.Receive() {
+	}
+	if connect.CodeOf(stream.Err()) != connect.CodeNotFound {
+		t.Fatalf("expected NotFound, got %v", stream.Err())
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	demov1 "github.com/zarldev/cs2stats/transport/grpc/gen/demo/v1"
+	playerv1 "github.com/zarldev/cs2stats/transport/grpc/gen/player/v1"
 	statsv1 "github.com/zarldev/cs2stats/transport/grpc/gen/stats/v1"
+	teamv1 "github.com/zarldev/cs2stats/transport/grpc/gen/team/v1"
 
 	"github.com/zarldev/cs2stats/service"
 
@@ -37,6 +39,115 @@ func listMatchesFilter(req *demov1.ListMatchesRequest) service.MatchFilter {
 	return f
 }
 
+func listIngestJobsFilter(req *demov1.ListIngestJobsRequest) service.IngestJobFilter {
+	f := service.IngestJobFilter{
+		Status: jobStatusFromProto(req.GetStatus()),
+		Limit:  int(req.GetPageSize()),
+	}
+	if tok := req.GetPageToken(); tok != "" {
+		ct, cid := decodeCursor(tok)
+		f.CursorTime = ct
+		f.CursorID = cid
+	}
+	return f
+}
+
+func playerProfileFilter(req *playerv1.GetPlayerProfileRequest) service.PlayerFilter {
+	f := service.PlayerFilter{
+		MapName: req.GetMapName(),
+	}
+	if req.GetDateFrom() != nil {
+		f.DateFrom = req.GetDateFrom().AsTime()
+	}
+	if req.GetDateTo() != nil {
+		f.DateTo = req.GetDateTo().AsTime()
+	}
+	return f
+}
+
+func playerHistoryFilter(req *playerv1.GetPlayerHistoryRequest) service.PlayerFilter {
+	f := service.PlayerFilter{
+		MapName: req.GetMapName(),
+		Limit:   int(req.GetPageSize()),
+	}
+	if req.GetDateFrom() != nil {
+		f.DateFrom = req.GetDateFrom().AsTime()
+	}
+	if req.GetDateTo() != nil {
+		f.DateTo = req.GetDateTo().AsTime()
+	}
+	if tok := req.GetPageToken(); tok != "" {
+		ct, cid := decodeCursor(tok)
+		f.CursorTime = ct
+		f.CursorID = cid
+	}
+	return f
+}
+
+func leaderboardFilter(req *playerv1.GetLeaderboardRequest) service.LeaderboardFilter {
+	f := service.LeaderboardFilter{
+		Metric:     leaderboardMetricFromProto(req.GetMetric()),
+		MapName:    req.GetMapName(),
+		MinRounds:  int(req.GetMinRounds()),
+		MinMatches: int(req.GetMinMatches()),
+		Limit:      int(req.GetPageSize()),
+	}
+	if req.GetDateFrom() != nil {
+		f.DateFrom = req.GetDateFrom().AsTime()
+	}
+	if req.GetDateTo() != nil {
+		f.DateTo = req.GetDateTo().AsTime()
+	}
+	if tok := req.GetPageToken(); tok != "" {
+		f.CursorValue, f.CursorSteamID = decodeRankCursor(tok)
+	}
+	return f
+}
+
+// leaderboardMetricFromProto maps unknown metrics to a name the service
+// rejects.
+func listTeamsFilter(req *teamv1.ListTeamsRequest) service.TeamFilter {
+	f := service.TeamFilter{
+		Name:  req.GetName(),
+		Limit: int(req.GetPageSize()),
+	}
+	if tok := req.GetPageToken(); tok != "" {
+		f.CursorTime, f.CursorID = decodeCursor(tok)
+	}
+	return f
+}
+
+func teamMatchesFilter(req *teamv1.GetTeamMatchesRequest) service.MatchFilter {
+	f := service.MatchFilter{
+		Limit: int(req.GetPageSize()),
+	}
+	if tok := req.GetPageToken(); tok != "" {
+		f.CursorTime, f.CursorID = decodeCursor(tok)
+	}
+	return f
+}
+
+func leaderboardMetricFromProto(m playerv1.LeaderboardMetric) service.LeaderboardMetric {
+	switch m {
+	case playerv1.LeaderboardMetric_LEADERBOARD_METRIC_UNSPECIFIED:
+		return ""
+	case playerv1.LeaderboardMetric_LEADERBOARD_METRIC_RATING:
+		return service.MetricRating
+	case playerv1.LeaderboardMetric_LEADERBOARD_METRIC_ADR:
+		return service.MetricADR
+	case playerv1.LeaderboardMetric_LEADERBOARD_METRIC_KAST:
+		return service.MetricKAST
+	case playerv1.LeaderboardMetric_LEADERBOARD_METRIC_HS_PCT:
+		return service.MetricHeadshotPct
+	case playerv1.LeaderboardMetric_LEADERBOARD_METRIC_OPENING_KILL_SUCCESS:
+		return service.MetricOpeningKillSuccess
+	case playerv1.LeaderboardMetric_LEADERBOARD_METRIC_CLUTCH_WIN_RATE:
+		return service.MetricClutchWinRate
+	default:
+		return service.LeaderboardMetric(m.String())
+	}
+}
+
 // response mapping: service -> proto
 
 func matchDetailToProto(m service.MatchDetail) *demov1.Match {
@@ -51,6 +162,9 @@ func matchDetailToProto(m service.MatchDetail) *demov1.Match {
 		TeamBScore:      int32(m.ScoreB),
 		DemoFileHash:    m.DemoHash,
 		TeamAStartedAs:  m.TeamAStartedAs,
+		ParserVersion:   int32(m.ParserVersion),
+		TeamAId:         m.TeamAID,
+		TeamBId:         m.TeamBID,
 	}
 }
 
@@ -66,6 +180,56 @@ func matchSummaryToProto(m service.MatchSummary) *demov1.Match {
 		TeamBScore:      int32(m.ScoreB),
 		DemoFileHash:    m.DemoHash,
 		TeamAStartedAs:  m.TeamAStartedAs,
+		TeamAId:         m.TeamAID,
+		TeamBId:         m.TeamBID,
+	}
+}
+
+func ingestJobToProto(j service.IngestJob) *demov1.IngestJob {
+	return &demov1.IngestJob{
+		Id:           j.ID,
+		FileName:     j.FileName,
+		DemoFileHash: j.DemoHash,
+		Status:       jobStatusToProto(j.Status),
+		Error:        j.Error,
+		MatchId:      j.MatchID,
+		Progress:     float32(j.Progress),
+		CreatedAt:    timestamppb.New(j.CreatedAt),
+		UpdatedAt:    timestamppb.New(j.UpdatedAt),
+	}
+}
+
+func jobStatusToProto(s service.JobStatus) demov1.IngestJobStatus {
+	switch s {
+	case service.JobQueued:
+		return demov1.IngestJobStatus_INGEST_JOB_STATUS_QUEUED
+	case service.JobParsing:
+		return demov1.IngestJobStatus_INGEST_JOB_STATUS_PARSING
+	case service.JobStoring:
+		return demov1.IngestJobStatus_INGEST_JOB_STATUS_STORING
+	case service.JobDone:
+		return demov1.IngestJobStatus_INGEST_JOB_STATUS_DONE
+	case service.JobFailed:
+		return demov1.IngestJobStatus_INGEST_JOB_STATUS_FAILED
+	default:
+		return demov1.IngestJobStatus_INGEST_JOB_STATUS_UNSPECIFIED
+	}
+}
+
+func jobStatusFromProto(s demov1.IngestJobStatus) service.JobStatus {
+	switch s {
+	case demov1.IngestJobStatus_INGEST_JOB_STATUS_QUEUED:
+		return service.JobQueued
+	case demov1.IngestJobStatus_INGEST_JOB_STATUS_PARSING:
+		return service.JobParsing
+	case demov1.IngestJobStatus_INGEST_JOB_STATUS_STORING:
+		return service.JobStoring
+	case demov1.IngestJobStatus_INGEST_JOB_STATUS_DONE:
+		return service.JobDone
+	case demov1.IngestJobStatus_INGEST_JOB_STATUS_FAILED:
+		return service.JobFailed
+	default:
+		return ""
 	}
 }
 
@@ -91,15 +255,38 @@ func playerStatsToStatsProto(ps service.PlayerStats) *statsv1.PlayerStats {
 		Rating:        float32(ps.Rating),
 		FlashAssists:  int32(ps.FlashAssists),
 		UtilityDamage: int32(ps.UtilityDamage),
+
+		Kpr:    float32(ps.KPR),
+		Dpr:    float32(ps.DPR),
+		Impact: float32(ps.Impact),
+
+		Headshots:   int32(ps.Headshots),
+		TotalDamage: int32(ps.TotalDamage),
+		Survived:    int32(ps.Survived),
+		FirstKills:  int32(ps.FirstKills),
+		FirstDeaths: int32(ps.FirstDeaths),
+		TradeKills:  int32(ps.TradeKills),
+
+		TwoK:   int32(ps.TwoK),
+		ThreeK: int32(ps.ThreeK),
+		FourK:  int32(ps.FourK),
+		FiveK:  int32(ps.FiveK),
+
+		EnemiesFlashed:        int32(ps.EnemiesFlashed),
+		TeammatesFlashed:      int32(ps.TeammatesFlashed),
+		EnemyBlindDuration:    float32(ps.EnemyBlindDuration),
+		AvgEnemyBlindDuration: float32(ps.AvgEnemyBlindDuration),
+		FlashesLeadingToKill:  int32(ps.FlashesLeadingToKill),
 	}
 }
 
-// mergeEconomyRounds pairs CT and T economy rows into proto EconomyRound messages.
-// The service returns one row per team per round; the proto expects one row per round.
+// mergeEconomyRounds pairs team A and team B economy rows into proto EconomyRound
+// messages. The service returns one row per team per round; the proto expects one
+// row per round.
 func mergeEconomyRounds(rows []service.EconomyData) []*statsv1.EconomyRound {
 	type pair struct {
-		ct *service.EconomyData
-		t  *service.EconomyData
+		a *service.EconomyData
+		b *service.EconomyData
 	}
 	byRound := make(map[int]*pair)
 
@@ -110,11 +297,10 @@ func mergeEconomyRounds(rows []service.EconomyData) []*statsv1.EconomyRound {
 			p = &pair{}
 			byRound[r.RoundNumber] = p
 		}
-		switch r.Team {
-		case "CT":
-			p.ct = r
-		case "T":
-			p.t = r
+		if r.TeamA {
+			p.a = r
+		} else {
+			p.b = r
 		}
 	}
 
@@ -123,15 +309,15 @@ func mergeEconomyRounds(rows []service.EconomyData) []*statsv1.EconomyRound {
 		er := &statsv1.EconomyRound{
 			RoundNumber: int32(rn),
 		}
-		if p.ct != nil {
-			er.TeamASpend = int32(p.ct.Spend)
-			er.TeamAEquipmentValue = int32(p.ct.EquipmentValue)
-			er.TeamABuyType = parseBuyType(p.ct.BuyType)
+		if p.a != nil {
+			er.TeamASpend = int32(p.a.Spend)
+			er.TeamAEquipmentValue = int32(p.a.EquipmentValue)
+			er.TeamABuyType = parseBuyType(p.a.BuyType)
 		}
-		if p.t != nil {
-			er.TeamBSpend = int32(p.t.Spend)
-			er.TeamBEquipmentValue = int32(p.t.EquipmentValue)
-			er.TeamBBuyType = parseBuyType(p.t.BuyType)
+		if p.b != nil {
+			er.TeamBSpend = int32(p.b.Spend)
+			er.TeamBEquipmentValue = int32(p.b.EquipmentValue)
+			er.TeamBBuyType = parseBuyType(p.b.BuyType)
 		}
 		out = append(out, er)
 	}
@@ -163,6 +349,8 @@ func roundEventToProto(r service.RoundEvent) *statsv1.RoundEvent {
 		RoundNumber: int32(r.Number),
 		Winner:      r.WinnerTeam,
 		WinMethod:   parseWinMethod(r.WinMethod),
+		TeamASide:   r.TeamASide,
+		TeamAWon:    r.TeamAWon,
 	}
 	if r.FirstKillSteamID != "" || r.FirstDeathSteamID != "" {
 		pe.FirstKill = &statsv1.FirstKill{
@@ -230,6 +418,278 @@ func killPositionToProto(k service.KillPosition) *statsv1.KillPosition {
 	}
 }
 
+func damageEventToProto(d service.DamageEvent) *statsv1.DamageEvent {
+	return &statsv1.DamageEvent{
+		RoundNumber:     int32(d.RoundNumber),
+		Tick:            int32(d.Tick),
+		AttackerSteamId: d.AttackerSteamID,
+		VictimSteamId:   d.VictimSteamID,
+		Weapon:          d.Weapon,
+		HitGroup:        d.HitGroup,
+		HealthDamage:    int32(d.HealthDamage),
+		ArmorDamage:     int32(d.ArmorDamage),
+		Aggregate:       d.Aggregate,
+		AttackerPos: &statsv1.Position{
+			X: float32(d.AttackerX),
+			Y: float32(d.AttackerY),
+			Z: float32(d.AttackerZ),
+		},
+		VictimPos: &statsv1.Position{
+			X: float32(d.VictimX),
+			Y: float32(d.VictimY),
+			Z: float32(d.VictimZ),
+		},
+	}
+}
+
+func playerRoundToProto(p service.PlayerRoundStats) *statsv1.PlayerRoundStats {
+	return &statsv1.PlayerRoundStats{
+		RoundNumber:    int32(p.RoundNumber),
+		SteamId:        p.SteamID,
+		Name:           p.Name,
+		Side:           p.Side,
+		Kills:          int32(p.Kills),
+		Deaths:         int32(p.Deaths),
+		Assists:        int32(p.Assists),
+		Damage:         int32(p.Damage),
+		Survived:       p.Survived,
+		Traded:         p.Traded,
+		Kast:           p.KAST,
+		EquipmentValue: int32(p.EquipmentValue),
+		MoneySpent:     int32(p.MoneySpent),
+		BuyType:        p.BuyType,
+	}
+}
+
+func replayFrameToProto(f service.ReplayFrame) *statsv1.ReplayFrame {
+	players := make([]*statsv1.ReplayPlayer, len(f.Players))
+	for i, p := range f.Players {
+		players[i] = &statsv1.ReplayPlayer{
+			SteamId: p.SteamID,
+			Pos: &statsv1.Position{
+				X: float32(p.X),
+				Y: float32(p.Y),
+				Z: float32(p.Z),
+			},
+			Yaw:    float32(p.Yaw),
+			Pitch:  float32(p.Pitch),
+			Health: int32(p.Health),
+			Armor:  int32(p.Armor),
+			Weapon: p.Weapon,
+			Money:  int32(p.Money),
+		}
+	}
+	return &statsv1.ReplayFrame{
+		Tick:    int32(f.Tick),
+		Players: players,
+	}
+}
+
+func careerStatsToProto(c service.CareerStats) *playerv1.CareerStats {
+	sides := make([]*playerv1.SideStats, len(c.Sides))
+	for i, s := range c.Sides {
+		sides[i] = &playerv1.SideStats{
+			Side:      s.Side,
+			Rounds:    int32(s.Rounds),
+			RoundsWon: int32(s.RoundsWon),
+			Kills:     int32(s.Kills),
+			Deaths:    int32(s.Deaths),
+			Assists:   int32(s.Assists),
+			Damage:    int32(s.Damage),
+			Survived:  int32(s.Survived),
+			Adr:       float32(s.ADR),
+			Kast:      float32(s.KAST),
+			Kpr:       float32(s.KPR),
+			Dpr:       float32(s.DPR),
+		}
+	}
+	return &playerv1.CareerStats{
+		MapName:       c.MapName,
+		Matches:       int32(c.Matches),
+		Wins:          int32(c.Wins),
+		Losses:        int32(c.Losses),
+		RoundsPlayed:  int32(c.RoundsPlayed),
+		Kills:         int32(c.Kills),
+		Deaths:        int32(c.Deaths),
+		Assists:       int32(c.Assists),
+		Adr:           float32(c.ADR),
+		Kast:          float32(c.KAST),
+		HsPct:         float32(c.HeadshotPct),
+		Rating:        float32(c.Rating),
+		Kpr:           float32(c.KPR),
+		Dpr:           float32(c.DPR),
+		Impact:        float32(c.Impact),
+		Headshots:     int32(c.Headshots),
+		TotalDamage:   int32(c.TotalDamage),
+		FirstKills:    int32(c.FirstKills),
+		FirstDeaths:   int32(c.FirstDeaths),
+		TradeKills:    int32(c.TradeKills),
+		FlashAssists:  int32(c.FlashAssists),
+		UtilityDamage: int32(c.UtilityDamage),
+		TwoK:          int32(c.TwoK),
+		ThreeK:        int32(c.ThreeK),
+		FourK:         int32(c.FourK),
+		FiveK:         int32(c.FiveK),
+		Sides:         sides,
+	}
+}
+
+func playerMatchToProto(pm service.PlayerMatch) *playerv1.PlayerMatch {
+	return &playerv1.PlayerMatch{
+		Match:        matchSummaryToProto(pm.Match),
+		Stats:        playerStatsToStatsProto(pm.Stats),
+		RoundsPlayed: int32(pm.RoundsPlayed),
+		RoundsWon:    int32(pm.RoundsWon),
+		RoundsLost:   int32(pm.RoundsLost),
+	}
+}
+
+func leaderboardEntryToProto(e service.LeaderboardEntry) *playerv1.LeaderboardEntry {
+	return &playerv1.LeaderboardEntry{
+		Rank:               int32(e.Rank),
+		SteamId:            e.SteamID,
+		Name:               e.Name,
+		Value:              float32(e.Value),
+		Matches:            int32(e.Matches),
+		RoundsPlayed:       int32(e.RoundsPlayed),
+		Kills:              int32(e.Kills),
+		Deaths:             int32(e.Deaths),
+		FirstKills:         int32(e.FirstKills),
+		FirstDeaths:        int32(e.FirstDeaths),
+		Clutches:           int32(e.Clutches),
+		ClutchesWon:        int32(e.ClutchesWon),
+		Rating:             float32(e.Rating),
+		Adr:                float32(e.ADR),
+		Kast:               float32(e.KAST),
+		HsPct:              float32(e.HeadshotPct),
+		OpeningKillSuccess: float32(e.OpeningKillSuccess),
+		ClutchWinRate:      float32(e.ClutchWinRate),
+	}
+}
+
+func mapStatsToProto(m service.MapStats) *statsv1.MapStats {
+	return &statsv1.MapStats{
+		MapName:          m.MapName,
+		Matches:          int32(m.Matches),
+		Wins:             int32(m.Wins),
+		Losses:           int32(m.Losses),
+		Rounds:           int32(m.Rounds),
+		Ct:               mapSideStatsToProto(m.CT),
+		T:                mapSideStatsToProto(m.T),
+		Eliminations:     int32(m.Eliminations),
+		BombsExploded:    int32(m.BombsExploded),
+		BombsDefused:     int32(m.BombsDefused),
+		TimeExpired:      int32(m.TimeExpired),
+		AvgRoundDuration: float32(m.AvgRoundDuration),
+		BombPlants:       int32(m.BombPlants),
+		PlantsA:          int32(m.PlantsA),
+		PlantsB:          int32(m.PlantsB),
+	}
+}
+
+func mapSideStatsToProto(s service.MapSideStats) *statsv1.MapSideStats {
+	return &statsv1.MapSideStats{
+		Rounds:          int32(s.Rounds),
+		RoundsWon:       int32(s.RoundsWon),
+		WinPct:          float32(s.WinPct),
+		PistolRounds:    int32(s.PistolRounds),
+		PistolRoundsWon: int32(s.PistolRoundsWon),
+		PistolWinPct:    float32(s.PistolWinPct),
+	}
+}
+
+func teamToProto(t service.Team) *teamv1.Team {
+	return &teamv1.Team{
+		Id:          t.ID,
+		Name:        t.Name,
+		ClanName:    t.ClanName,
+		Matches:     int32(t.Matches),
+		Wins:        int32(t.Wins),
+		Losses:      int32(t.Losses),
+		FirstPlayed: timestamppb.New(t.FirstPlayed),
+		LastPlayed:  timestamppb.New(t.LastPlayed),
+	}
+}
+
+func teamMemberToProto(m service.TeamMember) *teamv1.TeamMember {
+	return &teamv1.TeamMember{
+		SteamId:     m.SteamID,
+		Name:        m.Name,
+		Matches:     int32(m.Matches),
+		FirstPlayed: timestamppb.New(m.FirstPlayed),
+		LastPlayed:  timestamppb.New(m.LastPlayed),
+	}
+}
+
+func teamComparisonToProto(c service.TeamComparison) *teamv1.CompareTeamsResponse {
+	maps := make([]*teamv1.HeadToHeadMap, len(c.Maps))
+	for i, m := range c.Maps {
+		maps[i] = &teamv1.HeadToHeadMap{
+			MapName:   m.MapName,
+			Matches:   int32(m.Matches),
+			WinsA:     int32(m.WinsA),
+			WinsB:     int32(m.WinsB),
+			RoundDiff: int32(m.RoundDiff),
+		}
+	}
+	matches := make([]*teamv1.HeadToHeadMatch, len(c.Matches))
+	for i, m := range c.Matches {
+		matches[i] = &teamv1.HeadToHeadMatch{
+			Match:     matchSummaryToProto(m.Match),
+			ScoreA:    int32(m.ScoreA),
+			ScoreB:    int32(m.ScoreB),
+			RoundDiff: int32(m.RoundDiff),
+		}
+	}
+	return &teamv1.CompareTeamsResponse{
+		TeamA:     teamToProto(c.A),
+		TeamB:     teamToProto(c.B),
+		WinsA:     int32(c.WinsA),
+		WinsB:     int32(c.WinsB),
+		Draws:     int32(c.Draws),
+		RoundsA:   int32(c.RoundsA),
+		RoundsB:   int32(c.RoundsB),
+		RoundDiff: int32(c.RoundDiff),
+		Maps:      maps,
+		Matches:   matches,
+	}
+}
+
+func playerComparisonToProto(c service.PlayerComparison) *playerv1.ComparePlayersResponse {
+	deltas := make([]*playerv1.StatDelta, len(c.Deltas))
+	for i, d := range c.Deltas {
+		deltas[i] = &playerv1.StatDelta{
+			Stat:  d.Stat,
+			A:     float32(d.A),
+			B:     float32(d.B),
+			Delta: float32(d.Delta),
+		}
+	}
+	return &playerv1.ComparePlayersResponse{
+		PlayerA: comparedPlayerToProto(c.A),
+		PlayerB: comparedPlayerToProto(c.B),
+		Matches: int32(c.Matches),
+		Opposed: int32(c.Opposed),
+		WinsA:   int32(c.WinsA),
+		WinsB:   int32(c.WinsB),
+		Deltas:  deltas,
+		Duels: &playerv1.DuelRecord{
+			KillsA:         int32(c.Duels.KillsA),
+			KillsB:         int32(c.Duels.KillsB),
+			HeadshotKillsA: int32(c.Duels.HeadshotKillsA),
+			HeadshotKillsB: int32(c.Duels.HeadshotKillsB),
+		},
+	}
+}
+
+func comparedPlayerToProto(p service.ComparedPlayer) *playerv1.ComparedPlayer {
+	return &playerv1.ComparedPlayer{
+		SteamId: p.SteamID,
+		Name:    p.Name,
+		Stats:   careerStatsToProto(p.Stats),
+	}
+}
+
 // cursor encoding for pagination
 
 type cursor struct {
@@ -253,3 +713,26 @@ func decodeCursor(tok string) (time.Time, string) {
 	}
 	return c.Time, c.ID
 }
+
+// rankCursor pages rankings ordered by value, ties broken by steam ID.
+type rankCursor struct {
+	Value   float64 `json:"v"`
+	SteamID string  `json:"sid"`
+}
+
+func encodeRankCursor(value float64, steamID string) string {
+	data, _ := json.Marshal(rankCursor{Value: value, SteamID: steamID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeRankCursor(tok string) (float64, string) {
+	data, err := base64.RawURLEncoding.DecodeString(tok)
+	if err != nil {
+		return 0, ""
+	}
+	var c rankCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return 0, ""
+	}
+	return c.Value, c.SteamID
+}
@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+
+	"github.com/zarldev/cs2stats/repository"
+	"github.com/zarldev/cs2stats/service"
+	playerv1 "github.com/zarldev/cs2stats/transport/grpc/gen/player/v1"
+	"github.com/zarldev/cs2stats/transport/grpc/gen/player/v1/playerv1connect"
+)
+
+// PlayerHandler implements the PlayerService ConnectRPC handler.
+type PlayerHandler struct {
+	playerv1connect.UnimplementedPlayerServiceHandler
+	svc *service.Service
+}
+
+// NewPlayerHandler creates a PlayerHandler backed by the given service.
+func NewPlayerHandler(svc *service.Service) *PlayerHandler {
+	return &PlayerHandler{svc: svc}
+}
+
+func (h *PlayerHandler) GetPlayerProfile(
+	ctx context.Context,
+	req *connect.Request[playerv1.GetPlayerProfileRequest],
+) (*connect.Response[playerv1.GetPlayerProfileResponse], error) {
+	steamID := req.Msg.GetSteamId()
+	if steamID == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("steam_id is required"))
+	}
+
+	profile, err := h.svc.GetPlayerProfile(ctx, steamID, playerProfileFilter(req.Msg))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("player %s not found", steamID))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get player profile for %s: %w", steamID, err))
+	}
+
+	maps := make([]*playerv1.CareerStats, len(profile.Maps))
+	for i, m := range profile.Maps {
+		maps[i] = careerStatsToProto(m)
+	}
+
+	return connect.NewResponse(&playerv1.GetPlayerProfileResponse{
+		SteamId: profile.SteamID,
+		Name:    profile.Name,
+		Overall: careerStatsToProto(profile.Overall),
+		Maps:    maps,
+	}), nil
+}
+
+func (h *PlayerHandler) GetPlayerHistory(
+	ctx context.Context,
+	req *connect.Request[playerv1.GetPlayerHistoryRequest],
+) (*connect.Response[playerv1.GetPlayerHistoryResponse], error) {
+	steamID := req.Msg.GetSteamId()
+	if steamID == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("steam_id is required"))
+	}
+
+	filter := playerHistoryFilter(req.Msg)
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	// fetch one extra to detect next page
+	filter.Limit++
+
+	matches, err := h.svc.GetPlayerHistory(ctx, steamID, filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("player %s not found", steamID))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get player history for %s: %w", steamID, err))
+	}
+
+	hasMore := len(matches) >= filter.Limit
+	if hasMore {
+		matches = matches[:filter.Limit-1]
+	}
+
+	pbMatches := make([]*playerv1.PlayerMatch, len(matches))
+	for i, m := range matches {
+		pbMatches[i] = playerMatchToProto(m)
+	}
+
+	resp := &playerv1.GetPlayerHistoryResponse{
+		Matches: pbMatches,
+	}
+	if hasMore {
+		last := matches[len(matches)-1].Match
+		resp.NextPageToken = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+func (h *PlayerHandler) GetLeaderboard(
+	ctx context.Context,
+	req *connect.Request[playerv1.GetLeaderboardRequest],
+) (*connect.Response[playerv1.GetLeaderboardResponse], error) {
+	filter := leaderboardFilter(req.Msg)
+	if filter.MinRounds < 0 || filter.MinMatches < 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("min_rounds and min_matches must not be negative"))
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	// fetch one extra to detect next page
+	filter.Limit++
+
+	entries, err := h.svc.GetLeaderboard(ctx, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownMetric) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get leaderboard: %w", err))
+	}
+
+	hasMore := len(entries) >= filter.Limit
+	if hasMore {
+		entries = entries[:filter.Limit-1]
+	}
+
+	pbEntries := make([]*playerv1.LeaderboardEntry, len(entries))
+	for i, e := range entries {
+		pbEntries[i] = leaderboardEntryToProto(e)
+	}
+
+	resp := &playerv1.GetLeaderboardResponse{
+		Entries: pbEntries,
+	}
+	if hasMore {
+		last := entries[len(entries)-1]
+		resp.NextPageToken = encodeRankCursor(last.Value, last.SteamID)
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+func (h *PlayerHandler) ComparePlayers(
+	ctx context.Context,
+	req *connect.Request[playerv1.ComparePlayersRequest],
+) (*connect.Response[playerv1.ComparePlayersResponse], error) {
+	steamA, steamB := req.Msg.GetSteamIdA(), req.Msg.GetSteamIdB()
+	if steamA == "" || steamB == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("steam_id_a and steam_id_b are required"))
+	}
+	if steamA == steamB {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("cannot compare player %s with themselves", steamA))
+	}
+
+	c, err := h.svc.ComparePlayers(ctx, steamA, steamB)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("player %s or %s not found", steamA, steamB))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("compare players %s and %s: %w", steamA, steamB, err))
+	}
+
+	return connect.NewResponse(playerComparisonToProto(c)), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"connectrpc.com/connect"
 
@@ -147,3 +148,124 @@ func (h *StatsHandler) GetPositionalData(
 		Kills:   out,
 	}), nil
 }
+
+func (h *StatsHandler) GetDamageEvents(
+	ctx context.Context,
+	req *connect.Request[statsv1.GetDamageEventsRequest],
+) (*connect.Response[statsv1.GetDamageEventsResponse], error) {
+	matchID := req.Msg.GetMatchId()
+	if matchID == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("match_id is required"))
+	}
+
+	events, err := h.svc.GetDamageEvents(ctx, matchID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("match %s not found", matchID))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get damage events for %s: %w", matchID, err))
+	}
+
+	// filter by round number and player if provided
+	roundNum := req.Msg.GetRoundNumber()
+	steamID := req.Msg.GetSteamId()
+	out := make([]*statsv1.DamageEvent, 0, len(events))
+	for _, d := range events {
+		if roundNum > 0 && int32(d.RoundNumber) != roundNum {
+			continue
+		}
+		if steamID != "" && d.AttackerSteamID != steamID && d.VictimSteamID != steamID {
+			continue
+		}
+		out = append(out, damageEventToProto(d))
+	}
+
+	return connect.NewResponse(&statsv1.GetDamageEventsResponse{
+		Events: out,
+	}), nil
+}
+
+func (h *StatsHandler) GetPlayerRoundStats(
+	ctx context.Context,
+	req *connect.Request[statsv1.GetPlayerRoundStatsRequest],
+) (*connect.Response[statsv1.GetPlayerRoundStatsResponse], error) {
+	matchID := req.Msg.GetMatchId()
+	if matchID == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("match_id is required"))
+	}
+
+	rounds, err := h.svc.GetPlayerRoundStats(ctx, matchID, req.Msg.GetSteamId())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("match %s not found", matchID))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get player round stats for %s: %w", matchID, err))
+	}
+
+	// filter by buy type if provided
+	buyType := req.Msg.GetBuyType()
+	out := make([]*statsv1.PlayerRoundStats, 0, len(rounds))
+	for _, r := range rounds {
+		if buyType != "" && !strings.EqualFold(r.BuyType, buyType) {
+			continue
+		}
+		out = append(out, playerRoundToProto(r))
+	}
+
+	return connect.NewResponse(&statsv1.GetPlayerRoundStatsResponse{
+		Rounds: out,
+	}), nil
+}
+
+func (h *StatsHandler) StreamRoundReplay(
+	ctx context.Context,
+	req *connect.Request[statsv1.StreamRoundReplayRequest],
+	stream *connect.ServerStream[statsv1.StreamRoundReplayResponse],
+) error {
+	matchID := req.Msg.GetMatchId()
+	if matchID == "" {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("match_id is required"))
+	}
+	roundNum := int(req.Msg.GetRoundNumber())
+	if roundNum <= 0 {
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("round_number is required"))
+	}
+
+	frames, err := h.svc.GetRoundReplay(ctx, matchID, roundNum)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("no replay for match %s round %d", matchID, roundNum))
+		}
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("get replay for %s round %d: %w", matchID, roundNum, err))
+	}
+
+	for _, f := range frames {
+		if err := stream.Send(&statsv1.StreamRoundReplayResponse{Frame: replayFrameToProto(f)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *StatsHandler) GetMapStats(
+	ctx context.Context,
+	req *connect.Request[statsv1.GetMapStatsRequest],
+) (*connect.Response[statsv1.GetMapStatsResponse], error) {
+	maps, err := h.svc.GetMapStats(ctx, service.MapStatsFilter{
+		MapName:  req.Msg.GetMapName(),
+		SteamID:  req.Msg.GetSteamId(),
+		TeamName: req.Msg.GetTeamName(),
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get map stats: %w", err))
+	}
+
+	out := make([]*statsv1.MapStats, len(maps))
+	for i, m := range maps {
+		out[i] = mapStatsToProto(m)
+	}
+
+	return connect.NewResponse(&statsv1.GetMapStatsResponse{
+		Maps: out,
+	}), nil
+}
@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+
+	"github.com/zarldev/cs2stats/repository"
+	"github.com/zarldev/cs2stats/service"
+	demov1 "github.com/zarldev/cs2stats/transport/grpc/gen/demo/v1"
+	teamv1 "github.com/zarldev/cs2stats/transport/grpc/gen/team/v1"
+	"github.com/zarldev/cs2stats/transport/grpc/gen/team/v1/teamv1connect"
+)
+
+// TeamHandler implements the TeamService ConnectRPC handler.
+type TeamHandler struct {
+	teamv1connect.UnimplementedTeamServiceHandler
+	svc *service.Service
+}
+
+// NewTeamHandler creates a TeamHandler backed by the given service.
+func NewTeamHandler(svc *service.Service) *TeamHandler {
+	return &TeamHandler{svc: svc}
+}
+
+func (h *TeamHandler) GetTeam(
+	ctx context.Context,
+	req *connect.Request[teamv1.GetTeamRequest],
+) (*connect.Response[teamv1.GetTeamResponse], error) {
+	teamID := req.Msg.GetTeamId()
+	if teamID == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("team_id is required"))
+	}
+
+	team, err := h.svc.GetTeam(ctx, teamID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("team %s not found", teamID))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get team %s: %w", teamID, err))
+	}
+
+	roster := make([]*teamv1.TeamMember, len(team.Roster))
+	for i, m := range team.Roster {
+		roster[i] = teamMemberToProto(m)
+	}
+
+	return connect.NewResponse(&teamv1.GetTeamResponse{
+		Team:   teamToProto(team),
+		Roster: roster,
+	}), nil
+}
+
+func (h *TeamHandler) ListTeams(
+	ctx context.Context,
+	req *connect.Request[teamv1.ListTeamsRequest],
+) (*connect.Response[teamv1.ListTeamsResponse], error) {
+	filter := listTeamsFilter(req.Msg)
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	// fetch one extra to detect next page
+	filter.Limit++
+
+	teams, err := h.svc.ListTeams(ctx, filter)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("list teams: %w", err))
+	}
+
+	hasMore := len(teams) >= filter.Limit
+	if hasMore {
+		teams = teams[:filter.Limit-1]
+	}
+
+	pbTeams := make([]*teamv1.Team, len(teams))
+	for i, t := range teams {
+		pbTeams[i] = teamToProto(t)
+	}
+
+	resp := &teamv1.ListTeamsResponse{
+		Teams: pbTeams,
+	}
+	if hasMore {
+		last := teams[len(teams)-1]
+		resp.NextPageToken = encodeCursor(last.LastPlayed, last.ID)
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+func (h *TeamHandler) GetTeamMatches(
+	ctx context.Context,
+	req *connect.Request[teamv1.GetTeamMatchesRequest],
+) (*connect.Response[teamv1.GetTeamMatchesResponse], error) {
+	teamID := req.Msg.GetTeamId()
+	if teamID == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("team_id is required"))
+	}
+
+	filter := teamMatchesFilter(req.Msg)
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	// fetch one extra to detect next page
+	filter.Limit++
+
+	matches, err := h.svc.GetTeamMatches(ctx, teamID, filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("team %s not found", teamID))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("get matches of team %s: %w", teamID, err))
+	}
+
+	hasMore := len(matches) >= filter.Limit
+	if hasMore {
+		matches = matches[:filter.Limit-1]
+	}
+
+	pbMatches := make([]*demov1.Match, len(matches))
+	for i, m := range matches {
+		pbMatches[i] = matchSummaryToProto(m)
+	}
+
+	resp := &teamv1.GetTeamMatchesResponse{
+		Matches: pbMatches,
+	}
+	if hasMore {
+		last := matches[len(matches)-1]
+		resp.NextPageToken = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return connect.NewResponse(resp), nil
+}
+
+func (h *TeamHandler) CompareTeams(
+	ctx context.Context,
+	req *connect.Request[teamv1.CompareTeamsRequest],
+) (*connect.Response[teamv1.CompareTeamsResponse], error) {
+	teamA, teamB := req.Msg.GetTeamAId(), req.Msg.GetTeamBId()
+	if teamA == "" || teamB == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("team_a_id and team_b_id are required"))
+	}
+	if teamA == teamB {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("cannot compare team %s with itself", teamA))
+	}
+
+	c, err := h.svc.CompareTeams(ctx, teamA, teamB)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("team %s or %s not found", teamA, teamB))
+		}
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("compare teams %s and %s: %w", teamA, teamB, err))
+	}
+
+	return connect.NewResponse(teamComparisonToProto(c)), nil
+}